@@ -0,0 +1,122 @@
+package binary
+
+// Matcher finds every occurrence of a fixed set of byte patterns in a single
+// pass over the input. It is an Aho–Corasick automaton compiled to a dense
+// transition table, so each input byte costs one table lookup regardless of
+// how many patterns there are. Overlapping matches are all reported.
+type Matcher struct {
+	// delta holds one row of 256 transitions per state. Entries are the
+	// target state's row offset (state*256), with matchFlag set when the
+	// target state completes at least one pattern.
+	delta    []uint32
+	out      [][]int
+	patterns [][]byte
+	maxLen   int
+}
+
+const matchFlag = 1
+
+// NewMatcher builds a matcher for patterns. Empty patterns are ignored. The
+// pattern index reported on a match is the pattern's index in patterns.
+func NewMatcher(patterns [][]byte) *Matcher {
+	m := &Matcher{patterns: patterns}
+
+	// Build the trie, with -1 marking missing edges.
+	trie := [][256]int32{newTrieRow()}
+	out := [][]int{nil}
+	for idx, pattern := range patterns {
+		if len(pattern) == 0 {
+			continue
+		}
+		m.maxLen = max(m.maxLen, len(pattern))
+
+		state := int32(0)
+		for _, b := range pattern {
+			if trie[state][b] == -1 {
+				trie = append(trie, newTrieRow())
+				out = append(out, nil)
+				trie[state][b] = int32(len(trie) - 1)
+			}
+			state = trie[state][b]
+		}
+		out[state] = append(out[state], idx)
+	}
+
+	// Breadth-first pass computing failure links and folding them into the
+	// transition table, which turns the trie into a DFA.
+	fail := make([]int32, len(trie))
+	queue := make([]int32, 0, len(trie))
+	for b := 0; b < 256; b++ {
+		if child := trie[0][b]; child == -1 {
+			trie[0][b] = 0
+		} else {
+			queue = append(queue, child)
+		}
+	}
+	for len(queue) > 0 {
+		state := queue[0]
+		queue = queue[1:]
+
+		out[state] = append(out[state], out[fail[state]]...)
+
+		for b := 0; b < 256; b++ {
+			child := trie[state][b]
+			if child == -1 {
+				trie[state][b] = trie[fail[state]][b]
+				continue
+			}
+			fail[child] = trie[fail[state]][b]
+			queue = append(queue, child)
+		}
+	}
+
+	m.out = out
+	m.delta = make([]uint32, len(trie)*256)
+	for state, row := range trie {
+		for b, target := range row {
+			v := uint32(target) * 256
+			if len(out[target]) > 0 {
+				v |= matchFlag
+			}
+			m.delta[state*256+b] = v
+		}
+	}
+
+	return m
+}
+
+func newTrieRow() [256]int32 {
+	var row [256]int32
+	for i := range row {
+		row[i] = -1
+	}
+	return row
+}
+
+// MaxLen returns the length of the longest pattern.
+func (m *Matcher) MaxLen() int {
+	return m.maxLen
+}
+
+// Find calls fn with the pattern index and start offset of every match in
+// data, in order of match end. Scanning stops when fn returns false.
+func (m *Matcher) Find(data []byte, fn func(pattern int, offset int) bool) {
+	if m.maxLen == 0 {
+		return
+	}
+
+	delta := m.delta
+	var state uint32
+	for i, b := range data {
+		state = delta[state+uint32(b)]
+		if state&matchFlag == 0 {
+			continue
+		}
+		state &^= matchFlag
+		for _, idx := range m.out[state/256] {
+			if !fn(idx, i+1-len(m.patterns[idx])) {
+				return
+			}
+		}
+	}
+}
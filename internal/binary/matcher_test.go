@@ -0,0 +1,203 @@
+package binary
+
+import (
+	"bytes"
+	"io"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+type match struct {
+	pattern int
+	offset  int64
+}
+
+func findAll(m *Matcher, data []byte) []match {
+	var got []match
+	m.Find(data, func(pattern, offset int) bool {
+		got = append(got, match{pattern, int64(offset)})
+		return true
+	})
+	return got
+}
+
+// naiveFindAll finds every match of every pattern with bytes.Index.
+func naiveFindAll(patterns [][]byte, data []byte) []match {
+	var got []match
+	for idx, pattern := range patterns {
+		if len(pattern) == 0 {
+			continue
+		}
+		for from := 0; ; {
+			pos := bytes.Index(data[from:], pattern)
+			if pos < 0 {
+				break
+			}
+			got = append(got, match{idx, int64(from + pos)})
+			from += pos + 1
+		}
+	}
+	return got
+}
+
+func sortMatches(ms []match) []match {
+	ms = slices.Clone(ms)
+	slices.SortFunc(ms, func(a, b match) int {
+		if a.offset != b.offset {
+			return int(a.offset - b.offset)
+		}
+		return a.pattern - b.pattern
+	})
+	return ms
+}
+
+func TestMatcherOverlappingPatterns(t *testing.T) {
+	patterns := [][]byte{[]byte("he"), []byte("she"), []byte("his"), []byte("hers"), nil, []byte("aa")}
+	m := NewMatcher(patterns)
+
+	tests := []struct {
+		data string
+		want []match
+	}{
+		{"ushers", []match{{1, 1}, {0, 2}, {3, 2}}},
+		{"ahishers", []match{{2, 1}, {1, 3}, {0, 4}, {3, 4}}},
+		{"aaaa", []match{{5, 0}, {5, 1}, {5, 2}}},
+		{"nothing", nil},
+		{"", nil},
+	}
+	for _, tt := range tests {
+		got := findAll(m, []byte(tt.data))
+		if !slices.Equal(sortMatches(got), sortMatches(tt.want)) {
+			t.Errorf("Find(%q) = %v, want %v", tt.data, got, tt.want)
+		}
+	}
+}
+
+func TestMatcherMatchesNaiveSearch(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	// A small alphabet makes partial and overlapping matches common.
+	data := make([]byte, 64*1024)
+	for i := range data {
+		data[i] = "abc\x00"[rng.Intn(4)]
+	}
+	patterns := [][]byte{[]byte("abca"), []byte("ca"), []byte("a\x00\x00b"), []byte("bcabc"), []byte("c")}
+
+	got := findAll(NewMatcher(patterns), data)
+	want := naiveFindAll(patterns, data)
+	if !slices.Equal(sortMatches(got), sortMatches(want)) {
+		t.Fatalf("Find found %d matches, bytes.Index found %d", len(got), len(want))
+	}
+}
+
+func TestMatcherStopsWhenFnReturnsFalse(t *testing.T) {
+	m := NewMatcher([][]byte{[]byte("x")})
+	calls := 0
+	m.Find([]byte("xxxx"), func(int, int) bool {
+		calls++
+		return calls < 2
+	})
+	if calls != 2 {
+		t.Fatalf("fn called %d times after returning false, want 2", calls)
+	}
+}
+
+func TestMatcherEmptyPatterns(t *testing.T) {
+	m := NewMatcher([][]byte{nil, {}})
+	if m.MaxLen() != 0 {
+		t.Fatalf("MaxLen = %d, want 0", m.MaxLen())
+	}
+	if got := findAll(m, []byte("anything")); got != nil {
+		t.Fatalf("Find = %v, want no matches", got)
+	}
+}
+
+func TestFindAllAcrossChunkBoundaries(t *testing.T) {
+	patterns := [][]byte{[]byte("BOUNDARY"), []byte("ARY!"), []byte("\x01\x02\x03")}
+	maxLen := 8
+	step := scanChunkSize - (maxLen - 1)
+
+	data := make([]byte, 3*scanChunkSize+123)
+	// Put one pattern across each window boundary, split at a different
+	// point each time, and one at the very end of the file.
+	offsets := []int{
+		step - 3,
+		2*step - 1,
+		3*step - 7,
+		len(data) - len(patterns[0]),
+	}
+	for i, off := range offsets {
+		idx := i % len(patterns)
+		if i == len(offsets)-1 {
+			idx = 0
+		}
+		copy(data[off:], patterns[idx])
+	}
+	want := naiveFindAll(patterns, data)
+	if len(want) < len(offsets) {
+		t.Fatalf("test data holds %d matches, want at least %d", len(want), len(offsets))
+	}
+
+	for name, r := range testReaders(t, data) {
+		var got []match
+		err := r.FindAll(NewMatcher(patterns), 0, func(pattern int, offset int64) bool {
+			got = append(got, match{pattern, offset})
+			return true
+		})
+		if err != nil {
+			t.Fatalf("%s: FindAll: %v", name, err)
+		}
+		if !slices.Equal(sortMatches(got), sortMatches(want)) {
+			t.Errorf("%s: FindAll = %v, want %v", name, got, want)
+		}
+
+		for idx, p := range patterns {
+			all, err := r.FindAllPatterns(p, 0, 0)
+			if err != nil {
+				t.Fatalf("%s: FindAllPatterns: %v", name, err)
+			}
+			var wantOffsets []int64
+			for _, m := range want {
+				if m.pattern == idx {
+					wantOffsets = append(wantOffsets, m.offset)
+				}
+			}
+			slices.Sort(wantOffsets)
+			if !slices.Equal(all, wantOffsets) {
+				t.Errorf("%s: FindAllPatterns(%q) = %v, want %v", name, p, all, wantOffsets)
+			}
+		}
+	}
+}
+
+// testReaders returns a memory-mapped and a pread Reader over data.
+func testReaders(tb testing.TB, data []byte) map[string]*Reader {
+	tb.Helper()
+
+	path := filepath.Join(tb.TempDir(), "data")
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		tb.Fatal(err)
+	}
+	mapped, err := NewReader(path)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	tb.Cleanup(func() { mapped.Close() })
+	if !mapped.Mapped() {
+		tb.Skip("memory mapping is not available on this platform")
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	tb.Cleanup(func() { f.Close() })
+
+	// Hiding the *os.File keeps NewReaderAt from mapping it.
+	return map[string]*Reader{
+		"mapped": mapped,
+		"pread":  NewReaderAt(struct{ io.ReaderAt }{f}, int64(len(data))),
+	}
+}
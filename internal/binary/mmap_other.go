@@ -0,0 +1,18 @@
+//go:build !(darwin || dragonfly || freebsd || linux || netbsd || openbsd)
+
+package binary
+
+import (
+	"errors"
+	"os"
+)
+
+// Memory mapping is not implemented on this platform; Reader falls back to
+// positioned reads.
+func mmapFile(f *os.File, size int64) ([]byte, error) {
+	return nil, errors.New("memory mapping not supported")
+}
+
+func munmapFile(data []byte) error {
+	return nil
+}
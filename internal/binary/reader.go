@@ -1,10 +1,13 @@
 package binary
 
 import (
+	"bytes"
+	"context"
 	"encoding/binary"
 	"errors"
 	"io"
 	"os"
+	"sync"
 )
 
 var (
@@ -12,9 +15,25 @@ var (
 	ErrReadFailed    = errors.New("read failed")
 )
 
+// scanChunkSize is the window used when a file cannot be memory-mapped and
+// has to be scanned through pread calls.
+const scanChunkSize = 1024 * 1024
+
+var scanBufferPool = sync.Pool{
+	New: func() any {
+		buf := make([]byte, scanChunkSize)
+		return &buf
+	},
+}
+
+// Reader gives random access to a project file. Where the platform allows it
+// the file is memory-mapped, so View and the pattern searches work on the
+// mapping directly without copying or issuing a read per lookup.
 type Reader struct {
-	file      *os.File
+	src       io.ReaderAt
+	closer    io.Closer // closes src when the Reader opened it, nil otherwise
 	size      int64
+	data      []byte // file contents when memory-mapped, nil otherwise
 	byteOrder binary.ByteOrder
 }
 
@@ -30,16 +49,44 @@ func NewReader(path string) (*Reader, error) {
 		return nil, err
 	}
 
-	return &Reader{
-		file:      f,
-		size:      stat.Size(),
+	r := NewReaderAt(f, stat.Size())
+	r.closer = f
+	return r, nil
+}
+
+// NewReaderAt reads the first size bytes of src. Files are memory-mapped like
+// in NewReader; other sources are read through ReadAt. Close does not close
+// src.
+func NewReaderAt(src io.ReaderAt, size int64) *Reader {
+	r := &Reader{
+		src:       io.NewSectionReader(src, 0, size),
+		size:      size,
 		byteOrder: binary.BigEndian,
-	}, nil
+	}
+
+	// Mapping is an optimisation only; fall back to pread when it fails.
+	if f, ok := src.(*os.File); ok {
+		if data, err := mmapFile(f, size); err == nil {
+			r.data = data
+		}
+	}
+
+	return r
 }
 
 func (r *Reader) Close() error {
-	if r.file != nil {
-		return r.file.Close()
+	if r.data != nil {
+		data := r.data
+		r.data = nil
+		if err := munmapFile(data); err != nil {
+			if r.closer != nil {
+				r.closer.Close()
+			}
+			return err
+		}
+	}
+	if r.closer != nil {
+		return r.closer.Close()
 	}
 	return nil
 }
@@ -48,13 +95,28 @@ func (r *Reader) Size() int64 {
 	return r.size
 }
 
+// Mapped reports whether the file is memory-mapped.
+func (r *Reader) Mapped() bool {
+	return r.data != nil
+}
+
+// ReadAt returns a copy of up to length bytes at offset. The result is owned
+// by the caller and stays valid after Close.
 func (r *Reader) ReadAt(offset int64, length int) ([]byte, error) {
 	if offset < 0 || offset >= r.size {
 		return nil, ErrInvalidOffset
 	}
+	if length < 0 {
+		return nil, ErrReadFailed
+	}
+
+	if r.data != nil {
+		view := r.data[offset:min(offset+int64(length), r.size)]
+		return bytes.Clone(view), nil
+	}
 
 	buf := make([]byte, length)
-	n, err := r.file.ReadAt(buf, offset)
+	n, err := r.src.ReadAt(buf, offset)
 	if err != nil && err != io.EOF {
 		return nil, err
 	}
@@ -62,8 +124,53 @@ func (r *Reader) ReadAt(offset int64, length int) ([]byte, error) {
 	return buf[:n], nil
 }
 
+// View returns up to length bytes at offset. When the file is memory-mapped
+// the slice aliases the mapping: it must not be modified and must not be used
+// after Close. Callers that keep the bytes should use ReadAt instead.
+func (r *Reader) View(offset int64, length int) ([]byte, error) {
+	if r.data == nil {
+		return r.ReadAt(offset, length)
+	}
+	if offset < 0 || offset >= r.size {
+		return nil, ErrInvalidOffset
+	}
+	if length < 0 {
+		return nil, ErrReadFailed
+	}
+	return r.data[offset:min(offset+int64(length), r.size)], nil
+}
+
+// SectionReader returns an io.SectionReader over length bytes at offset,
+// reading from the mapping or the file as it is consumed. Like View, it must
+// not be read from concurrently with Close.
+func (r *Reader) SectionReader(offset, length int64) *io.SectionReader {
+	return io.NewSectionReader(readerAt{r}, offset, length)
+}
+
+// readerAt adapts Reader to io.ReaderAt.
+type readerAt struct {
+	r *Reader
+}
+
+func (ra readerAt) ReadAt(p []byte, off int64) (int, error) {
+	if off < 0 {
+		return 0, ErrInvalidOffset
+	}
+	if off >= ra.r.size {
+		return 0, io.EOF
+	}
+	if ra.r.data != nil {
+		n := copy(p, ra.r.data[off:])
+		if n < len(p) {
+			return n, io.EOF
+		}
+		return n, nil
+	}
+	return ra.r.src.ReadAt(p, off)
+}
+
 func (r *Reader) ReadUint16At(offset int64) (uint16, error) {
-	buf, err := r.ReadAt(offset, 2)
+	buf, err := r.View(offset, 2)
 	if err != nil {
 		return 0, err
 	}
@@ -74,7 +181,7 @@ func (r *Reader) ReadUint16At(offset int64) (uint16, error) {
 }
 
 func (r *Reader) ReadUint32At(offset int64) (uint32, error) {
-	buf, err := r.ReadAt(offset, 4)
+	buf, err := r.View(offset, 4)
 	if err != nil {
 		return 0, err
 	}
@@ -85,7 +192,7 @@ func (r *Reader) ReadUint32At(offset int64) (uint32, error) {
 }
 
 func (r *Reader) ReadUint64At(offset int64) (uint64, error) {
-	buf, err := r.ReadAt(offset, 8)
+	buf, err := r.View(offset, 8)
 	if err != nil {
 		return 0, err
 	}
@@ -96,33 +203,14 @@ func (r *Reader) ReadUint64At(offset int64) (uint64, error) {
 }
 
 func (r *Reader) FindPattern(pattern []byte, startOffset int64) (int64, error) {
-	if len(pattern) == 0 {
-		return -1, errors.New("empty pattern")
+	results, err := r.FindAllPatterns(pattern, startOffset, 1)
+	if err != nil {
+		return -1, err
 	}
-
-	bufSize := 64 * 1024
-	buf := make([]byte, bufSize+len(pattern)-1)
-	offset := startOffset
-
-	for offset < r.size {
-		n, err := r.file.ReadAt(buf, offset)
-		if err != nil && err != io.EOF {
-			return -1, err
-		}
-		if n == 0 {
-			break
-		}
-
-		for i := 0; i <= n-len(pattern); i++ {
-			if matchPattern(buf[i:i+len(pattern)], pattern) {
-				return offset + int64(i), nil
-			}
-		}
-
-		offset += int64(bufSize)
+	if len(results) == 0 {
+		return -1, nil
 	}
-
-	return -1, nil
+	return results[0], nil
 }
 
 func (r *Reader) FindAllPatterns(pattern []byte, startOffset int64, maxResults int) ([]int64, error) {
@@ -131,64 +219,125 @@ func (r *Reader) FindAllPatterns(pattern []byte, startOffset int64, maxResults i
 	}
 
 	var results []int64
-	bufSize := 64 * 1024
-	buf := make([]byte, bufSize+len(pattern)-1)
-	offset := startOffset
-
-	for offset < r.size {
-		if maxResults > 0 && len(results) >= maxResults {
-			break
+	err := r.scanChunks(context.Background(), startOffset, len(pattern), nil, func(chunk []byte, base int64, limit int) bool {
+		searchIdx := 0
+		for {
+			pos := bytes.Index(chunk[searchIdx:], pattern)
+			if pos == -1 || searchIdx+pos >= limit {
+				return true
+			}
+			results = append(results, base+int64(searchIdx+pos))
+			if maxResults > 0 && len(results) >= maxResults {
+				return false
+			}
+			searchIdx += pos + 1
 		}
+	})
+	if err != nil {
+		return nil, err
+	}
 
-		n, err := r.file.ReadAt(buf, offset)
-		if err != nil && err != io.EOF {
-			return nil, err
-		}
-		if n == 0 {
-			break
-		}
+	return results, nil
+}
 
-		for i := 0; i <= n-len(pattern); i++ {
-			if matchPattern(buf[i:i+len(pattern)], pattern) {
-				results = append(results, offset+int64(i))
-				if maxResults > 0 && len(results) >= maxResults {
-					break
-				}
-			}
-		}
+// FindAll calls fn with the pattern index and file offset of every match of
+// m at or after startOffset, in a single pass over the file. Scanning stops
+// when fn returns false.
+func (r *Reader) FindAll(m *Matcher, startOffset int64, fn func(pattern int, offset int64) bool) error {
+	return r.FindAllContext(context.Background(), m, startOffset, nil, fn)
+}
 
-		offset += int64(bufSize)
+// FindAllContext is FindAll that checks ctx between chunks of the file,
+// returning ctx.Err() once it is cancelled, and calls progress, when set,
+// with the offset scanned up to after each chunk.
+func (r *Reader) FindAllContext(ctx context.Context, m *Matcher, startOffset int64, progress func(scanned int64), fn func(pattern int, offset int64) bool) error {
+	if m.MaxLen() == 0 {
+		return nil
 	}
 
-	return results, nil
+	return r.scanChunks(ctx, startOffset, m.MaxLen(), progress, func(chunk []byte, base int64, limit int) bool {
+		more := true
+		m.Find(chunk, func(pattern int, offset int) bool {
+			if offset >= limit {
+				return true
+			}
+			more = fn(pattern, base+int64(offset))
+			return more
+		})
+		return more
+	})
 }
 
-func matchPattern(data, pattern []byte) bool {
-	if len(data) < len(pattern) {
-		return false
+// scanChunks calls fn over the file from startOffset onwards in windows of
+// scanChunkSize that overlap by patternLen-1 bytes; fn must only report
+// matches starting before limit so that none is seen twice. On a mapped file
+// the windows are slices of the mapping, otherwise they are read into pooled
+// buffers. ctx is checked and progress called between windows.
+func (r *Reader) scanChunks(ctx context.Context, startOffset int64, patternLen int, progress func(scanned int64), fn func(chunk []byte, base int64, limit int) bool) error {
+	if startOffset < 0 {
+		startOffset = 0
+	}
+	if startOffset >= r.size {
+		return nil
+	}
+
+	var buf []byte
+	if r.data == nil {
+		bufPtr := scanBufferPool.Get().(*[]byte)
+		defer scanBufferPool.Put(bufPtr)
+		buf = *bufPtr
 	}
-	for i := range pattern {
-		if data[i] != pattern[i] {
-			return false
+	window := max(scanChunkSize, patternLen*2)
+	step := window - (patternLen - 1)
+
+	for offset := startOffset; offset < r.size; offset += int64(step) {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		var chunk []byte
+		if r.data != nil {
+			chunk = r.data[offset:min(offset+int64(window), r.size)]
+		} else {
+			if len(buf) < window {
+				buf = make([]byte, window)
+			}
+			n, err := r.src.ReadAt(buf[:window], offset)
+			if err != nil && err != io.EOF {
+				return err
+			}
+			if n == 0 {
+				break
+			}
+			chunk = buf[:n]
+		}
+
+		limit := step
+		if offset+int64(len(chunk)) >= r.size {
+			limit = len(chunk)
+		}
+		if !fn(chunk, offset, min(limit, len(chunk))) {
+			break
+		}
+		if progress != nil {
+			progress(min(offset+int64(step), r.size))
 		}
 	}
-	return true
+
+	return nil
 }
 
 func (r *Reader) ReadUntil(offset int64, delimiter byte, maxLen int) ([]byte, error) {
-	buf := make([]byte, maxLen)
-	n, err := r.file.ReadAt(buf, offset)
-	if err != nil && err != io.EOF {
+	buf, err := r.View(offset, maxLen)
+	if err != nil {
 		return nil, err
 	}
 
-	for i := 0; i < n; i++ {
-		if buf[i] == delimiter {
-			return buf[:i], nil
-		}
+	if i := bytes.IndexByte(buf, delimiter); i >= 0 {
+		buf = buf[:i]
 	}
 
-	return buf[:n], nil
+	return bytes.Clone(buf), nil
 }
 
 func (r *Reader) ReadLine(offset int64, maxLen int) ([]byte, error) {
@@ -0,0 +1,133 @@
+package binary
+
+import (
+	"bytes"
+	"math/rand"
+	"testing"
+)
+
+// The signatures a project scan looks for, in the shape the parser passes
+// them: a handful of short, mostly binary patterns.
+var benchPatterns = [][]byte{
+	{0x00, 0x00, 0x00, 0x0c, 0x00, 0x00, 0x00, 0x06},
+	{0x00, 0x0b, 0x00, 0x00, 0x00, 0x05, 0x00, 0x00},
+	[]byte(" HTTP/1.1\r\n"),
+	[]byte(" HTTP/2\r\n"),
+	{0x00, 0x00, 0x00, 0x29, 0x00, 0x00, 0x00, 0x09},
+	{0x00, 0x00, 0x00, 0x31, 0x00, 0x00, 0x00, 0x04},
+	{0x00, 0x00, 0x00, 0x2a, 0x00, 0x00, 0x00, 0x0e},
+	{0x00, 0x00, 0x00, 0x0a, 0x00, 0x00, 0x00, 0x0b},
+}
+
+const benchDataSize = 64 * 1024 * 1024
+
+// benchData is project-like data: mostly text with runs of small big-endian
+// integers, and a sprinkling of the benchmark patterns.
+func benchData() []byte {
+	rng := rand.New(rand.NewSource(1))
+	data := make([]byte, benchDataSize)
+	for i := range data {
+		if rng.Intn(4) == 0 {
+			data[i] = 0
+		} else {
+			data[i] = byte(' ' + rng.Intn(95))
+		}
+	}
+	for i := 0; i < 10000; i++ {
+		p := benchPatterns[rng.Intn(len(benchPatterns))]
+		copy(data[rng.Intn(len(data)-len(p)):], p)
+	}
+	return data
+}
+
+// BenchmarkFindAllPatterns is the scan before the matcher: one pass over
+// the file per signature.
+func BenchmarkFindAllPatterns(b *testing.B) {
+	for name, r := range testReaders(b, benchData()) {
+		b.Run(name, func(b *testing.B) {
+			b.SetBytes(benchDataSize)
+			for b.Loop() {
+				for _, p := range benchPatterns {
+					if _, err := r.FindAllPatterns(p, 0, 0); err != nil {
+						b.Fatal(err)
+					}
+				}
+			}
+		})
+	}
+}
+
+// BenchmarkFindAll finds every signature in a single matcher pass.
+func BenchmarkFindAll(b *testing.B) {
+	m := NewMatcher(benchPatterns)
+	for name, r := range testReaders(b, benchData()) {
+		b.Run(name, func(b *testing.B) {
+			b.SetBytes(benchDataSize)
+			for b.Loop() {
+				if err := r.FindAll(m, 0, func(int, int64) bool { return true }); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+// BenchmarkRecordReads reads small records at scattered offsets, like the
+// record parsers do: a copy per read through pread, or a slice of the
+// mapping through View.
+func BenchmarkRecordReads(b *testing.B) {
+	data := benchData()
+	rng := rand.New(rand.NewSource(2))
+	offsets := make([]int64, 4096)
+	for i := range offsets {
+		offsets[i] = rng.Int63n(int64(len(data) - 64))
+	}
+
+	for name, r := range testReaders(b, data) {
+		b.Run(name+"/ReadAt", func(b *testing.B) {
+			for b.Loop() {
+				for _, off := range offsets {
+					if _, err := r.ReadAt(off, 64); err != nil {
+						b.Fatal(err)
+					}
+				}
+			}
+		})
+		b.Run(name+"/View", func(b *testing.B) {
+			for b.Loop() {
+				for _, off := range offsets {
+					if _, err := r.View(off, 64); err != nil {
+						b.Fatal(err)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestReaderViewAndReadAtAgree(t *testing.T) {
+	data := []byte("0123456789abcdef")
+	for name, r := range testReaders(t, data) {
+		for _, tt := range []struct {
+			offset int64
+			length int
+			want   string
+		}{
+			{0, 4, "0123"},
+			{12, 10, "cdef"},
+			{15, 1, "f"},
+		} {
+			view, err := r.View(tt.offset, tt.length)
+			if err != nil || string(view) != tt.want {
+				t.Errorf("%s: View(%d, %d) = %q, %v, want %q", name, tt.offset, tt.length, view, err, tt.want)
+			}
+			read, err := r.ReadAt(tt.offset, tt.length)
+			if err != nil || !bytes.Equal(read, view) {
+				t.Errorf("%s: ReadAt(%d, %d) = %q, %v, want %q", name, tt.offset, tt.length, read, err, tt.want)
+			}
+		}
+		if _, err := r.ReadAt(int64(len(data)), 1); err != ErrInvalidOffset {
+			t.Errorf("%s: ReadAt past the end: err = %v, want ErrInvalidOffset", name, err)
+		}
+	}
+}
@@ -2,10 +2,18 @@ package cli
 
 import (
 	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"os"
+	"os/signal"
+	"slices"
+	"strconv"
 	"strings"
 	"time"
+	"unicode/utf8"
 
 	"github.com/bmm-sec/burp-insights/pkg/burp"
 	"github.com/spf13/cobra"
@@ -17,12 +25,19 @@ var (
 	noColor      bool
 	verbose      bool
 	quiet        bool
+	useIndex     bool
+	recoverFile  bool
+	assumeFormat uint32
 
 	hostFilter        string
 	pathFilter        string
 	methodFilter      string
 	statusFilter      string
 	contentTypeFilter string
+	toolFilter        string
+	highlightFilter   string
+	commentFilter     string
+	inScopeOnly       bool
 	fromTime          string
 	toTime            string
 	minSize           int64
@@ -30,17 +45,31 @@ var (
 	limit             int
 	includeBody       bool
 	maxBodySize       int64
+	rawBody           bool
 
 	searchQuery      string
 	searchRegex      bool
 	searchIgnoreCase bool
 	searchScope      string
+	searchWebSockets bool
 
 	reportTitle    string
 	reportTemplate string
 
 	burpJarPath string
 
+	repeaterShow  string
+	intruderShow  string
+	webSocketShow int
+
+	collaboratorPayload  string
+	collaboratorShowData bool
+
+	dumpType      string
+	dumpLimit     int
+	dumpDepth     int
+	dumpHexLength int
+
 	reportSections        string
 	reportMaxHistory      int
 	reportMaxIssues       int
@@ -67,6 +96,13 @@ var infoCmd = &cobra.Command{
 	RunE:  runInfo,
 }
 
+var formatsCmd = &cobra.Command{
+	Use:   "formats",
+	Short: "List the supported project file format versions and what can be read from each",
+	Args:  cobra.NoArgs,
+	RunE:  runFormats,
+}
+
 var historyCmd = &cobra.Command{
 	Use:   "history <file.burp>",
 	Short: "List HTTP history entries",
@@ -104,11 +140,32 @@ var sitemapCmd = &cobra.Command{
 
 var repeaterCmd = &cobra.Command{
 	Use:   "repeater <file.burp>",
-	Short: "List Repeater tab names",
+	Short: "List Repeater tabs with their requests, responses and send history",
 	Args:  cobra.ExactArgs(1),
 	RunE:  runRepeater,
 }
 
+var intruderCmd = &cobra.Command{
+	Use:   "intruder <file.burp>",
+	Short: "List saved Intruder attacks and their results",
+	Args:  cobra.ExactArgs(1),
+	RunE:  runIntruder,
+}
+
+var websocketsCmd = &cobra.Command{
+	Use:   "websockets <file.burp>",
+	Short: "List WebSocket connections and their messages",
+	Args:  cobra.ExactArgs(1),
+	RunE:  runWebSockets,
+}
+
+var collaboratorCmd = &cobra.Command{
+	Use:   "collaborator <file.burp>",
+	Short: "List Burp Collaborator interactions and the requests that triggered them",
+	Args:  cobra.ExactArgs(1),
+	RunE:  runCollaborator,
+}
+
 var issuesCmd = &cobra.Command{
 	Use:   "issues <file.burp>",
 	Short: "List Scanner issues found in the project",
@@ -123,6 +180,27 @@ var tasksCmd = &cobra.Command{
 	RunE:  runTasks,
 }
 
+var fsckCmd = &cobra.Command{
+	Use:   "fsck <file.burp>",
+	Short: "Check a project file for damage and report what can be recovered",
+	Args:  cobra.ExactArgs(1),
+	RunE:  runFsck,
+}
+
+var dumpCmd = &cobra.Command{
+	Use:   "dump <file.burp> [offset]",
+	Short: "Decode the raw record at an offset, or every record of a type, for format reverse engineering",
+	Args:  cobra.RangeArgs(1, 2),
+	RunE:  runDump,
+}
+
+var carveDeletedCmd = &cobra.Command{
+	Use:   "carve-deleted <file.burp>",
+	Short: "Recover deleted and overwritten records that the live project no longer reaches",
+	Args:  cobra.ExactArgs(1),
+	RunE:  runCarveDeleted,
+}
+
 var issueDefinitionsCmd = &cobra.Command{
 	Use:   "issue-definitions",
 	Short: "Export Burp Scanner issue definitions as JSON",
@@ -136,25 +214,53 @@ func init() {
 	rootCmd.PersistentFlags().BoolVar(&noColor, "no-color", false, "Disable colored output")
 	rootCmd.PersistentFlags().BoolVarP(&verbose, "verbose", "v", false, "Verbose output")
 	rootCmd.PersistentFlags().BoolVar(&quiet, "quiet", false, "Suppress non-essential output")
+	rootCmd.PersistentFlags().BoolVar(&useIndex, "index", false, "Use and maintain a <file>"+burp.IndexSuffix+" index to skip rescanning unchanged projects")
+	rootCmd.PersistentFlags().BoolVar(&recoverFile, "recover", false, "Salvage what can be read from damaged or truncated project files")
+	rootCmd.PersistentFlags().Uint32Var(&assumeFormat, "assume-format", 0, "Read projects with the layout of this format version instead of the one in their header")
 
 	historyCmd.Flags().StringVarP(&hostFilter, "host", "H", "", "Filter by host (regex)")
 	historyCmd.Flags().StringVarP(&pathFilter, "path", "p", "", "Filter by path (regex)")
 	historyCmd.Flags().StringVarP(&methodFilter, "method", "m", "", "Filter by HTTP method (comma-separated)")
 	historyCmd.Flags().StringVarP(&statusFilter, "status", "s", "", "Filter by status code (e.g., 200,301-399,500)")
 	historyCmd.Flags().StringVarP(&contentTypeFilter, "content-type", "t", "", "Filter by content type")
-	historyCmd.Flags().StringVar(&fromTime, "from", "", "Filter from timestamp (RFC3339)")
-	historyCmd.Flags().StringVar(&toTime, "to", "", "Filter to timestamp (RFC3339)")
+	historyCmd.Flags().StringVar(&toolFilter, "tool", "", "Filter by the Burp tool flag on each history item (comma-separated, e.g. proxy,repeater,unknown)")
+	historyCmd.Flags().StringVar(&highlightFilter, "highlight", "", "Filter by highlight colour (comma-separated, \"any\" or \"none\")")
+	historyCmd.Flags().StringVar(&commentFilter, "comment", "", "Filter by comment text (case-insensitive substring)")
+	historyCmd.Flags().BoolVar(&inScopeOnly, "in-scope", false, "Only include entries in the project's Target scope")
+	historyCmd.Flags().StringVar(&fromTime, "from", "", "Only include entries from this time (RFC3339); entries without a time are left out")
+	historyCmd.Flags().StringVar(&toTime, "to", "", "Only include entries up to this time (RFC3339); entries without a time are left out")
 	historyCmd.Flags().Int64Var(&minSize, "min-size", 0, "Minimum response size")
 	historyCmd.Flags().Int64Var(&maxSize, "max-size", 0, "Maximum response size")
 	historyCmd.Flags().IntVarP(&limit, "limit", "n", 0, "Limit number of results")
 	historyCmd.Flags().BoolVar(&includeBody, "include-body", false, "Include request/response bodies")
 	historyCmd.Flags().Int64Var(&maxBodySize, "body-size", 10240, "Max body size to include")
+	historyCmd.Flags().BoolVar(&rawBody, "raw-body", false, "Output bodies as stored, without decoding chunked, gzip, deflate or brotli encoding")
 
 	searchCmd.Flags().StringVarP(&searchQuery, "query", "q", "", "Search query")
 	searchCmd.Flags().BoolVarP(&searchRegex, "regex", "r", false, "Treat query as regex")
 	searchCmd.Flags().BoolVarP(&searchIgnoreCase, "ignore-case", "i", true, "Case-insensitive search")
 	searchCmd.Flags().StringVar(&searchScope, "scope", "all", "Search scope: all, requests, responses, headers, bodies, urls")
 	searchCmd.Flags().IntVarP(&limit, "limit", "n", 0, "Limit number of results")
+	searchCmd.Flags().BoolVar(&rawBody, "raw-body", false, "Search bodies as stored, without decoding chunked, gzip, deflate or brotli encoding")
+	searchCmd.Flags().StringVar(&toolFilter, "tool", "", "Only search entries from these Burp tools (comma-separated)")
+	searchCmd.Flags().BoolVar(&searchWebSockets, "websockets", false, "Search WebSocket connections and messages instead of HTTP history")
+
+	sitemapCmd.Flags().BoolVar(&inScopeOnly, "in-scope", false, "Only include entries in the project's Target scope")
+
+	repeaterCmd.Flags().StringVar(&repeaterShow, "show", "", "Show the full contents of the named tab")
+
+	intruderCmd.Flags().StringVar(&intruderShow, "show", "", "Show the configuration and results grid of the named attack")
+	intruderCmd.Flags().IntVarP(&limit, "limit", "n", 0, "Limit number of result rows shown per attack")
+
+	websocketsCmd.Flags().IntVar(&webSocketShow, "show", 0, "Show the upgrade request and every message of the connection with this ID")
+
+	collaboratorCmd.Flags().StringVar(&collaboratorPayload, "payload", "", "Only list interactions for this payload, given as the bare ID or the full host name")
+	collaboratorCmd.Flags().BoolVar(&collaboratorShowData, "data", false, "Print the raw interaction data under each interaction")
+
+	dumpCmd.Flags().StringVar(&dumpType, "type", "", "Dump typed records of this type instead of an offset, e.g. 0x0c")
+	dumpCmd.Flags().IntVar(&dumpDepth, "depth", burp.DefaultDumpOptions().Depth, "Levels of pointers to follow")
+	dumpCmd.Flags().IntVar(&dumpHexLength, "length", burp.DefaultDumpOptions().HexLength, "Bytes to hexdump when the data is not a recognised record")
+	dumpCmd.Flags().IntVarP(&dumpLimit, "limit", "n", 10, "Maximum records to dump with --type")
 
 	issuesCmd.Flags().StringVar(&burpJarPath, "burp-jar", "", "Optional Burp Suite JAR path to override embedded issue definitions")
 	issuesCmd.Flags().BoolVar(&burpNoAutoDetect, "no-jar-autodetect", false, "Disable auto-detection of Burp Suite jar for issue definitions")
@@ -163,13 +269,21 @@ func init() {
 	exportCmd.Flags().StringVarP(&pathFilter, "path", "p", "", "Filter by path (regex)")
 	exportCmd.Flags().StringVarP(&methodFilter, "method", "m", "", "Filter by HTTP method")
 	exportCmd.Flags().StringVarP(&statusFilter, "status", "s", "", "Filter by status code")
+	exportCmd.Flags().StringVar(&toolFilter, "tool", "", "Filter by Burp tool (comma-separated)")
+	exportCmd.Flags().StringVar(&highlightFilter, "highlight", "", "Filter by highlight colour (comma-separated, \"any\" or \"none\")")
+	exportCmd.Flags().StringVar(&commentFilter, "comment", "", "Filter by comment text (case-insensitive substring)")
+	exportCmd.Flags().BoolVar(&inScopeOnly, "in-scope", false, "Only include entries in the project's Target scope")
+	exportCmd.Flags().StringVar(&fromTime, "from", "", "Only include entries from this time (RFC3339); entries without a time are left out")
+	exportCmd.Flags().StringVar(&toTime, "to", "", "Only include entries up to this time (RFC3339); entries without a time are left out")
 	exportCmd.Flags().BoolVar(&includeBody, "include-body", false, "Include bodies in export")
 	exportCmd.Flags().Int64Var(&maxBodySize, "body-size", 10240, "Max body size to include")
+	exportCmd.Flags().BoolVar(&rawBody, "raw-body", false, "Export bodies as stored, without decoding chunked, gzip, deflate or brotli encoding")
 
 	reportCmd.Flags().StringVar(&reportTitle, "title", "Burp Project Report", "Report title")
 	reportCmd.Flags().StringVar(&reportTemplate, "template", "", "Custom HTML template")
 	reportCmd.Flags().BoolVar(&includeBody, "include-bodies", false, "Include request/response bodies")
 	reportCmd.Flags().StringVar(&reportSections, "sections", "all", "Report sections: all, issues, history, repeater, tasks, sitemap")
+	reportCmd.Flags().BoolVar(&inScopeOnly, "in-scope", false, "Only include history and site map entries in the project's Target scope")
 	reportCmd.Flags().IntVar(&reportMaxHistory, "max-history", 500, "Max HTTP history entries to include (0 for all)")
 	reportCmd.Flags().IntVar(&reportMaxIssues, "max-issues", 0, "Max issues to include (0 for all)")
 	reportCmd.Flags().IntVar(&reportMaxRepeater, "max-repeater", 0, "Max repeater tabs to include (0 for all)")
@@ -184,21 +298,125 @@ func init() {
 	issueDefinitionsCmd.Flags().BoolVar(&burpNoAutoDetect, "no-jar-autodetect", false, "Disable auto-detection of Burp Suite jar for issue definitions")
 
 	rootCmd.AddCommand(infoCmd)
+	rootCmd.AddCommand(formatsCmd)
 	rootCmd.AddCommand(historyCmd)
 	rootCmd.AddCommand(searchCmd)
 	rootCmd.AddCommand(exportCmd)
 	rootCmd.AddCommand(reportCmd)
 	rootCmd.AddCommand(sitemapCmd)
 	rootCmd.AddCommand(repeaterCmd)
+	rootCmd.AddCommand(intruderCmd)
+	rootCmd.AddCommand(websocketsCmd)
+	rootCmd.AddCommand(collaboratorCmd)
 	rootCmd.AddCommand(issuesCmd)
 	rootCmd.AddCommand(tasksCmd)
 	rootCmd.AddCommand(issueDefinitionsCmd)
+	rootCmd.AddCommand(fsckCmd)
+	rootCmd.AddCommand(carveDeletedCmd)
+	rootCmd.AddCommand(dumpCmd)
 }
 
+// Execute runs the command line. The first Ctrl-C cancels the command's
+// context so it can stop its scan and write what it has so far; a second one
+// kills the process as usual.
 func Execute() error {
-	return rootCmd.Execute()
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		stop()
+	}()
+
+	for _, c := range rootCmd.Commands() {
+		if c.RunE != nil {
+			c.RunE = stopOnInterrupt(c.RunE)
+		}
+	}
+
+	err := rootCmd.ExecuteContext(ctx)
+	if errors.Is(err, errInterrupted) {
+		fmt.Fprintln(os.Stderr, "Interrupted; output is incomplete")
+	}
+	return err
+}
+
+var errInterrupted = errors.New("interrupted")
+
+// stopOnInterrupt reports a command cut short by Ctrl-C as errInterrupted,
+// whatever error the cancelled read left it with, and without a usage dump.
+func stopOnInterrupt(run func(*cobra.Command, []string) error) func(*cobra.Command, []string) error {
+	return func(cmd *cobra.Command, args []string) error {
+		err := run(cmd, args)
+		if cmd.Context().Err() == nil {
+			return err
+		}
+		cmd.SilenceUsage = true
+		cmd.SilenceErrors = true
+		return errInterrupted
+	}
+}
+
+// keepPartial drops the error of a read stopped by Ctrl-C, so the command
+// goes on to write the records read before it.
+func keepPartial(err error) error {
+	if errors.Is(err, context.Canceled) {
+		return nil
+	}
+	return err
+}
+
+// warnSkippedHistory tells the user about history items the project lists
+// but that could not be read, as they are missing from the output.
+func warnSkippedHistory(reader *burp.Reader) {
+	n := reader.SkippedHistoryItems()
+	if n == 0 || quiet {
+		return
+	}
+	hint := ""
+	if !recoverFile {
+		hint = "; use --recover to salvage what remains of them"
+	}
+	fmt.Fprintf(os.Stderr, "Warning: %d history item(s) listed in the project could not be read and are left out%s\n", n, hint)
+}
+
+// openProject opens a project with the reader options selected by the global
+// flags. A filePath of "-" reads the project from stdin; gzip, zip and tar
+// archives are unpacked either way.
+func openProject(filePath string) (*burp.Reader, error) {
+	opts := burp.DefaultReaderOptions()
+	opts.Recover = recoverFile
+	return openProjectWithOptions(filePath, opts)
+}
+
+// openProjectWithOptions is openProject starting from opts instead of the
+// default options. A project whose format version has no layout is refused;
+// --assume-format names the layout to read it with instead.
+func openProjectWithOptions(filePath string, opts burp.ReaderOptions) (*burp.Reader, error) {
+	opts.AssumeFormatVersion = assumeFormat
+	if bar := newProgressBar(); bar != nil {
+		opts.ScanProgress = bar.scan
+		opts.Progress = bar.parse
+	}
+
+	var reader *burp.Reader
+	var err error
+	if filePath == stdinPath {
+		reader, err = burp.OpenStream(os.Stdin, opts)
+	} else {
+		if useIndex {
+			opts.IndexPath = burp.IndexPathFor(filePath)
+		}
+		reader, err = burp.OpenWithOptions(filePath, opts)
+	}
+	if errors.Is(err, burp.ErrUnsupportedFormat) {
+		return nil, fmt.Errorf("%w; use --assume-format %d to read it with the newest layout anyway", err, burp.MaxSupportedFormatVersion)
+	}
+	return reader, err
 }
 
+// stdinPath stands for stdin in place of a project file.
+const stdinPath = "-"
+
 func runInfo(cmd *cobra.Command, args []string) error {
 	filePath := args[0]
 
@@ -206,37 +424,94 @@ func runInfo(cmd *cobra.Command, args []string) error {
 		fmt.Fprintf(os.Stderr, "Opening %s...\n", filePath)
 	}
 
-	reader, err := burp.Open(filePath)
+	reader, err := openProject(filePath)
 	if err != nil {
 		return fmt.Errorf("failed to open file: %w", err)
 	}
 	defer reader.Close()
 
-	count, err := reader.HTTPHistoryCount()
-	if err != nil {
+	ctx := cmd.Context()
+	count, err := reader.HTTPHistoryCountContext(ctx)
+	if err = keepPartial(err); err != nil {
 		return fmt.Errorf("failed to count records: %w", err)
 	}
+	warnSkippedHistory(reader)
 
 	meta := reader.Metadata()
+	counts := collectToolCounts(ctx, reader)
+
+	if reader.FormatVersion() != meta.FormatVersion && !quiet {
+		fmt.Fprintf(os.Stderr, "Warning: file format version %s is read with the layout of version %d; some records may not be decoded\n",
+			formatVersionLabel(meta.FormatVersion), reader.FormatVersion())
+	}
 
 	output := getOutputWriter()
 	defer closeOutputWriter(output)
 
 	if outputFormat == "json" {
-		return outputJSON(output, map[string]interface{}{
-			"file":         filePath,
-			"file_size":    meta.FileSize,
-			"record_count": count,
-		})
+		info := map[string]interface{}{
+			"file":             filePath,
+			"file_size":        meta.FileSize,
+			"format_version":   meta.FormatVersion,
+			"burp_edition":     meta.BurpEdition,
+			"burp_version":     meta.BurpVersion,
+			"record_count":     count,
+			"tool_counts":      counts.history,
+			"repeater_tabs":    counts.repeaterTabs,
+			"intruder_attacks": counts.intruderAttacks,
+			"scanner_issues":   counts.scannerIssues,
+			"layout_version":   reader.FormatVersion(),
+			"features":         reader.Compatibility(),
+		}
+		for _, key := range []string{"tool_counts", "repeater_tabs", "intruder_attacks", "scanner_issues"} {
+			if !counts.known[key] {
+				delete(info, key)
+			}
+		}
+		if !meta.CreatedAt.IsZero() {
+			info["created_at"] = meta.CreatedAt.Format(time.RFC3339)
+		}
+		if !meta.ModifiedAt.IsZero() {
+			info["modified_at"] = meta.ModifiedAt.Format(time.RFC3339)
+		}
+		if meta.ProjectOptions != "" {
+			info["project_options"] = json.RawMessage(meta.ProjectOptions)
+			if !json.Valid([]byte(meta.ProjectOptions)) {
+				info["project_options"] = meta.ProjectOptions
+			}
+		}
+		return outputJSON(output, info)
 	}
 
 	fmt.Fprintf(output, "File: %s\n", filePath)
 	fmt.Fprintf(output, "Size: %s (%d bytes)\n", formatSize(meta.FileSize), meta.FileSize)
+	fmt.Fprintf(output, "Format Version: %s\n", formatVersionLabel(meta.FormatVersion))
+	fmt.Fprintf(output, "Burp Version: %s\n", burpVersionLabel(meta))
+	fmt.Fprintf(output, "Created: %s\n", formatInfoTime(meta.CreatedAt))
+	fmt.Fprintf(output, "Last Saved: %s\n", formatInfoTime(meta.ModifiedAt))
 	fmt.Fprintf(output, "HTTP Records: %d\n", count)
+	if unsupported := unsupportedFeatures(reader.Compatibility()); len(unsupported) > 0 {
+		fmt.Fprintf(output, "Unsupported: %s\n", strings.Join(unsupported, ", "))
+	}
+
+	fmt.Fprintf(output, "\nHistory by Tool:\n")
+	if !counts.known["tool_counts"] {
+		fmt.Fprintf(output, "  unknown\n")
+	}
+	for _, tool := range toolDisplayOrder {
+		if n := counts.history[tool.String()]; n > 0 {
+			fmt.Fprintf(output, "  %s: %d\n", tool, n)
+		}
+	}
+
+	fmt.Fprintf(output, "\nTool Records:\n")
+	fmt.Fprintf(output, "  Repeater tabs: %s\n", counts.label("repeater_tabs", counts.repeaterTabs))
+	fmt.Fprintf(output, "  Intruder attacks: %s\n", counts.label("intruder_attacks", counts.intruderAttacks))
+	fmt.Fprintf(output, "  Scanner issues: %s\n", counts.label("scanner_issues", counts.scannerIssues))
 
 	if verbose {
-		history, err := reader.HTTPHistory()
-		if err == nil {
+		history, err := reader.HTTPHistorySummaryContext(ctx)
+		if keepPartial(err) == nil {
 			hosts := make(map[string]int)
 			methods := make(map[string]int)
 			statusCodes := make(map[int]int)
@@ -269,21 +544,186 @@ func runInfo(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
+var toolDisplayOrder = []burp.ToolType{
+	burp.ToolProxy,
+	burp.ToolRepeater,
+	burp.ToolScanner,
+	burp.ToolIntruder,
+	burp.ToolSpider,
+	burp.ToolSequencer,
+	burp.ToolTarget,
+	burp.ToolExtension,
+	burp.ToolUnknown,
+}
+
+// toolCounts holds the per-tool record counts shown by info. A count whose
+// records could not be read is missing from known and shown as unknown.
+type toolCounts struct {
+	history         map[string]int
+	repeaterTabs    int
+	intruderAttacks int
+	scannerIssues   int
+	known           map[string]bool
+}
+
+// collectToolCounts counts the records of each tool. The counts are extras
+// next to the header details, so a tool whose records cannot be read is
+// warned about and left out instead of failing the command.
+func collectToolCounts(ctx context.Context, reader *burp.Reader) toolCounts {
+	counts := toolCounts{history: make(map[string]int), known: make(map[string]bool)}
+	count := func(key, what string, n int, err error) int {
+		if err = keepPartial(err); err != nil {
+			if !quiet {
+				fmt.Fprintf(os.Stderr, "Warning: could not count %s: %v\n", what, err)
+			}
+			return 0
+		}
+		counts.known[key] = true
+		return n
+	}
+
+	history, err := reader.HTTPHistorySummaryContext(ctx)
+	count("tool_counts", "history by tool", len(history), err)
+	if counts.known["tool_counts"] {
+		for _, entry := range history {
+			counts.history[entry.ToolSource.String()]++
+		}
+	}
+
+	tabs, err := reader.RepeaterTabsContext(ctx)
+	counts.repeaterTabs = count("repeater_tabs", "repeater tabs", len(tabs), err)
+
+	attacks, err := reader.IntruderAttacksContext(ctx)
+	counts.intruderAttacks = count("intruder_attacks", "intruder attacks", len(attacks), err)
+
+	issues, err := reader.ScannerIssueMetasContext(ctx)
+	counts.scannerIssues = count("scanner_issues", "scanner issues", len(issues), err)
+
+	return counts
+}
+
+// label formats the count stored under key for the text output.
+func (c toolCounts) label(key string, n int) string {
+	if !c.known[key] {
+		return "unknown"
+	}
+	return strconv.Itoa(n)
+}
+
+func formatVersionLabel(version uint32) string {
+	if version == 0 {
+		return "unknown"
+	}
+	if _, err := burp.FormatCompatibility(version); err != nil {
+		return fmt.Sprintf("%d (unsupported)", version)
+	}
+	return fmt.Sprintf("%d", version)
+}
+
+func unsupportedFeatures(support []burp.FeatureSupport) []string {
+	var names []string
+	for _, s := range support {
+		if !s.Supported {
+			names = append(names, string(s.Feature))
+		}
+	}
+	return names
+}
+
+type formatSupport struct {
+	Version  uint32                `json:"version"`
+	Features []burp.FeatureSupport `json:"features"`
+}
+
+func runFormats(cmd *cobra.Command, args []string) error {
+	var formats []formatSupport
+	for _, version := range burp.SupportedFormatVersions() {
+		support, err := burp.FormatCompatibility(version)
+		if err != nil {
+			return err
+		}
+		formats = append(formats, formatSupport{Version: version, Features: support})
+	}
+
+	output := getOutputWriter()
+	defer closeOutputWriter(output)
+
+	if outputFormat == "json" {
+		return outputJSON(output, formats)
+	}
+
+	fmt.Fprintf(output, "%-16s", "Feature")
+	for _, f := range formats {
+		fmt.Fprintf(output, " %8s", fmt.Sprintf("v%d", f.Version))
+	}
+	fmt.Fprintln(output)
+
+	for i, feature := range burp.Features {
+		fmt.Fprintf(output, "%-16s", feature)
+		for _, f := range formats {
+			mark := "-"
+			if f.Features[i].Supported {
+				mark = "yes"
+			}
+			fmt.Fprintf(output, " %8s", mark)
+		}
+		fmt.Fprintln(output)
+	}
+
+	fmt.Fprintf(output, "\nVersion 0 is reported for projects whose header does not record a version.\n")
+	return nil
+}
+
+func burpVersionLabel(meta *burp.ProjectMetadata) string {
+	switch {
+	case meta.BurpVersion == "" && meta.BurpEdition == "":
+		return "unknown"
+	case meta.BurpEdition == "":
+		return meta.BurpVersion
+	case meta.BurpVersion == "":
+		return "Burp Suite " + meta.BurpEdition
+	default:
+		return "Burp Suite " + meta.BurpEdition + " " + meta.BurpVersion
+	}
+}
+
+func formatInfoTime(t time.Time) string {
+	if t.IsZero() {
+		return "unknown"
+	}
+	return t.Format("2006-01-02 15:04:05 MST")
+}
+
 func runHistory(cmd *cobra.Command, args []string) error {
 	filePath := args[0]
 
-	reader, err := burp.Open(filePath)
+	reader, err := openProject(filePath)
 	if err != nil {
 		return fmt.Errorf("failed to open file: %w", err)
 	}
 	defer reader.Close()
 
-	history, err := reader.HTTPHistory()
-	if err != nil {
+	// Table and CSV output only list entry metadata, which the index can
+	// supply without parsing any messages.
+	ctx := cmd.Context()
+	readHistory := reader.HTTPHistoryContext
+	if outputFormat == "table" || outputFormat == "csv" {
+		readHistory = reader.HTTPHistorySummaryContext
+	}
+	history, err := readHistory(ctx)
+	if err = keepPartial(err); err != nil {
 		return fmt.Errorf("failed to read history: %w", err)
 	}
+	warnSkippedHistory(reader)
 
-	filter := buildFilter()
+	filter, err := buildFilter()
+	if err != nil {
+		return err
+	}
+	filter, err = applyScopeFilter(ctx, reader, filter)
+	if err != nil {
+		return err
+	}
 	if filter != nil {
 		history = burp.FilterHTTPHistory(history, filter)
 	}
@@ -302,6 +742,7 @@ func runHistory(cmd *cobra.Command, args []string) error {
 			IncludeBody: includeBody,
 			PrettyPrint: true,
 			MaxBodySize: maxBodySize,
+			RawBody:     rawBody,
 		}
 		return burp.Export(output, history, opts)
 	case "jsonl":
@@ -309,6 +750,7 @@ func runHistory(cmd *cobra.Command, args []string) error {
 			Format:      burp.FormatJSONLines,
 			IncludeBody: includeBody,
 			MaxBodySize: maxBodySize,
+			RawBody:     rawBody,
 		}
 		return burp.Export(output, history, opts)
 	case "csv":
@@ -322,6 +764,7 @@ func runHistory(cmd *cobra.Command, args []string) error {
 			IncludeBody: includeBody,
 			PrettyPrint: true,
 			MaxBodySize: maxBodySize,
+			RawBody:     rawBody,
 		}
 		return burp.Export(output, history, opts)
 	default:
@@ -333,18 +776,23 @@ func runSearch(cmd *cobra.Command, args []string) error {
 	if searchQuery == "" {
 		return fmt.Errorf("search query is required (use -q flag)")
 	}
+	// WebSocket connections carry no tool and their payloads are never
+	// decoded, so these flags would be silently ignored.
+	if searchWebSockets && toolFilter != "" {
+		return fmt.Errorf("--tool cannot be used with --websockets")
+	}
+	if searchWebSockets && rawBody {
+		return fmt.Errorf("--raw-body cannot be used with --websockets")
+	}
 
 	filePath := args[0]
 
-	reader, err := burp.Open(filePath)
+	reader, err := openProject(filePath)
 	if err != nil {
 		return fmt.Errorf("failed to open file: %w", err)
 	}
 	defer reader.Close()
 
-	ctx := context.Background()
-	entryChan, errChan := reader.StreamHTTPHistory(ctx)
-
 	scope := burp.SearchAll
 	switch strings.ToLower(searchScope) {
 	case "requests":
@@ -365,22 +813,36 @@ func runSearch(cmd *cobra.Command, args []string) error {
 		Scope:         scope,
 		Regex:         searchRegex,
 		MaxResults:    limit,
+		RawBodies:     rawBody,
 	}
 
-	resultChan, searchErrChan := burp.SearchStream(ctx, entryChan, opts)
-
 	output := getOutputWriter()
 	defer closeOutputWriter(output)
 
+	ctx := cmd.Context()
+	if searchWebSockets {
+		return searchWebSocketHistory(ctx, output, reader, opts)
+	}
+
+	filter, err := buildFilter()
+	if err != nil {
+		return err
+	}
+
+	entryChan, errChan := reader.StreamHTTPHistory(ctx)
+	filteredChan := burp.FilterHTTPHistoryStream(ctx, entryChan, filter)
+	resultChan, searchErrChan := burp.SearchStream(ctx, filteredChan, opts)
+
 	var results []burp.SearchResult
 	for result := range resultChan {
 		results = append(results, result)
 	}
 
-	if err := <-errChan; err != nil {
+	if err := keepPartial(<-errChan); err != nil {
 		return err
 	}
-	if err := <-searchErrChan; err != nil {
+	warnSkippedHistory(reader)
+	if err := keepPartial(<-searchErrChan); err != nil {
 		return err
 	}
 
@@ -401,21 +863,58 @@ func runSearch(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
+func searchWebSocketHistory(ctx context.Context, output io.Writer, reader *burp.Reader, opts burp.SearchOptions) error {
+	conns, err := reader.WebSocketHistoryContext(ctx)
+	if err = keepPartial(err); err != nil {
+		return fmt.Errorf("failed to extract websocket history: %w", err)
+	}
+
+	results := burp.SearchWebSockets(conns, opts)
+
+	switch outputFormat {
+	case "json":
+		return burp.ExportWebSocketSearchResults(output, results, burp.ExportOptions{Format: burp.FormatJSON, PrettyPrint: true})
+	case "jsonl":
+		return burp.ExportWebSocketSearchResults(output, results, burp.ExportOptions{Format: burp.FormatJSONLines})
+	}
+
+	fmt.Fprintf(output, "Found %d WebSocket results for \"%s\"\n\n", len(results), searchQuery)
+	for _, result := range results {
+		fmt.Fprintf(output, "[%d] %s\n", result.Connection.ID, result.Connection.URL)
+		fmt.Fprintf(output, "    Messages: %d\n", len(result.Connection.Messages))
+		for _, match := range result.Matches {
+			fmt.Fprintf(output, "    Match in %s: ...%s...\n", match.Location, truncate(match.Context, 80))
+		}
+		fmt.Fprintln(output)
+	}
+
+	return nil
+}
+
 func runExport(cmd *cobra.Command, args []string) error {
 	filePath := args[0]
 
-	reader, err := burp.Open(filePath)
+	reader, err := openProject(filePath)
 	if err != nil {
 		return fmt.Errorf("failed to open file: %w", err)
 	}
 	defer reader.Close()
 
-	history, err := reader.HTTPHistory()
-	if err != nil {
+	ctx := cmd.Context()
+	history, err := reader.HTTPHistoryContext(ctx)
+	if err = keepPartial(err); err != nil {
 		return fmt.Errorf("failed to read history: %w", err)
 	}
+	warnSkippedHistory(reader)
 
-	filter := buildFilter()
+	filter, err := buildFilter()
+	if err != nil {
+		return err
+	}
+	filter, err = applyScopeFilter(ctx, reader, filter)
+	if err != nil {
+		return err
+	}
 	if filter != nil {
 		history = burp.FilterHTTPHistory(history, filter)
 	}
@@ -438,6 +937,7 @@ func runExport(cmd *cobra.Command, args []string) error {
 		IncludeBody: includeBody,
 		PrettyPrint: true,
 		MaxBodySize: maxBodySize,
+		RawBody:     rawBody,
 	}
 
 	return burp.Export(output, history, opts)
@@ -454,12 +954,13 @@ func runReport(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("no report sections selected")
 	}
 
-	reader, err := burp.Open(filePath)
+	reader, err := openProject(filePath)
 	if err != nil {
 		return fmt.Errorf("failed to open file: %w", err)
 	}
 	defer reader.Close()
 
+	ctx := cmd.Context()
 	report := &ReportData{}
 	opts := ReportOptions{
 		Title:            reportTitle,
@@ -476,24 +977,24 @@ func runReport(cmd *cobra.Command, args []string) error {
 
 	if sections.Issues {
 		loadIssueDefinitions()
-		issues, err := reader.ScannerIssueMetas()
-		if err != nil {
+		issues, err := reader.ScannerIssueMetasContext(ctx)
+		if err = keepPartial(err); err != nil {
 			return fmt.Errorf("failed to extract issues: %w", err)
 		}
 		report.Issues = issues
 	}
 
 	if sections.Repeater {
-		tabs, err := reader.RepeaterTabNames()
-		if err != nil {
+		tabs, err := reader.RepeaterTabNamesContext(ctx)
+		if err = keepPartial(err); err != nil {
 			return fmt.Errorf("failed to extract repeater tabs: %w", err)
 		}
 		report.RepeaterTabs = tabs
 	}
 
 	if sections.Tasks {
-		tasks, err := reader.UITasks()
-		if err != nil {
+		tasks, err := reader.UITasksContext(ctx)
+		if err = keepPartial(err); err != nil {
 			return fmt.Errorf("failed to extract tasks: %w", err)
 		}
 		report.Tasks = tasks
@@ -501,10 +1002,18 @@ func runReport(cmd *cobra.Command, args []string) error {
 
 	needHistory := sections.History || sections.Sitemap
 	if needHistory {
-		history, err := reader.HTTPHistory()
-		if err != nil {
+		history, err := reader.HTTPHistoryContext(ctx)
+		if err = keepPartial(err); err != nil {
 			return fmt.Errorf("failed to read history: %w", err)
 		}
+		warnSkippedHistory(reader)
+		scopeFilter, err := applyScopeFilter(ctx, reader, nil)
+		if err != nil {
+			return err
+		}
+		if scopeFilter != nil {
+			history = burp.FilterHTTPHistory(history, scopeFilter)
+		}
 		report.History = history
 		if sections.Sitemap {
 			report.SiteMap = buildSiteMapFromHistory(history)
@@ -520,16 +1029,27 @@ func runReport(cmd *cobra.Command, args []string) error {
 func runSitemap(cmd *cobra.Command, args []string) error {
 	filePath := args[0]
 
-	reader, err := burp.Open(filePath)
+	reader, err := openProject(filePath)
 	if err != nil {
 		return fmt.Errorf("failed to open file: %w", err)
 	}
 	defer reader.Close()
 
-	project, err := reader.Project()
-	if err != nil {
+	ctx := cmd.Context()
+	project, err := reader.ProjectContext(ctx)
+	if err = keepPartial(err); err != nil {
 		return fmt.Errorf("failed to load project: %w", err)
 	}
+	warnSkippedHistory(reader)
+
+	scopeFilter, err := applyScopeFilter(ctx, reader, nil)
+	if err != nil {
+		return err
+	}
+	if scopeFilter != nil {
+		project.HTTPHistory = burp.FilterHTTPHistory(project.HTTPHistory, scopeFilter)
+		project.SiteMap = burp.BuildSiteMap(project.HTTPHistory)
+	}
 
 	output := getOutputWriter()
 	defer closeOutputWriter(output)
@@ -562,84 +1082,492 @@ func runRepeater(cmd *cobra.Command, args []string) error {
 		fmt.Fprintf(os.Stderr, "Scanning %s for Repeater tabs...\n", filePath)
 	}
 
-	reader, err := burp.Open(filePath)
+	reader, err := openProject(filePath)
 	if err != nil {
 		return fmt.Errorf("failed to open file: %w", err)
 	}
 	defer reader.Close()
 
-	tabNames, err := reader.RepeaterTabNames()
-	if err != nil {
+	tabs, err := reader.RepeaterTabsContext(cmd.Context())
+	if err = keepPartial(err); err != nil {
 		return fmt.Errorf("failed to extract repeater tabs: %w", err)
 	}
 
+	if repeaterShow != "" {
+		var selected []burp.RepeaterTab
+		for _, tab := range tabs {
+			if tab.Name == repeaterShow {
+				selected = append(selected, tab)
+			}
+		}
+		if len(selected) == 0 {
+			return fmt.Errorf("repeater tab not found: %s", repeaterShow)
+		}
+		tabs = selected
+	}
+
 	output := getOutputWriter()
 	defer closeOutputWriter(output)
 
-	if outputFormat == "json" {
-		return outputJSON(output, map[string]interface{}{
-			"count": len(tabNames),
-			"tabs":  tabNames,
-		})
+	opts := burp.ExportOptions{
+		IncludeBody: true,
+		PrettyPrint: true,
+		IncludeRaw:  true,
+	}
+	switch outputFormat {
+	case "json":
+		opts.Format = burp.FormatJSON
+		return burp.ExportRepeaterTabs(output, tabs, opts)
+	case "jsonl":
+		opts.Format = burp.FormatJSONLines
+		return burp.ExportRepeaterTabs(output, tabs, opts)
+	case "har":
+		opts.Format = burp.FormatHAR
+		return burp.ExportRepeaterTabs(output, tabs, opts)
 	}
 
-	if len(tabNames) == 0 {
+	if len(tabs) == 0 {
 		fmt.Fprintln(output, "No Repeater tabs found")
 		return nil
 	}
 
-	fmt.Fprintf(output, "Found %d Repeater tab(s):\n\n", len(tabNames))
-	for i, name := range tabNames {
-		fmt.Fprintf(output, "%3d. %s\n", i+1, name)
+	if repeaterShow != "" {
+		for _, tab := range tabs {
+			printRepeaterTab(output, tab)
+		}
+		return nil
 	}
 
-	if !quiet {
-		fmt.Fprintf(os.Stderr, "\nNote: Only tab names are extracted. Request/response data requires more complex parsing.\n")
+	fmt.Fprintf(output, "Found %d Repeater tab(s):\n\n", len(tabs))
+	fmt.Fprintf(output, "%-4s %-34s %-40s %s\n", "#", "Name", "Target", "Sends")
+	fmt.Fprintf(output, "%s\n", strings.Repeat("-", 90))
+	for i, tab := range tabs {
+		fmt.Fprintf(output, "%-4d %-34s %-40s %d\n", i+1, truncate(tab.Name, 34), truncate(repeaterTarget(tab), 40), len(tab.History))
 	}
 
 	return nil
 }
 
-func runIssues(cmd *cobra.Command, args []string) error {
-	filePath := args[0]
+func printRepeaterTab(w io.Writer, tab burp.RepeaterTab) {
+	fmt.Fprintf(w, "Tab: %s\n", tab.Name)
+	fmt.Fprintf(w, "Target: %s\n\n", repeaterTarget(tab))
 
-	loadIssueDefinitions()
+	fmt.Fprintln(w, "=== Request ===")
+	if tab.Request != nil {
+		fmt.Fprintln(w, string(tab.Request.Raw()))
+	} else {
+		fmt.Fprintln(w, "(none)")
+	}
+
+	fmt.Fprintln(w, "\n=== Response ===")
+	if tab.Response != nil {
+		fmt.Fprintln(w, string(tab.Response.Raw()))
+	} else {
+		fmt.Fprintln(w, "(none)")
+	}
+
+	if len(tab.History) == 0 {
+		return
+	}
+
+	fmt.Fprintf(w, "\n=== History (%d sends) ===\n", len(tab.History))
+	for i, send := range tab.History {
+		ts := "-"
+		if !send.Timestamp.IsZero() {
+			ts = send.Timestamp.Format("2006-01-02 15:04:05")
+		}
+		status := "(no response)"
+		if send.Response != nil {
+			status = send.Response.StartLine
+		}
+		fmt.Fprintf(w, "%3d. %s  %s  ->  %s\n", i+1, ts, send.Request.StartLine, status)
+	}
+}
+
+func repeaterTarget(tab burp.RepeaterTab) string {
+	return serviceLabel(tab.Protocol, tab.Host, tab.Port)
+}
+
+func runIntruder(cmd *cobra.Command, args []string) error {
+	filePath := args[0]
 
-	reader, err := burp.Open(filePath)
+	reader, err := openProject(filePath)
 	if err != nil {
 		return fmt.Errorf("failed to open file: %w", err)
 	}
 	defer reader.Close()
 
-	metas, err := reader.ScannerIssueMetas()
-	if err != nil {
-		return fmt.Errorf("failed to extract issues: %w", err)
+	attacks, err := reader.IntruderAttacksContext(cmd.Context())
+	if err = keepPartial(err); err != nil {
+		return fmt.Errorf("failed to extract intruder attacks: %w", err)
+	}
+
+	if intruderShow != "" {
+		var selected []burp.IntruderAttack
+		for _, attack := range attacks {
+			if attack.Name == intruderShow {
+				selected = append(selected, attack)
+			}
+		}
+		if len(selected) == 0 {
+			return fmt.Errorf("intruder attack not found: %s", intruderShow)
+		}
+		attacks = selected
 	}
 
 	output := getOutputWriter()
 	defer closeOutputWriter(output)
 
-	if outputFormat == "json" {
-		return outputJSON(output, map[string]interface{}{
-			"burpFile": filePath,
-			"count":    len(metas),
-			"issues":   metas,
-		})
+	switch outputFormat {
+	case "json":
+		return burp.ExportIntruderAttacks(output, attacks, burp.ExportOptions{Format: burp.FormatJSON, PrettyPrint: true})
+	case "jsonl":
+		return burp.ExportIntruderAttacks(output, attacks, burp.ExportOptions{Format: burp.FormatJSONLines})
+	case "csv":
+		return burp.ExportIntruderAttacks(output, attacks, burp.ExportOptions{Format: burp.FormatCSV})
 	}
 
-	fmt.Fprintf(output, "Found %d issue(s):\n\n", len(metas))
-	fmt.Fprintf(output, "%-3s %-20s %-22s %-10s %-40s %s\n", "#", "Serial", "Severity/Confidence", "Type", "Name", "Host/Path")
-	fmt.Fprintf(output, "%s\n", strings.Repeat("-", 120))
-	for i, meta := range metas {
-		sevConf := fmt.Sprintf("%s/%s", meta.Severity.String(), meta.Confidence.String())
-		typeID := fmt.Sprintf("0x%08x", meta.Type)
+	if len(attacks) == 0 {
+		fmt.Fprintln(output, "No Intruder attacks found")
+		return nil
+	}
 
-		name := "Unknown"
-		if meta.Definition != nil && meta.Definition.Name != "" {
-			name = meta.Definition.Name
+	if intruderShow != "" {
+		for _, attack := range attacks {
+			printIntruderAttack(output, attack)
 		}
+		return nil
+	}
 
-		hostPath := meta.Location
+	fmt.Fprintf(output, "Found %d Intruder attack(s):\n\n", len(attacks))
+	fmt.Fprintf(output, "%-4s %-34s %-14s %-36s %-9s %-8s %s\n", "#", "Name", "Type", "Target", "Positions", "Payloads", "Results")
+	fmt.Fprintf(output, "%s\n", strings.Repeat("-", 120))
+	for i, attack := range attacks {
+		payloads := 0
+		for _, set := range attack.PayloadSets {
+			payloads += len(set.Payloads)
+		}
+		fmt.Fprintf(output, "%-4d %-34s %-14s %-36s %-9d %-8d %d\n",
+			i+1,
+			truncate(attack.Name, 34),
+			attack.AttackType.String(),
+			truncate(serviceLabel(attack.Protocol, attack.Host, attack.Port), 36),
+			len(attack.Positions),
+			payloads,
+			len(attack.Results),
+		)
+	}
+
+	return nil
+}
+
+func printIntruderAttack(w io.Writer, attack burp.IntruderAttack) {
+	fmt.Fprintf(w, "Attack: %s\n", attack.Name)
+	fmt.Fprintf(w, "Type: %s\n", attack.AttackType.String())
+	fmt.Fprintf(w, "Target: %s\n\n", serviceLabel(attack.Protocol, attack.Host, attack.Port))
+
+	fmt.Fprintln(w, "=== Base request ===")
+	fmt.Fprintln(w, attack.BaseRequest)
+
+	fmt.Fprintf(w, "\n=== Payload positions (%d) ===\n", len(attack.Positions))
+	for _, pos := range attack.Positions {
+		fmt.Fprintf(w, "%3d. [%d-%d] %q\n", pos.Index, pos.Start, pos.End, pos.Value)
+	}
+
+	fmt.Fprintf(w, "\n=== Payload sets (%d) ===\n", len(attack.PayloadSets))
+	for _, set := range attack.PayloadSets {
+		fmt.Fprintf(w, "%3d. %s (%d payloads)\n", set.Index, set.Type, len(set.Payloads))
+	}
+
+	if len(attack.GrepMatch) > 0 {
+		fmt.Fprintf(w, "\nGrep - Match: %s\n", strings.Join(attack.GrepMatch, ", "))
+	}
+
+	results := attack.Results
+	fmt.Fprintf(w, "\n=== Results (%d) ===\n", len(results))
+	if limit > 0 && len(results) > limit {
+		results = results[:limit]
+	}
+	fmt.Fprintf(w, "%-8s %-30s %-6s %-10s %-10s %-10s %s\n", "Request", "Payload", "Status", "Length", "Received", "Completed", "Matches")
+	for _, result := range results {
+		status := "-"
+		if result.Error {
+			status = "error"
+		} else if result.StatusCode > 0 {
+			status = fmt.Sprintf("%d", result.StatusCode)
+		}
+		fmt.Fprintf(w, "%-8d %-30s %-6s %-10d %-10d %-10d %s\n",
+			result.Request,
+			truncate(strings.Join(result.Payloads, ", "), 30),
+			status,
+			result.Length,
+			result.ReceivedMs,
+			result.CompletedMs,
+			strings.Join(result.GrepMatches, ", "),
+		)
+	}
+	if len(results) < len(attack.Results) {
+		fmt.Fprintf(w, "... %d more result(s); use --limit 0 or -f csv for the full grid\n", len(attack.Results)-len(results))
+	}
+}
+
+func serviceLabel(protocol, host string, port int) string {
+	if host == "" {
+		return "-"
+	}
+	target := host
+	if port > 0 {
+		target = fmt.Sprintf("%s:%d", target, port)
+	}
+	if protocol != "" {
+		target = protocol + "://" + target
+	}
+	return target
+}
+
+func runWebSockets(cmd *cobra.Command, args []string) error {
+	filePath := args[0]
+
+	reader, err := openProject(filePath)
+	if err != nil {
+		return fmt.Errorf("failed to open file: %w", err)
+	}
+	defer reader.Close()
+
+	conns, err := reader.WebSocketHistoryContext(cmd.Context())
+	if err = keepPartial(err); err != nil {
+		return fmt.Errorf("failed to extract websocket history: %w", err)
+	}
+
+	if webSocketShow > 0 {
+		var selected []burp.WebSocketConnection
+		for _, conn := range conns {
+			if conn.ID == webSocketShow {
+				selected = append(selected, conn)
+			}
+		}
+		if len(selected) == 0 {
+			return fmt.Errorf("websocket connection not found: %d", webSocketShow)
+		}
+		conns = selected
+	}
+
+	output := getOutputWriter()
+	defer closeOutputWriter(output)
+
+	opts := burp.ExportOptions{
+		IncludeBody: true,
+		PrettyPrint: true,
+		IncludeRaw:  true,
+	}
+	switch outputFormat {
+	case "json":
+		opts.Format = burp.FormatJSON
+		return burp.ExportWebSockets(output, conns, opts)
+	case "jsonl":
+		opts.Format = burp.FormatJSONLines
+		return burp.ExportWebSockets(output, conns, opts)
+	}
+
+	if len(conns) == 0 {
+		fmt.Fprintln(output, "No WebSocket connections found")
+		return nil
+	}
+
+	if webSocketShow > 0 {
+		for _, conn := range conns {
+			printWebSocketConnection(output, conn)
+		}
+		return nil
+	}
+
+	fmt.Fprintf(output, "Found %d WebSocket connection(s):\n\n", len(conns))
+	fmt.Fprintf(output, "%-6s %-19s %-60s %s\n", "ID", "Opened", "URL", "Messages")
+	fmt.Fprintf(output, "%s\n", strings.Repeat("-", 100))
+	for _, conn := range conns {
+		fmt.Fprintf(output, "%-6d %-19s %-60s %d\n", conn.ID, formatListTime(conn.Timestamp), truncate(conn.URL, 60), len(conn.Messages))
+	}
+
+	return nil
+}
+
+func printWebSocketConnection(w io.Writer, conn burp.WebSocketConnection) {
+	fmt.Fprintf(w, "Connection: %d\n", conn.ID)
+	fmt.Fprintf(w, "URL: %s\n", conn.URL)
+	fmt.Fprintf(w, "Opened: %s\n\n", formatListTime(conn.Timestamp))
+
+	fmt.Fprintln(w, "=== Upgrade request ===")
+	fmt.Fprintln(w, string(conn.UpgradeRequest.Raw()))
+
+	fmt.Fprintln(w, "\n=== Upgrade response ===")
+	if conn.UpgradeResponse != nil {
+		fmt.Fprintln(w, string(conn.UpgradeResponse.Raw()))
+	} else {
+		fmt.Fprintln(w, "(none)")
+	}
+
+	fmt.Fprintf(w, "\n=== Messages (%d) ===\n", len(conn.Messages))
+	for i, msg := range conn.Messages {
+		payload := fmt.Sprintf("%q", truncate(string(msg.Payload), 80))
+		if msg.Opcode == burp.WebSocketBinary {
+			payload = fmt.Sprintf("(%d bytes binary)", len(msg.Payload))
+		}
+		edited := ""
+		if msg.Edited {
+			edited = " [edited]"
+		}
+		fmt.Fprintf(w, "%4d. %s  %-9s  %-12s %8d  %s%s\n",
+			i+1,
+			formatListTime(msg.Timestamp),
+			msg.Direction,
+			msg.Opcode,
+			len(msg.Payload),
+			payload,
+			edited,
+		)
+	}
+}
+
+func formatListTime(t time.Time) string {
+	if t.IsZero() {
+		return "-"
+	}
+	return t.Format("2006-01-02 15:04:05")
+}
+
+func runCollaborator(cmd *cobra.Command, args []string) error {
+	filePath := args[0]
+
+	reader, err := openProject(filePath)
+	if err != nil {
+		return fmt.Errorf("failed to open file: %w", err)
+	}
+	defer reader.Close()
+
+	interactions, err := reader.CollaboratorInteractionsContext(cmd.Context())
+	if err = keepPartial(err); err != nil {
+		return fmt.Errorf("failed to extract collaborator interactions: %w", err)
+	}
+
+	if collaboratorPayload != "" {
+		var selected []burp.CollaboratorInteraction
+		for _, interaction := range interactions {
+			if interaction.MatchesPayload(collaboratorPayload) {
+				selected = append(selected, interaction)
+			}
+		}
+		interactions = selected
+	}
+
+	output := getOutputWriter()
+	defer closeOutputWriter(output)
+
+	switch outputFormat {
+	case "json":
+		return burp.ExportCollaboratorInteractions(output, interactions, burp.ExportOptions{Format: burp.FormatJSON, PrettyPrint: true})
+	case "jsonl":
+		return burp.ExportCollaboratorInteractions(output, interactions, burp.ExportOptions{Format: burp.FormatJSONLines})
+	case "csv":
+		return burp.ExportCollaboratorInteractions(output, interactions, burp.ExportOptions{Format: burp.FormatCSV})
+	}
+
+	if len(interactions) == 0 {
+		fmt.Fprintln(output, "No Collaborator interactions found")
+		return nil
+	}
+
+	fmt.Fprintf(output, "Found %d Collaborator interaction(s):\n\n", len(interactions))
+	fmt.Fprintf(output, "%-19s %-6s %-34s %-18s %s\n", "Time", "Type", "Payload ID", "Client IP", "Linked")
+	fmt.Fprintf(output, "%s\n", strings.Repeat("-", 110))
+	for _, interaction := range interactions {
+		fmt.Fprintf(output, "%-19s %-6s %-34s %-18s %s\n",
+			formatListTime(interaction.Timestamp),
+			interaction.Type.String(),
+			truncate(interaction.PayloadID, 34),
+			truncate(interaction.ClientIP, 18),
+			collaboratorLinks(interaction),
+		)
+		if collaboratorShowData && len(interaction.Data) > 0 {
+			printCollaboratorData(output, interaction)
+		}
+	}
+
+	return nil
+}
+
+// collaboratorLinks lists the history entries and issues an interaction is
+// linked to, e.g. "history #12, #40; issues 3".
+func collaboratorLinks(interaction burp.CollaboratorInteraction) string {
+	var parts []string
+	if len(interaction.HistoryIDs) > 0 {
+		ids := make([]string, 0, len(interaction.HistoryIDs))
+		for _, id := range interaction.HistoryIDs {
+			ids = append(ids, fmt.Sprintf("#%d", id))
+		}
+		parts = append(parts, "history "+strings.Join(ids, ", "))
+	}
+	if len(interaction.IssueSerials) > 0 {
+		serials := make([]string, 0, len(interaction.IssueSerials))
+		for _, serial := range interaction.IssueSerials {
+			serials = append(serials, fmt.Sprintf("%d", serial))
+		}
+		parts = append(parts, "issues "+strings.Join(serials, ", "))
+	}
+	if len(parts) == 0 {
+		return "-"
+	}
+	return strings.Join(parts, "; ")
+}
+
+func printCollaboratorData(w io.Writer, interaction burp.CollaboratorInteraction) {
+	if interaction.Type == burp.CollaboratorDNS || !utf8.Valid(interaction.Data) {
+		fmt.Fprint(w, hex.Dump(interaction.Data))
+	} else {
+		fmt.Fprintln(w, strings.TrimRight(string(interaction.Data), "\r\n"))
+	}
+	fmt.Fprintln(w)
+}
+
+func runIssues(cmd *cobra.Command, args []string) error {
+	filePath := args[0]
+
+	loadIssueDefinitions()
+
+	reader, err := openProject(filePath)
+	if err != nil {
+		return fmt.Errorf("failed to open file: %w", err)
+	}
+	defer reader.Close()
+
+	metas, err := reader.ScannerIssueMetasContext(cmd.Context())
+	if err = keepPartial(err); err != nil {
+		return fmt.Errorf("failed to extract issues: %w", err)
+	}
+
+	output := getOutputWriter()
+	defer closeOutputWriter(output)
+
+	if outputFormat == "json" {
+		return outputJSON(output, map[string]interface{}{
+			"burpFile": filePath,
+			"count":    len(metas),
+			"issues":   metas,
+		})
+	}
+
+	fmt.Fprintf(output, "Found %d issue(s):\n\n", len(metas))
+	fmt.Fprintf(output, "%-3s %-20s %-22s %-10s %-40s %s\n", "#", "Serial", "Severity/Confidence", "Type", "Name", "Host/Path")
+	fmt.Fprintf(output, "%s\n", strings.Repeat("-", 120))
+	for i, meta := range metas {
+		sevConf := fmt.Sprintf("%s/%s", meta.Severity.String(), meta.Confidence.String())
+		typeID := fmt.Sprintf("0x%08x", meta.Type)
+
+		name := "Unknown"
+		if meta.Definition != nil && meta.Definition.Name != "" {
+			name = meta.Definition.Name
+		}
+
+		hostPath := meta.Location
 		if meta.Host != "" && meta.Path != "" {
 			hostPath = meta.Host + meta.Path
 		} else if meta.Host != "" {
@@ -663,14 +1591,14 @@ func runIssues(cmd *cobra.Command, args []string) error {
 func runTasks(cmd *cobra.Command, args []string) error {
 	filePath := args[0]
 
-	reader, err := burp.Open(filePath)
+	reader, err := openProject(filePath)
 	if err != nil {
 		return fmt.Errorf("failed to open file: %w", err)
 	}
 	defer reader.Close()
 
-	tasks, err := reader.UITasks()
-	if err != nil {
+	tasks, err := reader.UITasksContext(cmd.Context())
+	if err = keepPartial(err); err != nil {
 		return fmt.Errorf("failed to extract tasks: %w", err)
 	}
 
@@ -698,6 +1626,309 @@ func runTasks(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
+func runFsck(cmd *cobra.Command, args []string) error {
+	filePath := args[0]
+
+	if !quiet {
+		fmt.Fprintf(os.Stderr, "Checking %s...\n", filePath)
+	}
+
+	opts := burp.DefaultReaderOptions()
+	opts.Recover = true
+	reader, err := openProjectWithOptions(filePath, opts)
+	if err != nil {
+		return fmt.Errorf("failed to check file: %w", err)
+	}
+	defer reader.Close()
+
+	report, err := burp.CheckReader(cmd.Context(), reader)
+	if err != nil {
+		return fmt.Errorf("failed to check file: %w", err)
+	}
+	report.Path = filePath
+
+	output := getOutputWriter()
+	defer closeOutputWriter(output)
+
+	if outputFormat == "json" {
+		if err := outputJSON(output, report); err != nil {
+			return err
+		}
+	} else {
+		printHealthReport(output, report)
+	}
+
+	if !report.Healthy() {
+		// The report says it all; a usage dump would only get in the way.
+		cmd.SilenceUsage = true
+		return fmt.Errorf("%s is damaged; use --recover to extract what can be salvaged", filePath)
+	}
+	return nil
+}
+
+func printHealthReport(w io.Writer, report *burp.HealthReport) {
+	status := "OK"
+	if !report.Healthy() {
+		status = "DAMAGED"
+	}
+	header := "ok"
+	if !report.HeaderOK {
+		header = "damaged"
+	}
+
+	fmt.Fprintf(w, "File: %s (%s)\n", report.Path, formatSize(report.FileSize))
+	fmt.Fprintf(w, "Status: %s\n", status)
+	fmt.Fprintf(w, "Header: %s\n", header)
+	if report.Truncated {
+		fmt.Fprintln(w, "Truncated: yes, records run past the end of the file")
+	}
+
+	fmt.Fprintf(w, "\n%-14s %10s %9s %14s\n", "Category", "Recovered", "Salvaged", "Unrecoverable")
+	fmt.Fprintf(w, "%s\n", strings.Repeat("-", 50))
+	for _, c := range report.Categories {
+		fmt.Fprintf(w, "%-14s %10d %9d %14d\n", c.Category, c.Recovered, c.Salvaged, c.Unrecoverable)
+	}
+
+	if len(report.Damaged) == 0 {
+		return
+	}
+
+	fmt.Fprintf(w, "\nDamaged regions (%d):\n", len(report.Damaged))
+	fmt.Fprintf(w, "%-12s %-13s %-9s %s\n", "Offset", "Category", "Outcome", "Reason")
+	for _, region := range report.Damaged {
+		outcome := "lost"
+		if region.Salvaged {
+			outcome = "salvaged"
+		}
+		fmt.Fprintf(w, "0x%-10x %-13s %-9s %s\n", region.Offset, region.Category, outcome, region.Reason)
+	}
+}
+
+func runCarveDeleted(cmd *cobra.Command, args []string) error {
+	filePath := args[0]
+
+	reader, err := openProject(filePath)
+	if err != nil {
+		return fmt.Errorf("failed to open file: %w", err)
+	}
+	defer reader.Close()
+
+	loadIssueDefinitions()
+
+	records, err := reader.CarveDeletedContext(cmd.Context())
+	if err = keepPartial(err); err != nil {
+		return fmt.Errorf("failed to carve deleted records: %w", err)
+	}
+
+	output := getOutputWriter()
+	defer closeOutputWriter(output)
+
+	opts := burp.ExportOptions{
+		IncludeBody: true,
+		PrettyPrint: true,
+		IncludeRaw:  true,
+	}
+	switch outputFormat {
+	case "json":
+		opts.Format = burp.FormatJSON
+		return burp.ExportCarvedRecords(output, records, opts)
+	case "jsonl":
+		opts.Format = burp.FormatJSONLines
+		return burp.ExportCarvedRecords(output, records, opts)
+	}
+
+	if len(records) == 0 {
+		fmt.Fprintln(output, "No unreachable records found")
+		return nil
+	}
+
+	fmt.Fprintf(output, "Recovered %d record(s) not reachable from the live project:\n\n", len(records))
+	fmt.Fprintf(output, "%-12s %-9s %-11s %-60s %s\n", "Offset", "Kind", "Status", "Item", "Reason")
+	fmt.Fprintf(output, "%s\n", strings.Repeat("-", 130))
+	for _, record := range records {
+		status := "deleted"
+		switch {
+		case record.Superseded:
+			status = "superseded"
+		case record.Unclassified:
+			status = "unknown"
+		}
+		fmt.Fprintf(output, "0x%-10x %-9s %-11s %-60s %s\n", record.Offset, record.Kind, status, truncate(carvedItemLabel(record), 60), record.Reason)
+	}
+
+	return nil
+}
+
+func carvedItemLabel(record burp.CarvedRecord) string {
+	if record.Issue != nil {
+		name := "Unknown"
+		if record.Issue.Definition != nil && record.Issue.Definition.Name != "" {
+			name = record.Issue.Definition.Name
+		}
+		where := record.Issue.Host + record.Issue.Path
+		if where == "" {
+			where = record.Issue.Location
+		}
+		return fmt.Sprintf("#%d %s (%s)", record.Issue.SerialNumber, name, where)
+	}
+	if record.Entry == nil {
+		return ""
+	}
+
+	label := record.Entry.Method + " " + record.Entry.URL
+	if record.Entry.URL == "" {
+		label = record.Entry.Method + " " + record.Entry.Path
+	}
+	if record.Entry.StatusCode > 0 {
+		label += fmt.Sprintf(" -> %d", record.Entry.StatusCode)
+	}
+	return label
+}
+
+func runDump(cmd *cobra.Command, args []string) error {
+	filePath := args[0]
+	if (len(args) == 2) == (dumpType != "") {
+		return errors.New("give either an offset or --type")
+	}
+
+	reader, err := openProject(filePath)
+	if err != nil {
+		return fmt.Errorf("failed to open file: %w", err)
+	}
+	defer reader.Close()
+
+	var offsets []int64
+	if dumpType != "" {
+		recordType, err := strconv.ParseUint(dumpType, 0, 16)
+		if err != nil {
+			return fmt.Errorf("invalid record type %q: %w", dumpType, err)
+		}
+		offsets, err = reader.FindTypedRecordsContext(cmd.Context(), uint16(recordType), dumpLimit)
+		if err = keepPartial(err); err != nil {
+			return fmt.Errorf("failed to find records: %w", err)
+		}
+		if len(offsets) == 0 {
+			return fmt.Errorf("no typed records of type 0x%04x found", recordType)
+		}
+	} else {
+		offset, err := strconv.ParseInt(args[1], 0, 64)
+		if err != nil {
+			return fmt.Errorf("invalid offset %q: %w", args[1], err)
+		}
+		offsets = []int64{offset}
+	}
+
+	opts := burp.DefaultDumpOptions()
+	opts.Depth = dumpDepth
+	opts.HexLength = dumpHexLength
+
+	nodes := make([]*burp.DumpNode, 0, len(offsets))
+	for _, offset := range offsets {
+		node, err := reader.Dump(offset, opts)
+		if err != nil {
+			return err
+		}
+		nodes = append(nodes, node)
+	}
+
+	output := getOutputWriter()
+	defer closeOutputWriter(output)
+
+	if outputFormat == "json" {
+		return outputJSON(output, nodes)
+	}
+
+	for i, node := range nodes {
+		if i > 0 {
+			fmt.Fprintln(output)
+		}
+		printDumpNode(output, node, "")
+	}
+	return nil
+}
+
+func printDumpNode(w io.Writer, node *burp.DumpNode, indent string) {
+	line := fmt.Sprintf("%s0x%08x  %s", indent, node.Offset, node.Kind)
+	if node.Summary != "" {
+		line += "  " + node.Summary
+	}
+	if node.Length > 0 {
+		line += fmt.Sprintf("  [%d bytes]", node.Length)
+	}
+	if node.Repeat {
+		line += "  (shown above)"
+	}
+	fmt.Fprintln(w, line)
+	if node.Error != "" {
+		fmt.Fprintf(w, "%s  ! %s\n", indent, node.Error)
+	}
+
+	for _, field := range node.Fields {
+		size := "?"
+		if field.Size > 0 {
+			size = strconv.Itoa(field.Size)
+		}
+		fmt.Fprintf(w, "%s  field 0x%02x  +0x%04x  size %-3s %-18s %s\n", indent, field.ID, field.Offset, size, field.Hex, field.Value)
+		if field.Target != nil {
+			printDumpNode(w, field.Target, indent+"      ")
+		}
+	}
+
+	for i, item := range node.Items {
+		fmt.Fprintf(w, "%s  [%d] -> 0x%x\n", indent, i, item.Pointer)
+		if item.Target != nil {
+			printDumpNode(w, item.Target, indent+"      ")
+		}
+	}
+	if node.MoreItems > 0 {
+		fmt.Fprintf(w, "%s  ... %d more\n", indent, node.MoreItems)
+	}
+
+	if node.Kind == burp.DumpHex {
+		printAnnotatedHex(w, node, indent+"  ")
+	} else if node.Hex != "" {
+		fmt.Fprintf(w, "%s  %s\n", indent, node.Hex)
+	}
+}
+
+// printAnnotatedHex prints a hexdump with absolute offsets, each line followed
+// by the annotations that fall on it.
+func printAnnotatedHex(w io.Writer, node *burp.DumpNode, indent string) {
+	data, err := hex.DecodeString(node.Hex)
+	if err != nil {
+		return
+	}
+
+	notes := node.Annotations
+	for i := 0; i < len(data); i += 16 {
+		chunk := data[i:min(i+16, len(data))]
+
+		var hexPart, textPart strings.Builder
+		for j := 0; j < 16; j++ {
+			if j < len(chunk) {
+				fmt.Fprintf(&hexPart, "%02x ", chunk[j])
+				if chunk[j] >= 0x20 && chunk[j] < 0x7f {
+					textPart.WriteByte(chunk[j])
+				} else {
+					textPart.WriteByte('.')
+				}
+			} else {
+				hexPart.WriteString("   ")
+			}
+			if j == 7 {
+				hexPart.WriteByte(' ')
+			}
+		}
+		fmt.Fprintf(w, "%s0x%08x  %s |%s|\n", indent, node.Offset+int64(i), hexPart.String(), textPart.String())
+
+		lineEnd := node.Offset + int64(i+len(chunk))
+		for len(notes) > 0 && notes[0].Offset < lineEnd {
+			fmt.Fprintf(w, "%s            ^ 0x%08x: %s\n", indent, notes[0].Offset, notes[0].Note)
+			notes = notes[1:]
+		}
+	}
+}
+
 func runIssueDefinitions(cmd *cobra.Command, args []string) error {
 	loaded := false
 
@@ -782,7 +2013,7 @@ func resolveBurpJarPath() (string, bool) {
 	return jarPath, autoDetected
 }
 
-func buildFilter() *burp.Filter {
+func buildFilter() (*burp.Filter, error) {
 	f := burp.NewFilter()
 	hasFilter := false
 
@@ -845,11 +2076,84 @@ func buildFilter() *burp.Filter {
 		hasFilter = true
 	}
 
+	if toolFilter != "" {
+		var tools []burp.ToolType
+		for _, name := range strings.Split(toolFilter, ",") {
+			if strings.TrimSpace(name) == "" {
+				continue
+			}
+			tool, ok := burp.ParseToolType(name)
+			if !ok {
+				return nil, fmt.Errorf("unknown tool: %s", strings.TrimSpace(name))
+			}
+			tools = append(tools, tool)
+		}
+		if len(tools) > 0 {
+			f.WithTool(tools...)
+			hasFilter = true
+		}
+	}
+
+	if highlightFilter != "" {
+		var colours []string
+		for _, c := range strings.Split(highlightFilter, ",") {
+			c = strings.ToLower(strings.TrimSpace(c))
+			if c == "" {
+				continue
+			}
+			if c != "any" && c != "none" && !isHighlightColour(c) {
+				return nil, fmt.Errorf("unknown highlight colour: %s (valid: %s, any, none)", c, strings.Join(burp.HighlightColours(), ", "))
+			}
+			colours = append(colours, c)
+		}
+		if len(colours) > 0 {
+			f.WithHighlight(colours...)
+			hasFilter = true
+		}
+	}
+
+	if commentFilter != "" {
+		f.WithCommentContains(commentFilter)
+		hasFilter = true
+	}
+
 	if !hasFilter {
-		return nil
+		return nil, nil
+	}
+
+	return f, nil
+}
+
+// applyScopeFilter adds the project's Target scope to filter when --in-scope
+// is set, creating a filter if none was built from the other flags.
+func applyScopeFilter(ctx context.Context, reader *burp.Reader, filter *burp.Filter) (*burp.Filter, error) {
+	if !inScopeOnly {
+		return filter, nil
 	}
 
-	return f
+	// The scope is needed to filter whatever history was read before a
+	// Ctrl-C, so its scan is not cut short.
+	scope, err := reader.TargetScopeContext(context.WithoutCancel(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read target scope: %w", err)
+	}
+	if scope == nil {
+		return nil, fmt.Errorf("project does not define a Target scope")
+	}
+	if !quiet {
+		for _, err := range scope.RuleErrors() {
+			fmt.Fprintf(os.Stderr, "Warning: Target scope %v; the rule never matches\n", err)
+		}
+	}
+
+	if filter == nil {
+		filter = burp.NewFilter()
+	}
+	return filter.WithScope(scope), nil
+}
+
+func isHighlightColour(c string) bool {
+	return slices.Contains(burp.HighlightColours(), c)
 }
 
 func getOutputWriter() *os.File {
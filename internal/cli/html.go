@@ -126,6 +126,7 @@ type historySectionView struct {
 
 type historyEntryView struct {
 	ID              uint64
+	Time            string
 	Method          string
 	MethodClass     string
 	Host            string
@@ -133,6 +134,8 @@ type historyEntryView struct {
 	Status          int
 	StatusClass     string
 	Size            string
+	Highlight       string
+	Comment         string
 	Expandable      bool
 	RequestPreview  string
 	ResponsePreview string
@@ -444,6 +447,7 @@ func buildHistorySection(entries []burp.HTTPEntry, opts ReportOptions) *historyS
 	for _, entry := range display {
 		row := historyEntryView{
 			ID:          entry.ID,
+			Time:        "-",
 			Method:      entry.Method,
 			MethodClass: "method-" + entry.Method,
 			Host:        entry.Host,
@@ -451,8 +455,13 @@ func buildHistorySection(entries []burp.HTTPEntry, opts ReportOptions) *historyS
 			Status:      entry.StatusCode,
 			StatusClass: getStatusClass(entry.StatusCode),
 			Size:        formatSizeShort(entry.ContentLength),
+			Highlight:   entry.Highlight,
+			Comment:     entry.Comment,
 			Expandable:  opts.IncludeBodies,
 		}
+		if !entry.Timestamp.IsZero() {
+			row.Time = entry.Timestamp.Format("2006-01-02 15:04:05")
+		}
 
 		if opts.IncludeBodies {
 			row.RequestPreview = formatRequestPreview(entry, opts.MaxBodySize)
@@ -474,37 +483,33 @@ func buildSiteMapSection(siteMap *burp.SiteMap) *siteMapSectionView {
 		return section
 	}
 
-	hosts := make([]string, 0, len(siteMap.Root))
-	for host := range siteMap.Root {
-		hosts = append(hosts, host)
+	origins := make([]string, 0, len(siteMap.Root))
+	for origin := range siteMap.Root {
+		origins = append(origins, origin)
 	}
-	sort.Strings(hosts)
+	sort.Strings(origins)
 
-		for _, host := range hosts {
-			node := siteMap.Root[host]
-			section.Hosts = append(section.Hosts, buildSiteMapNode(host, host, node))
+		for _, origin := range origins {
+			node := siteMap.Root[origin]
+			section.Hosts = append(section.Hosts, buildSiteMapNode(origin, origin, node))
 		}
 
 		section.HasData = len(section.Hosts) > 0
 		return section
 	}
 
-func buildSiteMapNode(host string, label string, node *burp.SiteMapNode) siteMapNodeView {
-	protocol := selectSiteMapProtocol(node)
+// buildSiteMapNode builds the view of node, which lies under the site map
+// root for origin.
+func buildSiteMapNode(origin string, label string, node *burp.SiteMapNode) siteMapNodeView {
 	path := "/"
 	if node != nil && node.Path != "" {
 		path = node.Path
 	}
 
-	copyValue := host + path
-	if protocol != "" {
-		copyValue = protocol + "://" + copyValue
-	}
-
 	view := siteMapNodeView{
 		Label:    label,
 		Count:    countSiteMapEntries(node),
-		CopyValue: copyValue,
+		CopyValue: origin + path,
 	}
 
 	if node == nil || len(node.Children) == 0 {
@@ -517,43 +522,12 @@ func buildSiteMapNode(host string, label string, node *burp.SiteMapNode) siteMap
 	}
 	sort.Strings(keys)
 	for _, key := range keys {
-		view.Children = append(view.Children, buildSiteMapNode(host, key, node.Children[key]))
+		view.Children = append(view.Children, buildSiteMapNode(origin, key, node.Children[key]))
 	}
 
 	return view
 }
 
-func selectSiteMapProtocol(node *burp.SiteMapNode) string {
-	if node == nil {
-		return ""
-	}
-
-	hasHTTP := false
-	for _, entry := range node.Entries {
-		switch strings.ToLower(entry.Protocol) {
-		case "https", "wss":
-			return "https"
-		case "http", "ws":
-			hasHTTP = true
-		}
-	}
-
-	for _, child := range node.Children {
-		proto := selectSiteMapProtocol(child)
-		if proto == "https" {
-			return "https"
-		}
-		if proto == "http" {
-			hasHTTP = true
-		}
-	}
-
-	if hasHTTP {
-		return "http"
-	}
-	return ""
-}
-
 func countSiteMapEntries(node *burp.SiteMapNode) int {
 	if node == nil {
 		return 0
@@ -782,21 +756,21 @@ func getStatusClass(code int) string {
 }
 
 func formatRequestPreview(entry burp.HTTPEntry, maxBodySize int) string {
-	if entry.Request == nil || entry.Request.Raw == nil {
+	if entry.Request.Size() == 0 {
 		return entry.Method + " " + entry.Path + " " + entry.Protocol
 	}
 
-	raw := string(entry.Request.Raw)
+	raw := string(entry.Request.DecodedRaw())
 	raw = truncateReportString(raw, maxBodySize)
 	return raw
 }
 
 func formatResponsePreview(entry burp.HTTPEntry, maxBodySize int) string {
-	if entry.Response == nil || entry.Response.Raw == nil {
+	if entry.Response.Size() == 0 {
 		return "No response"
 	}
 
-	raw := string(entry.Response.Raw)
+	raw := string(entry.Response.DecodedRaw())
 	raw = truncateReportString(raw, maxBodySize)
 	return raw
 }
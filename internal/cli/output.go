@@ -22,19 +22,25 @@ func outputTable(w io.Writer, entries []burp.HTTPEntry) error {
 	}
 
 	idWidth := 8
+	timeWidth := 19
+	toolWidth := 9
 	methodWidth := 7
 	statusWidth := 6
 	hostWidth := 30
 	pathWidth := 50
 	sizeWidth := 10
+	noteWidth := 40
 
-	header := fmt.Sprintf("%-*s %-*s %-*s %-*s %-*s %-*s",
+	header := fmt.Sprintf("%-*s %-*s %-*s %-*s %-*s %-*s %-*s %-*s %-*s",
 		idWidth, "ID",
+		timeWidth, "TIME",
+		toolWidth, "TOOL",
 		methodWidth, "METHOD",
 		statusWidth, "STATUS",
 		hostWidth, "HOST",
 		pathWidth, "PATH",
-		sizeWidth, "SIZE")
+		sizeWidth, "SIZE",
+		noteWidth, "NOTE")
 	fmt.Fprintln(w, header)
 	fmt.Fprintln(w, strings.Repeat("-", len(header)+10))
 
@@ -43,18 +49,36 @@ func outputTable(w io.Writer, entries []burp.HTTPEntry) error {
 		path := truncateString(entry.Path, pathWidth)
 		size := formatSizeShort(entry.ContentLength)
 
+		timeStr := "-"
+		if !entry.Timestamp.IsZero() {
+			timeStr = entry.Timestamp.Format("2006-01-02 15:04:05")
+		}
+
 		statusStr := "-"
 		if entry.StatusCode > 0 {
 			statusStr = fmt.Sprintf("%d", entry.StatusCode)
 		}
 
-		fmt.Fprintf(w, "%-*d %-*s %-*s %-*s %-*s %-*s\n",
+		toolStr := "-"
+		if entry.ToolSource != burp.ToolUnknown {
+			toolStr = entry.ToolSource.String()
+		}
+
+		note := entry.Comment
+		if entry.Highlight != "" {
+			note = strings.TrimSpace("[" + entry.Highlight + "] " + note)
+		}
+
+		fmt.Fprintf(w, "%-*d %-*s %-*s %-*s %-*s %-*s %-*s %-*s %-*s\n",
 			idWidth, entry.ID,
+			timeWidth, timeStr,
+			toolWidth, toolStr,
 			methodWidth, entry.Method,
 			statusWidth, statusStr,
 			hostWidth, host,
 			pathWidth, path,
-			sizeWidth, size)
+			sizeWidth, size,
+			noteWidth, truncateString(note, noteWidth))
 	}
 
 	fmt.Fprintf(w, "\nTotal: %d entries\n", len(entries))
@@ -0,0 +1,98 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bmm-sec/burp-insights/pkg/burp"
+)
+
+// progressInterval is the shortest time between redraws of the progress bar.
+const progressInterval = 100 * time.Millisecond
+
+const progressBarWidth = 30
+
+// progressBar draws scan and parse progress on one stderr line, redrawing it
+// in place. Scans may report from several goroutines at once.
+type progressBar struct {
+	mu    sync.Mutex
+	drawn time.Time
+	width int
+}
+
+// newProgressBar returns a progress bar, or nil when --quiet is set or stderr
+// is not a terminal, where the redraws would only clutter logs.
+func newProgressBar() *progressBar {
+	if quiet || !isTerminal(os.Stderr) {
+		return nil
+	}
+	return &progressBar{}
+}
+
+func isTerminal(f *os.File) bool {
+	stat, err := f.Stat()
+	return err == nil && stat.Mode()&os.ModeCharDevice != 0
+}
+
+// scan is the ScanProgressFunc for the reader.
+func (b *progressBar) scan(p burp.ScanProgress) {
+	if p.Done {
+		b.clear()
+		return
+	}
+	b.draw(p.Scan, p.BytesScanned, p.BytesTotal, fmt.Sprintf("%s / %s, %d found",
+		formatSize(p.BytesScanned), formatSize(p.BytesTotal), p.Records))
+}
+
+// parse is the ProgressFunc for HTTP history parsing.
+func (b *progressBar) parse(done, total int) {
+	if done >= total {
+		b.clear()
+		return
+	}
+	b.draw("parse", int64(done), int64(total), fmt.Sprintf("%d / %d records", done, total))
+}
+
+func (b *progressBar) draw(name string, n, total int64, detail string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := time.Now()
+	if now.Sub(b.drawn) < progressInterval {
+		return
+	}
+	b.drawn = now
+
+	fraction := 0.0
+	if total > 0 {
+		fraction = min(float64(n)/float64(total), 1)
+	}
+	filled := int(fraction * progressBarWidth)
+	line := fmt.Sprintf("%-10s [%s%s] %3.0f%% %s", name,
+		strings.Repeat("=", filled), strings.Repeat(" ", progressBarWidth-filled),
+		fraction*100, detail)
+
+	b.erase()
+	fmt.Fprint(os.Stderr, line)
+	b.width = len(line)
+}
+
+// clear removes the bar so the next scan, or the command's own output,
+// starts on a clean line.
+func (b *progressBar) clear() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.erase()
+	b.drawn = time.Time{}
+}
+
+func (b *progressBar) erase() {
+	if b.width > 0 {
+		fmt.Fprintf(os.Stderr, "\r%s\r", strings.Repeat(" ", b.width))
+		b.width = 0
+	}
+}
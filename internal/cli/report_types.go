@@ -83,14 +83,15 @@ func buildSiteMapFromHistory(entries []burp.HTTPEntry) *burp.SiteMap {
 			continue
 		}
 
-		hostNode, ok := siteMap.Root[entry.Host]
+		origin := entry.Origin()
+		hostNode, ok := siteMap.Root[origin]
 		if !ok {
 			hostNode = &burp.SiteMapNode{
 				Host:     entry.Host,
 				Path:     "/",
 				Children: make(map[string]*burp.SiteMapNode),
 			}
-			siteMap.Root[entry.Host] = hostNode
+			siteMap.Root[origin] = hostNode
 		}
 
 		path := entry.Path
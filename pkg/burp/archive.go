@@ -0,0 +1,300 @@
+package burp
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/bmm-sec/burp-insights/internal/binary"
+)
+
+// Projects are often passed around gzipped or inside zip and tar archives.
+// The parser needs random access, so these are unpacked to a temporary file
+// first, which is removed when the parser is closed.
+
+type archiveFormat int
+
+const (
+	archiveNone archiveFormat = iota
+	archiveGzip
+	archiveZip
+	archiveTar
+)
+
+func (f archiveFormat) String() string {
+	switch f {
+	case archiveGzip:
+		return "gzip"
+	case archiveZip:
+		return "zip"
+	case archiveTar:
+		return "tar"
+	default:
+		return "none"
+	}
+}
+
+// maxArchiveNesting bounds how many layers are unpacked, e.g. two for a
+// .tar.gz.
+const maxArchiveNesting = 4
+
+const (
+	tarMagicOffset = 257
+	sniffLen       = 512
+)
+
+var (
+	gzipMagic = []byte{0x1f, 0x8b}
+	zipMagic  = []byte("PK\x03\x04")
+	tarMagic  = []byte("ustar")
+)
+
+// sniffArchive tells gzip, zip and tar data apart by their magic bytes.
+func sniffArchive(src io.ReaderAt) archiveFormat {
+	head := make([]byte, sniffLen)
+	n, _ := src.ReadAt(head, 0)
+	head = head[:n]
+
+	switch {
+	case bytes.HasPrefix(head, gzipMagic):
+		return archiveGzip
+	case bytes.HasPrefix(head, zipMagic):
+		return archiveZip
+	case len(head) >= tarMagicOffset+len(tarMagic) && bytes.Equal(head[tarMagicOffset:tarMagicOffset+len(tarMagic)], tarMagic):
+		return archiveTar
+	default:
+		return archiveNone
+	}
+}
+
+// projectSource holds the bytes the parser reads. spill is the temporary
+// file they were unpacked to, if any.
+type projectSource struct {
+	reader *binary.Reader
+	spill  *os.File
+}
+
+func (s projectSource) close() error {
+	err := s.reader.Close()
+	if rmErr := removeSpill(s.spill); err == nil {
+		err = rmErr
+	}
+	return err
+}
+
+// openProjectFile opens the project at path, unpacking it first when it is
+// compressed or archived. Plain project files are read in place.
+func openProjectFile(path, tempDir string) (projectSource, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return projectSource{}, err
+	}
+	defer f.Close()
+
+	if sniffArchive(f) == archiveNone {
+		reader, err := binary.NewReader(path)
+		if err != nil {
+			return projectSource{}, err
+		}
+		return projectSource{reader: reader}, nil
+	}
+
+	stat, err := f.Stat()
+	if err != nil {
+		return projectSource{}, err
+	}
+	return unpackProject(f, stat.Size(), tempDir)
+}
+
+// openProjectStream copies a project from a stream, such as stdin, to a
+// temporary file and unpacks it from there when it is an archive.
+func openProjectStream(r io.Reader, tempDir string) (projectSource, error) {
+	f, err := spillToTemp(r, tempDir)
+	if err != nil {
+		return projectSource{}, err
+	}
+	stat, err := f.Stat()
+	if err != nil {
+		removeSpill(f)
+		return projectSource{}, err
+	}
+
+	src, err := unpackProject(f, stat.Size(), tempDir)
+	if err != nil {
+		removeSpill(f)
+		return projectSource{}, err
+	}
+	if src.spill == nil {
+		src.spill = f
+	} else {
+		removeSpill(f)
+	}
+	return src, nil
+}
+
+// unpackProject returns a source reading the project held in the first size
+// bytes of src, unpacking gzip, zip and tar layers until a project remains.
+// src itself is never closed.
+func unpackProject(src io.ReaderAt, size int64, tempDir string) (projectSource, error) {
+	var spill *os.File
+	for range maxArchiveNesting {
+		format := sniffArchive(io.NewSectionReader(src, 0, size))
+		if format == archiveNone {
+			return projectSource{reader: binary.NewReaderAt(src, size), spill: spill}, nil
+		}
+
+		next, err := extractArchive(format, src, size, tempDir)
+		removeSpill(spill)
+		if err != nil {
+			return projectSource{}, fmt.Errorf("unpack %s project: %w", format, err)
+		}
+		spill = next
+
+		stat, err := spill.Stat()
+		if err != nil {
+			removeSpill(spill)
+			return projectSource{}, err
+		}
+		src, size = spill, stat.Size()
+	}
+
+	removeSpill(spill)
+	return projectSource{}, fmt.Errorf("%w: archives nested more than %d deep", ErrInvalidFile, maxArchiveNesting)
+}
+
+// extractArchive writes the project inside one archive layer to a temporary
+// file.
+func extractArchive(format archiveFormat, src io.ReaderAt, size int64, tempDir string) (*os.File, error) {
+	switch format {
+	case archiveGzip:
+		gz, err := gzip.NewReader(io.NewSectionReader(src, 0, size))
+		if err != nil {
+			return nil, err
+		}
+		defer gz.Close()
+		return spillToTemp(gz, tempDir)
+
+	case archiveZip:
+		zr, err := zip.NewReader(src, size)
+		if err != nil {
+			return nil, err
+		}
+		var files []*zip.File
+		var names []string
+		for _, f := range zr.File {
+			if !f.FileInfo().IsDir() {
+				files = append(files, f)
+				names = append(names, f.Name)
+			}
+		}
+		i, err := pickArchiveEntry(names)
+		if err != nil {
+			return nil, err
+		}
+		rc, err := files[i].Open()
+		if err != nil {
+			return nil, err
+		}
+		defer rc.Close()
+		return spillToTemp(rc, tempDir)
+
+	case archiveTar:
+		var names []string
+		err := walkTar(src, size, func(hdr *tar.Header, _ io.Reader) bool {
+			names = append(names, hdr.Name)
+			return true
+		})
+		if err != nil {
+			return nil, err
+		}
+		want, err := pickArchiveEntry(names)
+		if err != nil {
+			return nil, err
+		}
+
+		var spill *os.File
+		var spillErr error
+		i := 0
+		err = walkTar(src, size, func(_ *tar.Header, r io.Reader) bool {
+			if i != want {
+				i++
+				return true
+			}
+			spill, spillErr = spillToTemp(r, tempDir)
+			return false
+		})
+		if err == nil {
+			err = spillErr
+		}
+		if err != nil {
+			removeSpill(spill)
+			return nil, err
+		}
+		return spill, nil
+	}
+
+	return nil, fmt.Errorf("unknown archive format %d", format)
+}
+
+// walkTar calls fn for every regular file in a tar archive, in order, until
+// fn returns false.
+func walkTar(src io.ReaderAt, size int64, fn func(hdr *tar.Header, r io.Reader) bool) error {
+	tr := tar.NewReader(io.NewSectionReader(src, 0, size))
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+		if !fn(hdr, tr) {
+			return nil
+		}
+	}
+}
+
+// pickArchiveEntry chooses the project among an archive's files: the first
+// one named *.burp, or the only file when there is just one.
+func pickArchiveEntry(names []string) (int, error) {
+	for i, name := range names {
+		if strings.EqualFold(path.Ext(name), ".burp") {
+			return i, nil
+		}
+	}
+	if len(names) == 1 {
+		return 0, nil
+	}
+	return 0, fmt.Errorf("%w: archive holds %d files and none is named *.burp", ErrInvalidFile, len(names))
+}
+
+// spillToTemp copies r to a new temporary file in dir, or in the default
+// temporary directory when dir is empty.
+func spillToTemp(r io.Reader, dir string) (*os.File, error) {
+	f, err := os.CreateTemp(dir, "burp-insights-*.burp")
+	if err != nil {
+		return nil, fmt.Errorf("create temporary project file: %w", err)
+	}
+	if _, err := io.Copy(f, r); err != nil {
+		removeSpill(f)
+		return nil, fmt.Errorf("write temporary project file: %w", err)
+	}
+	return f, nil
+}
+
+func removeSpill(f *os.File) error {
+	if f == nil {
+		return nil
+	}
+	f.Close()
+	return os.Remove(f.Name())
+}
@@ -0,0 +1,187 @@
+package burp
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/andybalholm/brotli"
+)
+
+// DecodedBody returns the message body with chunked transfer encoding and any
+// gzip, deflate or brotli content encoding removed. Body itself always keeps
+// the bytes as stored by Burp. When a layer cannot be decoded the body is
+// returned as decoded up to that layer. The body is decoded once and kept, so
+// repeated calls are cheap.
+func (m *HTTPMessage) DecodedBody() []byte {
+	switch {
+	case m == nil:
+		return nil
+	case !m.hasBodyCodings():
+		return m.Body()
+	case m.source != nil:
+		return m.source.decodedBody(m)
+	}
+
+	m.decodeOnce.Do(func() {
+		m.decoded, _ = decodeBody(m.Headers, m.Body())
+	})
+	return m.decoded
+}
+
+// DecodedRaw returns the raw message with its body replaced by DecodedBody.
+// The headers are left untouched, so they still describe the encoded body.
+func (m *HTTPMessage) DecodedRaw() []byte {
+	if m == nil {
+		return nil
+	}
+	raw, body := m.Raw(), m.Body()
+	if len(body) == 0 || len(body) > len(raw) {
+		return raw
+	}
+
+	decoded := m.DecodedBody()
+	if bytes.Equal(decoded, body) {
+		return raw
+	}
+
+	head := raw[:len(raw)-len(body)]
+	out := make([]byte, 0, len(head)+len(decoded))
+	out = append(out, head...)
+	return append(out, decoded...)
+}
+
+// decodeBody undoes the transfer and content codings named in headers. It
+// returns the partially decoded body together with the first error.
+func decodeBody(headers map[string][]string, body []byte) ([]byte, error) {
+	if len(body) == 0 {
+		return body, nil
+	}
+
+	for _, coding := range headerCodings(headers, "Transfer-Encoding") {
+		if coding != "chunked" {
+			continue
+		}
+		decoded, err := decodeChunked(body)
+		if err != nil {
+			return body, err
+		}
+		body = decoded
+	}
+
+	// Content codings are listed in the order they were applied.
+	codings := headerCodings(headers, "Content-Encoding")
+	for i := len(codings) - 1; i >= 0; i-- {
+		decoded, err := decodeContentCoding(body, codings[i])
+		if err != nil {
+			return body, err
+		}
+		body = decoded
+	}
+
+	return body, nil
+}
+
+func headerCodings(headers map[string][]string, name string) []string {
+	var codings []string
+	for key, values := range headers {
+		if !strings.EqualFold(key, name) {
+			continue
+		}
+		for _, value := range values {
+			for _, coding := range strings.Split(value, ",") {
+				coding = strings.ToLower(strings.TrimSpace(coding))
+				if coding != "" && coding != "identity" {
+					codings = append(codings, coding)
+				}
+			}
+		}
+	}
+	return codings
+}
+
+// decodeChunked removes chunked transfer encoding. Trailers are discarded. A
+// body truncated mid-chunk returns the data read so far without an error,
+// since Burp keeps partial responses for aborted requests.
+func decodeChunked(body []byte) ([]byte, error) {
+	var out bytes.Buffer
+
+	rest := body
+	for len(rest) > 0 {
+		lineEnd := bytes.IndexByte(rest, '\n')
+		if lineEnd == -1 {
+			break
+		}
+		sizeField := strings.TrimSpace(string(rest[:lineEnd]))
+		if idx := strings.IndexByte(sizeField, ';'); idx >= 0 {
+			sizeField = strings.TrimSpace(sizeField[:idx])
+		}
+		size, err := strconv.ParseInt(sizeField, 16, 64)
+		if err != nil || size < 0 {
+			return nil, fmt.Errorf("invalid chunk size %q", sizeField)
+		}
+		rest = rest[lineEnd+1:]
+
+		if size == 0 {
+			return out.Bytes(), nil
+		}
+		if int64(out.Len())+size > MaxBodySize {
+			return nil, errors.New("chunked body exceeds maximum size")
+		}
+		if size > int64(len(rest)) {
+			out.Write(rest)
+			return out.Bytes(), nil
+		}
+
+		out.Write(rest[:size])
+		rest = bytes.TrimPrefix(rest[size:], []byte("\r"))
+		rest = bytes.TrimPrefix(rest, []byte("\n"))
+	}
+
+	return out.Bytes(), nil
+}
+
+func decodeContentCoding(body []byte, coding string) ([]byte, error) {
+	var r io.Reader
+	switch coding {
+	case "gzip", "x-gzip":
+		gz, err := gzip.NewReader(bytes.NewReader(body))
+		if err != nil {
+			return nil, fmt.Errorf("gzip: %w", err)
+		}
+		defer gz.Close()
+		r = gz
+	case "deflate":
+		// Servers disagree on whether deflate means zlib-wrapped or raw
+		// DEFLATE data, so accept both.
+		zr, err := zlib.NewReader(bytes.NewReader(body))
+		if err != nil {
+			fr := flate.NewReader(bytes.NewReader(body))
+			defer fr.Close()
+			r = fr
+		} else {
+			defer zr.Close()
+			r = zr
+		}
+	case "br":
+		r = brotli.NewReader(bytes.NewReader(body))
+	default:
+		return nil, fmt.Errorf("unsupported content encoding: %s", coding)
+	}
+
+	decoded, err := io.ReadAll(io.LimitReader(r, MaxBodySize+1))
+	if int64(len(decoded)) > MaxBodySize {
+		return nil, fmt.Errorf("decoded %s body exceeds maximum size", coding)
+	}
+	// A truncated stream still yields whatever was decoded before the cut.
+	if err != nil && (len(decoded) == 0 || !errors.Is(err, io.ErrUnexpectedEOF)) {
+		return nil, fmt.Errorf("%s: %w", coding, err)
+	}
+	return decoded, nil
+}
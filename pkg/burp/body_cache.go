@@ -0,0 +1,80 @@
+package burp
+
+import (
+	"container/list"
+	"sync"
+)
+
+// bodyCache keeps recently loaded message bytes, and the decoded bodies of
+// those messages, keyed by file offset, up to a byte budget. The least
+// recently used items are evicted first. A nil cache stores nothing, so every
+// access rereads the file.
+type bodyCache struct {
+	mu     sync.Mutex
+	budget int64
+	used   int64
+	order  *list.List
+	items  map[bodyCacheKey]*list.Element
+}
+
+// bodyCacheKey tells a message's stored bytes apart from its decoded body.
+type bodyCacheKey struct {
+	offset  int64
+	decoded bool
+}
+
+type bodyCacheItem struct {
+	key  bodyCacheKey
+	data []byte
+}
+
+func newBodyCache(budget int64) *bodyCache {
+	if budget <= 0 {
+		return nil
+	}
+	return &bodyCache{
+		budget: budget,
+		order:  list.New(),
+		items:  make(map[bodyCacheKey]*list.Element),
+	}
+}
+
+func (c *bodyCache) get(key bodyCacheKey) ([]byte, bool) {
+	if c == nil {
+		return nil, false
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, ok := c.items[key]
+	if !ok {
+		return nil, false
+	}
+	c.order.MoveToFront(elem)
+	return elem.Value.(*bodyCacheItem).data, true
+}
+
+func (c *bodyCache) add(key bodyCacheKey, data []byte) {
+	if c == nil || int64(len(data)) > c.budget {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, ok := c.items[key]; ok {
+		return
+	}
+
+	c.items[key] = c.order.PushFront(&bodyCacheItem{key: key, data: data})
+	c.used += int64(len(data))
+
+	for c.used > c.budget {
+		oldest := c.order.Back()
+		item := oldest.Value.(*bodyCacheItem)
+		c.order.Remove(oldest)
+		delete(c.items, item.key)
+		c.used -= int64(len(item.data))
+	}
+}
@@ -0,0 +1,389 @@
+package burp
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"sort"
+)
+
+// CarvedKind is the kind of record a carved item was recovered from.
+type CarvedKind string
+
+const (
+	CarvedHistoryItem CarvedKind = "history"
+	CarvedRequest     CarvedKind = "request"
+	CarvedRepeater    CarvedKind = "repeater"
+	CarvedIssue       CarvedKind = "issue"
+)
+
+// CarvedRecord is an item recovered from bytes that nothing in the live
+// project refers to any more: history items and Scanner issues deleted by the
+// user, older versions of records Burp left behind when it rewrote them, and
+// requests that no record read by this package accounts for. Carved records
+// are never returned by HTTPHistory, RepeaterTabs or ScannerIssueMetas,
+// except that a reader recovering from a missing or damaged history table
+// returns unreferenced history items as history.
+//
+// Superseded is set for older versions of records that are still live, such
+// as a history item before its comment was edited. Unclassified is set for
+// requests found by scanning for request lines: they may have been deleted,
+// but may as well belong to live records this package does not read, such
+// as site map, Logger, Intruder result or Scanner audit items. Records with
+// neither set were deleted outright.
+type CarvedRecord struct {
+	Kind         CarvedKind
+	Offset       int64
+	Superseded   bool
+	Unclassified bool
+	Reason       string
+	Entry        *HTTPEntry        // history, request and repeater records
+	Issue        *ScannerIssueMeta // issue records
+	TabName      string            // repeater records
+}
+
+// carver tracks what the live project already accounts for, so that every
+// message is reported at most once and never when it is still reachable.
+type carver struct {
+	p *Parser
+	s *scanRun
+
+	// Request offsets of live and already carved history items.
+	claimedRequests map[int64]bool
+
+	// Digests of message contents that are live or already carved, for the
+	// records whose message offsets are not kept.
+	claimedContent map[[sha256.Size]byte]bool
+}
+
+func (c *carver) claimContent(raw []byte) {
+	if len(raw) > 0 {
+		c.claimedContent[sha256.Sum256(raw)] = true
+	}
+}
+
+func (c *carver) isClaimed(raw []byte) bool {
+	return c.claimedContent[sha256.Sum256(raw)]
+}
+
+func (c *carver) claimMessage(msg *HTTPMessage) {
+	c.claimContent(msg.Raw())
+}
+
+// CarveDeleted walks the records that the live record graph no longer
+// reaches and recovers the history items, request/response pairs, Repeater
+// content and Scanner issues they hold, in file order.
+//
+// Without a history table nothing can be told apart from live history, so
+// only Repeater content and Scanner issues are carved.
+func (p *Parser) CarveDeleted() ([]CarvedRecord, error) {
+	return p.CarveDeletedContext(context.Background())
+}
+
+// CarveDeletedContext is CarveDeleted that stops when ctx is cancelled,
+// returning the records carved so far with ctx.Err(). The live records are
+// read first, so a cancelled carve may return nothing.
+func (p *Parser) CarveDeletedContext(ctx context.Context) ([]CarvedRecord, error) {
+	s := p.startScan(ctx, ScanCarve)
+	defer s.done()
+
+	live, haveTable, err := p.liveHistoryLocations(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &carver{
+		p:               p,
+		s:               s,
+		claimedRequests: make(map[int64]bool, len(live)),
+		claimedContent:  make(map[[sha256.Size]byte]bool),
+	}
+	for _, loc := range live {
+		c.claimedRequests[loc.RequestOffset] = true
+	}
+	liveIssues, err := p.ScanScannerIssueMetasContext(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.claimLiveContent(liveIssues); err != nil {
+		return nil, err
+	}
+
+	var steps []func() ([]CarvedRecord, error)
+	if haveTable {
+		steps = append(steps, func() ([]CarvedRecord, error) { return c.carveHistoryItems(live) })
+	}
+	steps = append(steps, func() ([]CarvedRecord, error) { return c.carveIssues(liveIssues) }, c.carveRepeaterTabs)
+	if haveTable {
+		steps = append(steps, c.carveRequests)
+	}
+
+	var carved []CarvedRecord
+	for _, step := range steps {
+		var records []CarvedRecord
+		records, err = step()
+		carved = append(carved, records...)
+		if err != nil {
+			break
+		}
+	}
+
+	sort.SliceStable(carved, func(i, j int) bool {
+		return carved[i].Offset < carved[j].Offset
+	})
+	return carved, err
+}
+
+// liveHistoryLocations returns the history items reachable from the history
+// table, ignoring recovery mode, and whether the project has a table at all.
+func (p *Parser) liveHistoryLocations(ctx context.Context) ([]HTTPRecordLocation, bool, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	s := p.startScan(ctx, ScanHistory)
+	defer s.done()
+
+	tableOffset, err := p.findProxyHistoryTable(s)
+	if err != nil {
+		return nil, false, err
+	}
+	if tableOffset < 0 {
+		return nil, false, nil
+	}
+
+	locations, _, err := p.readProxyHistoryTable(s, tableOffset)
+	if err != nil {
+		return nil, true, err
+	}
+	return locations, true, nil
+}
+
+// claimLiveContent records the messages held by live records other than
+// history items, which may also be found by scanning for request lines.
+func (c *carver) claimLiveContent(issues []ScannerIssueMeta) error {
+	ctx := c.s.ctx
+
+	tabs, err := c.p.ScanRepeaterTabsContext(ctx)
+	if err != nil {
+		return err
+	}
+	for _, tab := range tabs {
+		c.claimMessage(tab.Request)
+		c.claimMessage(tab.Response)
+		for _, send := range tab.History {
+			c.claimMessage(send.Request)
+			c.claimMessage(send.Response)
+		}
+	}
+
+	attacks, err := c.p.ScanIntruderAttacksContext(ctx)
+	if err != nil {
+		return err
+	}
+	for _, attack := range attacks {
+		c.claimContent([]byte(attack.BaseRequest))
+	}
+
+	conns, err := c.p.ScanWebSocketConnectionsContext(ctx)
+	if err != nil {
+		return err
+	}
+	for _, conn := range conns {
+		c.claimMessage(conn.UpgradeRequest)
+		c.claimMessage(conn.UpgradeResponse)
+	}
+
+	interactions, err := c.p.ScanCollaboratorInteractionsContext(ctx)
+	if err != nil {
+		return err
+	}
+	for _, interaction := range interactions {
+		c.claimContent(interaction.Data)
+	}
+
+	for _, issue := range issues {
+		c.claimEvidence(issue)
+	}
+	return nil
+}
+
+func (c *carver) claimEvidence(issue ScannerIssueMeta) {
+	for _, ev := range issue.Evidence {
+		if ev.Request != nil {
+			c.claimContent([]byte(ev.Request.Raw))
+		}
+		if ev.Response != nil {
+			c.claimContent([]byte(ev.Response.Raw))
+		}
+	}
+}
+
+// carveHistoryItems recovers history items the table does not list. An item
+// whose request is still live is an older copy of that item, which is only
+// worth reporting when its annotations differ from the live ones.
+func (c *carver) carveHistoryItems(live []HTTPRecordLocation) ([]CarvedRecord, error) {
+	listed := make(map[int64]bool, len(live))
+	liveByRequest := make(map[int64]HTTPRecordLocation, len(live))
+	for _, loc := range live {
+		listed[loc.ItemOffset] = true
+		liveByRequest[loc.RequestOffset] = loc
+	}
+
+	c.p.mu.RLock()
+	candidates, err := c.p.historyItemCandidates(c.s)
+	c.p.mu.RUnlock()
+	if err != nil {
+		return nil, err
+	}
+
+	var carved []CarvedRecord
+	for _, off := range candidates {
+		if err := c.s.at(off); err != nil {
+			return carved, err
+		}
+		if listed[off] {
+			continue
+		}
+
+		c.p.mu.RLock()
+		loc, err := c.p.readProxyHistoryItem(off)
+		c.p.mu.RUnlock()
+		if err != nil {
+			continue
+		}
+		entry, err := c.p.ParseHTTPEntry(loc)
+		if err != nil {
+			continue
+		}
+
+		record := CarvedRecord{Kind: CarvedHistoryItem, Offset: off, Entry: entry}
+		if current, ok := liveByRequest[loc.RequestOffset]; ok {
+			liveEntry, err := c.p.ParseHTTPEntry(current)
+			if err != nil || (liveEntry.Comment == entry.Comment && liveEntry.Highlight == entry.Highlight) {
+				continue
+			}
+			record.Superseded = true
+			record.Reason = fmt.Sprintf("earlier version of history item 0x%x", current.ItemOffset)
+		} else {
+			record.Reason = "history item not listed in the history table"
+		}
+
+		c.claimedRequests[loc.RequestOffset] = true
+		carved = append(carved, record)
+		c.s.found()
+	}
+	return carved, nil
+}
+
+// carveIssues recovers Scanner issue records that no issue index entry
+// refers to. Older copies of live issues share their serial number and are
+// left out.
+func (c *carver) carveIssues(live []ScannerIssueMeta) ([]CarvedRecord, error) {
+	liveSerials := make(map[uint64]bool, len(live))
+	for _, issue := range live {
+		liveSerials[issue.SerialNumber] = true
+	}
+
+	// A cancelled scan still returns the issues it read.
+	all, err := c.p.ScanScannerIssueMetasRawContext(c.s.ctx, nil)
+	if err != nil && c.s.err() == nil {
+		return nil, err
+	}
+
+	var carved []CarvedRecord
+	for i := range all {
+		issue := all[i]
+		if liveSerials[issue.SerialNumber] {
+			continue
+		}
+		c.claimEvidence(issue)
+		carved = append(carved, CarvedRecord{
+			Kind:   CarvedIssue,
+			Offset: issue.RecordOffset,
+			Reason: "issue not listed in the issue index",
+			Issue:  &issue,
+		})
+		c.s.found()
+	}
+	return carved, err
+}
+
+// carveRepeaterTabs recovers requests and responses from older versions of
+// Repeater tabs that the current versions no longer hold, e.g. sends removed
+// from a tab's history.
+func (c *carver) carveRepeaterTabs() ([]CarvedRecord, error) {
+	type tabCopy struct {
+		offset int64
+		tab    RepeaterTab
+	}
+
+	var copies []tabCopy
+	c.p.mu.RLock()
+	err := c.p.scanRepeaterTabRecords(c.s, func(offset int64, name string) {
+		if tab, err := c.p.readRepeaterTab(offset+c.p.layout.repeaterTab.contentOffset(), name); err == nil {
+			copies = append(copies, tabCopy{offset, tab})
+		}
+	})
+	c.p.mu.RUnlock()
+
+	var carved []CarvedRecord
+	for _, cp := range copies {
+		for _, entry := range RepeaterTabEntries(cp.tab) {
+			if entry.Request == nil || c.isClaimed(entry.Request.Raw()) {
+				continue
+			}
+			c.claimMessage(entry.Request)
+			c.claimMessage(entry.Response)
+
+			entry.ID = 0
+			carved = append(carved, CarvedRecord{
+				Kind:       CarvedRepeater,
+				Offset:     cp.offset,
+				Superseded: true,
+				Reason:     fmt.Sprintf("earlier version of Repeater tab %q", cp.tab.Name),
+				Entry:      &entry,
+				TabName:    cp.tab.Name,
+			})
+			c.s.found()
+		}
+	}
+	return carved, err
+}
+
+// carveRequests recovers request/response pairs found by scanning for
+// request lines that no live or carved record accounts for. The pattern scan
+// reports as a history scan, as what it finds is not carved yet. Burp keeps
+// messages in records this package does not read, so what is left is not
+// known to be deleted and is marked Unclassified.
+func (c *carver) carveRequests() ([]CarvedRecord, error) {
+	c.p.mu.RLock()
+	s := c.p.startScan(c.s.ctx, ScanHistory)
+	locations, err := c.p.scanHTTPRecordsByPattern(s)
+	s.done()
+	c.p.mu.RUnlock()
+
+	var carved []CarvedRecord
+	for _, loc := range locations {
+		if err := c.s.err(); err != nil {
+			return carved, err
+		}
+		if c.claimedRequests[loc.RequestOffset] {
+			continue
+		}
+		entry, err := c.p.ParseHTTPEntry(loc)
+		if err != nil || entry.Request == nil || c.isClaimed(entry.Request.Raw()) {
+			continue
+		}
+		c.claimedRequests[loc.RequestOffset] = true
+
+		carved = append(carved, CarvedRecord{
+			Kind:         CarvedRequest,
+			Offset:       loc.RequestOffset,
+			Unclassified: true,
+			Reason:       "request not referenced by any record read",
+			Entry:        entry,
+		})
+		c.s.found()
+	}
+	return carved, err
+}
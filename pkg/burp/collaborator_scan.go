@@ -0,0 +1,276 @@
+package burp
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/bmm-sec/burp-insights/internal/binary"
+)
+
+// Each row of the Collaborator tab is a type 0x18 record holding an
+// interaction polled from the server, the payload ID and domain it arrived
+// on, and the captured DNS, HTTP or SMTP data.
+var collaboratorInteractionSignature = []byte{
+	0x00, 0x18, 0x00, 0x06,
+	0x00, 0x00, 0x16,
+	0x01, 0x00, 0x17,
+	0x02, 0x00, 0x1f,
+	0x03, 0x00, 0x27,
+	0x04, 0x00, 0x2f,
+	0x05, 0x00, 0x37,
+}
+
+const (
+	collaboratorInteractionRecordType uint16 = 0x0018
+
+	collaboratorFieldType     byte = 0x00
+	collaboratorFieldTime     byte = 0x01
+	collaboratorFieldPayload  byte = 0x02
+	collaboratorFieldDomain   byte = 0x03
+	collaboratorFieldClientIP byte = 0x04
+	collaboratorFieldData     byte = 0x05
+)
+
+type CollaboratorInteractionType int
+
+const (
+	CollaboratorDNS CollaboratorInteractionType = iota
+	CollaboratorHTTP
+	CollaboratorHTTPS
+	CollaboratorSMTP
+	CollaboratorSMTPS
+)
+
+func (t CollaboratorInteractionType) String() string {
+	switch t {
+	case CollaboratorDNS:
+		return "DNS"
+	case CollaboratorHTTP:
+		return "HTTP"
+	case CollaboratorHTTPS:
+		return "HTTPS"
+	case CollaboratorSMTP:
+		return "SMTP"
+	case CollaboratorSMTPS:
+		return "SMTPS"
+	default:
+		return "Unknown"
+	}
+}
+
+func (t CollaboratorInteractionType) MarshalText() ([]byte, error) {
+	return []byte(t.String()), nil
+}
+
+// CollaboratorInteraction is one DNS lookup, HTTP request or SMTP conversation
+// received by the Collaborator server. Data is the interaction as captured:
+// the DNS query packet, the HTTP request or the SMTP transcript.
+//
+// HistoryIDs and IssueSerials link the interaction to the HTTP history
+// entries and Scanner issues whose requests contain the payload subdomain.
+type CollaboratorInteraction struct {
+	RecordOffset int64
+	PayloadID    string
+	Domain       string
+	Type         CollaboratorInteractionType
+	Timestamp    time.Time
+	ClientIP     string
+	Data         []byte
+
+	HistoryIDs   []uint64
+	IssueSerials []uint64
+}
+
+// Payload returns the payload host name, e.g. "abc123.oastify.com", or just
+// the payload ID when the Collaborator domain is unknown.
+func (c CollaboratorInteraction) Payload() string {
+	if c.Domain == "" {
+		return c.PayloadID
+	}
+	return c.PayloadID + "." + c.Domain
+}
+
+// MatchesPayload reports whether payload names this interaction's payload,
+// either as the bare payload ID or as the full host name returned by
+// Payload. The comparison ignores case and a trailing dot.
+func (c CollaboratorInteraction) MatchesPayload(payload string) bool {
+	payload = strings.TrimSuffix(strings.TrimSpace(payload), ".")
+	return strings.EqualFold(payload, c.PayloadID) || strings.EqualFold(payload, c.Payload())
+}
+
+// ScanCollaboratorInteractions returns every stored Collaborator interaction,
+// oldest first. Copies of the same interaction left behind by Burp are
+// reported once.
+func (p *Parser) ScanCollaboratorInteractions() ([]CollaboratorInteraction, error) {
+	return p.ScanCollaboratorInteractionsContext(context.Background())
+}
+
+// ScanCollaboratorInteractionsContext is ScanCollaboratorInteractions that
+// stops when ctx is cancelled, returning the interactions found so far with
+// ctx.Err().
+func (p *Parser) ScanCollaboratorInteractionsContext(ctx context.Context) ([]CollaboratorInteraction, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	s := p.startScan(ctx, ScanCollaborator)
+	defer s.done()
+
+	offsets, err := s.offsets(sigCollaboratorInteraction)
+	if err != nil {
+		return nil, err
+	}
+
+	type interactionKey struct {
+		payload  string
+		typ      CollaboratorInteractionType
+		time     time.Time
+		clientIP string
+	}
+
+	var interactions []CollaboratorInteraction
+	index := make(map[interactionKey]int)
+
+	for _, abs := range offsets {
+		if err = s.at(abs); err != nil {
+			break
+		}
+		interaction, err := p.readCollaboratorInteraction(abs)
+		if err != nil {
+			p.noteDamage(CategoryCollaborator, abs, err)
+			continue
+		}
+		key := interactionKey{interaction.PayloadID, interaction.Type, interaction.Timestamp, interaction.ClientIP}
+		if j, ok := index[key]; ok {
+			interactions[j] = interaction
+			continue
+		}
+		index[key] = len(interactions)
+		interactions = append(interactions, interaction)
+		s.found()
+	}
+
+	sort.SliceStable(interactions, func(i, j int) bool {
+		return interactions[i].Timestamp.Before(interactions[j].Timestamp)
+	})
+	return interactions, err
+}
+
+func (p *Parser) readCollaboratorInteraction(offset int64) (CollaboratorInteraction, error) {
+	interaction := CollaboratorInteraction{RecordOffset: offset}
+
+	rec, err := p.readTypedRecordHeader(offset)
+	if err != nil {
+		return interaction, fmt.Errorf("read collaborator interaction record at 0x%x: %w", offset, err)
+	}
+
+	ptr, ok := p.readOptionalPointerField(offset, rec, collaboratorFieldPayload)
+	if !ok {
+		return interaction, fmt.Errorf("collaborator interaction at 0x%x has no payload ID", offset)
+	}
+	payloadID, err := p.readUTF16BEStringRecord(ptr)
+	if err != nil {
+		return interaction, fmt.Errorf("read collaborator payload ID: %w", err)
+	}
+	interaction.PayloadID = strings.ToLower(strings.TrimSpace(payloadID))
+	if interaction.PayloadID == "" {
+		return interaction, fmt.Errorf("collaborator interaction at 0x%x has an empty payload ID", offset)
+	}
+
+	if off, ok := rec.fieldOffset(collaboratorFieldType); ok {
+		if b, err := p.reader.ReadAt(offset+int64(off), 1); err == nil && len(b) == 1 {
+			interaction.Type = CollaboratorInteractionType(b[0])
+		}
+	}
+	if off, ok := rec.fieldOffset(collaboratorFieldTime); ok {
+		if ms, err := p.reader.ReadUint64At(offset + int64(off)); err == nil {
+			interaction.Timestamp = timeFromBurpMillis(ms)
+		}
+	}
+	if ptr, ok := p.readOptionalPointerField(offset, rec, collaboratorFieldDomain); ok {
+		if domain, err := p.readUTF16BEStringRecord(ptr); err == nil {
+			interaction.Domain = strings.ToLower(strings.Trim(strings.TrimSpace(domain), "."))
+		}
+	}
+	if ptr, ok := p.readOptionalPointerField(offset, rec, collaboratorFieldClientIP); ok {
+		if ip, err := p.readUTF16BEStringRecord(ptr); err == nil {
+			interaction.ClientIP = strings.TrimSpace(ip)
+		}
+	}
+	if ptr, ok := p.readOptionalPointerField(offset, rec, collaboratorFieldData); ok {
+		dataOffset, length, err := p.readMessageRecord(ptr)
+		if err != nil {
+			return interaction, fmt.Errorf("read collaborator interaction data at 0x%x: %w", ptr, err)
+		}
+		data, err := p.reader.ReadAt(dataOffset, length)
+		if err != nil || len(data) < length {
+			return interaction, fmt.Errorf("short collaborator interaction data at 0x%x", ptr)
+		}
+		interaction.Data = data
+	}
+
+	return interaction, nil
+}
+
+// LinkCollaboratorInteractions records on each interaction the HTTP history
+// entries and Scanner issues whose requests contain its payload, the full
+// host name when the Collaborator domain is known and the bare payload ID
+// otherwise. Payloads are matched case-insensitively, since DNS names are.
+func LinkCollaboratorInteractions(interactions []CollaboratorInteraction, entries []HTTPEntry, issues []ScannerIssueMeta) {
+	if len(interactions) == 0 {
+		return
+	}
+
+	// Several interactions usually share a payload, so each distinct one is
+	// searched for once and the hits fanned out afterwards.
+	var payloads [][]byte
+	byPayload := make(map[string][]int)
+	for i, interaction := range interactions {
+		payload := strings.ToLower(interaction.Payload())
+		if _, ok := byPayload[payload]; !ok {
+			payloads = append(payloads, []byte(payload))
+		}
+		byPayload[payload] = append(byPayload[payload], i)
+	}
+	matcher := binary.NewMatcher(payloads)
+
+	matching := func(raw []byte) []int {
+		var found []int
+		seen := make(map[int]bool)
+		matcher.Find(bytes.ToLower(raw), func(pattern, _ int) bool {
+			if !seen[pattern] {
+				seen[pattern] = true
+				found = append(found, byPayload[string(payloads[pattern])]...)
+			}
+			return true
+		})
+		return found
+	}
+
+	for _, entry := range entries {
+		if entry.Request == nil {
+			continue
+		}
+		for _, i := range matching(entry.Request.Raw()) {
+			interactions[i].HistoryIDs = append(interactions[i].HistoryIDs, entry.ID)
+		}
+	}
+
+	for _, issue := range issues {
+		linked := make(map[int]bool)
+		for _, ev := range issue.Evidence {
+			if ev.Request == nil {
+				continue
+			}
+			for _, i := range matching([]byte(ev.Request.Raw)) {
+				if !linked[i] {
+					linked[i] = true
+					interactions[i].IssueSerials = append(interactions[i].IssueSerials, issue.SerialNumber)
+				}
+			}
+		}
+	}
+}
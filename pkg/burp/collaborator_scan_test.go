@@ -0,0 +1,101 @@
+package burp
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+// collaboratorInteraction writes a Collaborator interaction record and
+// returns its offset.
+func (b *projectBuilder) collaboratorInteraction(typ CollaboratorInteractionType, at time.Time, payloadID, domain, data string) int64 {
+	payload := b.utf16String(payloadID)
+	dom := b.utf16String(domain)
+	ip := b.utf16String("203.0.113.7")
+	msg := b.message(data)
+
+	return b.typed(collaboratorInteractionRecordType,
+		byteField(collaboratorFieldType, byte(typ)),
+		ptrField(collaboratorFieldTime, at.UnixMilli()),
+		ptrField(collaboratorFieldPayload, payload),
+		ptrField(collaboratorFieldDomain, dom),
+		ptrField(collaboratorFieldClientIP, ip),
+		ptrField(collaboratorFieldData, msg),
+	)
+}
+
+func TestScanCollaboratorInteractions(t *testing.T) {
+	at := time.Date(2025, 10, 9, 9, 0, 0, 0, time.UTC)
+	lookup := "GET / HTTP/1.1\r\nHost: abc123.oastify.com\r\n\r\n"
+
+	b := newProjectBuilder()
+	first := b.collaboratorInteraction(CollaboratorHTTP, at.Add(time.Minute), "ABC123", "oastify.com.", lookup)
+	// A copy of the same interaction left behind by Burp.
+	b.collaboratorInteraction(CollaboratorHTTP, at.Add(time.Minute), "abc123", "oastify.com", lookup)
+	b.collaboratorInteraction(CollaboratorDNS, at, "abc123", "other.example", "dns")
+	path := b.write(t)
+
+	r, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	interactions, err := r.CollaboratorInteractions()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(interactions) != 2 {
+		t.Fatalf("found %d interactions, want 2: %+v", len(interactions), interactions)
+	}
+
+	dns, http := interactions[0], interactions[1]
+	if dns.Type != CollaboratorDNS || dns.Payload() != "abc123.other.example" {
+		t.Errorf("first interaction = %s %s, want DNS abc123.other.example", dns.Type, dns.Payload())
+	}
+	if http.Type != CollaboratorHTTP || http.Payload() != "abc123.oastify.com" || http.RecordOffset <= first {
+		t.Errorf("second interaction = %s %s at 0x%x, want the later HTTP copy for abc123.oastify.com",
+			http.Type, http.Payload(), http.RecordOffset)
+	}
+	if !http.Timestamp.Equal(at.Add(time.Minute)) || http.ClientIP != "203.0.113.7" || string(http.Data) != lookup {
+		t.Errorf("second interaction = %+v, want its time, client IP and data read", http)
+	}
+}
+
+func TestLinkCollaboratorInteractionsMatchesFullPayload(t *testing.T) {
+	interactions := []CollaboratorInteraction{
+		{PayloadID: "abc123", Domain: "oastify.com"},
+		{PayloadID: "abc123", Domain: "other.example"},
+		{PayloadID: "xyz789"},
+	}
+	entries := []HTTPEntry{
+		{ID: 1, Request: NewHTTPMessage([]byte("GET /?u=http://ABC123.oastify.com/ HTTP/1.1\r\nHost: a\r\n\r\n"))},
+		{ID: 2, Request: NewHTTPMessage([]byte("GET /?u=abc123 HTTP/1.1\r\nHost: a\r\n\r\n"))},
+		{ID: 3, Request: NewHTTPMessage([]byte("GET /?u=xyz789.anything HTTP/1.1\r\nHost: a\r\n\r\n"))},
+	}
+
+	LinkCollaboratorInteractions(interactions, entries, nil)
+
+	for i, want := range [][]uint64{{1}, nil, {3}} {
+		if !slices.Equal(interactions[i].HistoryIDs, want) {
+			t.Errorf("%s linked to %v, want %v", interactions[i].Payload(), interactions[i].HistoryIDs, want)
+		}
+	}
+}
+
+func TestCollaboratorMatchesPayload(t *testing.T) {
+	interaction := CollaboratorInteraction{PayloadID: "abc123", Domain: "oastify.com"}
+	for payload, want := range map[string]bool{
+		"abc123":              true,
+		"ABC123":              true,
+		"abc123.oastify.com":  true,
+		"abc123.oastify.com.": true,
+		"abc123.other.com":    false,
+		"oastify.com":         false,
+		"abc12":               false,
+	} {
+		if got := interaction.MatchesPayload(payload); got != want {
+			t.Errorf("MatchesPayload(%q) = %v, want %v", payload, got, want)
+		}
+	}
+}
@@ -0,0 +1,414 @@
+package burp
+
+import (
+	"bytes"
+	"context"
+	stdbinary "encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"time"
+	"unicode/utf16"
+	"unicode/utf8"
+
+	"github.com/bmm-sec/burp-insights/internal/binary"
+)
+
+// DumpOptions controls how much of the record graph Dump decodes.
+type DumpOptions struct {
+	// Depth is how many levels of pointers are followed from the record.
+	Depth int
+	// HexLength is how many bytes are shown for data that is not a
+	// recognised record.
+	HexLength int
+	// MaxItems caps the elements listed per list or pointer vector.
+	MaxItems int
+}
+
+func DefaultDumpOptions() DumpOptions {
+	return DumpOptions{
+		Depth:     1,
+		HexLength: 128,
+		MaxItems:  32,
+	}
+}
+
+// DumpKind is the kind of record Dump recognised at an offset.
+type DumpKind string
+
+const (
+	DumpTypedRecord DumpKind = "typed"
+	DumpList        DumpKind = "list"
+	DumpVector      DumpKind = "vector"
+	DumpUTF16String DumpKind = "utf16"
+	DumpBytes       DumpKind = "bytes"
+	DumpHex         DumpKind = "hex"
+)
+
+// DumpNode is the decoded record at Offset. Fields are set for typed records,
+// Items for lists and vectors, and Hex with its Annotations when nothing was
+// recognised. Repeat marks a record already shown elsewhere in the dump,
+// whose contents are left out.
+type DumpNode struct {
+	Offset      int64            `json:"offset"`
+	Kind        DumpKind         `json:"kind"`
+	Type        uint16           `json:"type,omitempty"`
+	Summary     string           `json:"summary,omitempty"`
+	Length      int64            `json:"length,omitempty"`
+	Fields      []DumpField      `json:"fields,omitempty"`
+	Items       []DumpItem       `json:"items,omitempty"`
+	MoreItems   int              `json:"moreItems,omitempty"`
+	Hex         string           `json:"hex,omitempty"`
+	Annotations []DumpAnnotation `json:"annotations,omitempty"`
+	Repeat      bool             `json:"repeat,omitempty"`
+	Error       string           `json:"error,omitempty"`
+}
+
+// DumpField is one field of a typed record. Offset is relative to the start
+// of the record. Size is the distance to the next field, or zero for the last
+// field, whose size the descriptor table does not give; up to eight bytes of
+// it are shown.
+type DumpField struct {
+	ID      byte      `json:"id"`
+	Offset  uint16    `json:"offset"`
+	Size    int       `json:"size"`
+	Hex     string    `json:"hex"`
+	Value   string    `json:"value,omitempty"`
+	Pointer int64     `json:"pointer,omitempty"`
+	Target  *DumpNode `json:"target,omitempty"`
+}
+
+// DumpItem is one pointer held by a list or pointer vector.
+type DumpItem struct {
+	Pointer int64     `json:"pointer"`
+	Target  *DumpNode `json:"target,omitempty"`
+}
+
+// DumpAnnotation notes something recognised inside a hex dump: a pointer to
+// a decodable record or the header of a known record type.
+type DumpAnnotation struct {
+	Offset int64  `json:"offset"`
+	Note   string `json:"note"`
+}
+
+// recordTypeNames names the typed record types the scanners understand.
+var recordTypeNames = map[uint16]string{
+	proxyHistoryItemType:         "history item",
+	repeaterTabRecordType:        "repeater tab",
+	repeaterSendRecordType:       "repeater send",
+	serviceRecordType:            "service",
+	0x0011:                       "intruder attack",
+	intruderPayloadSetRecordType: "intruder payload set",
+	intruderResultRecordType:     "intruder result",
+	0x0014:                       "target scope",
+	scopeRuleRecordType:          "scope rule",
+	0x0016:                       "websocket connection",
+	webSocketMessageRecordType:   "websocket message",
+	0x0018:                       "collaborator interaction",
+}
+
+// Burp timestamps are milliseconds since the epoch; u64 values in this range
+// are shown as times as well.
+var (
+	minDumpTimestamp = uint64(time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC).UnixMilli())
+	maxDumpTimestamp = uint64(time.Date(2100, 1, 1, 0, 0, 0, 0, time.UTC).UnixMilli())
+)
+
+// Dump decodes the record at offset as well as the parser can tell what it
+// is, following pointers to opts.Depth levels. It is meant for working out
+// the layout of records the scanners do not understand yet.
+func (p *Parser) Dump(offset int64, opts DumpOptions) (*DumpNode, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	if offset < 0 || offset >= p.reader.Size() {
+		return nil, fmt.Errorf("offset 0x%x outside file of %d bytes", offset, p.reader.Size())
+	}
+
+	d := &dumper{p: p, opts: opts, seen: make(map[int64]bool)}
+	return d.node(offset, opts.Depth), nil
+}
+
+// FindTypedRecords returns the offsets of up to limit typed records of the
+// given type, in file order.
+func (p *Parser) FindTypedRecords(recordType uint16, limit int) ([]int64, error) {
+	return p.FindTypedRecordsContext(context.Background(), recordType, limit)
+}
+
+// FindTypedRecordsContext is FindTypedRecords that stops when ctx is
+// cancelled, returning the offsets found so far with ctx.Err().
+func (p *Parser) FindTypedRecordsContext(ctx context.Context, recordType uint16, limit int) ([]int64, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	s := p.startScan(ctx, ScanRecordTypes)
+	defer s.done()
+
+	matcher := binary.NewMatcher([][]byte{stdbinary.BigEndian.AppendUint16(nil, recordType)})
+
+	var offsets []int64
+	err := p.reader.FindAllContext(ctx, matcher, int64(HeaderSize), func(scanned int64) {
+		s.report(scanned, false)
+	}, func(_ int, offset int64) bool {
+		if _, err := p.readTypedRecordHeader(offset); err == nil {
+			offsets = append(offsets, offset)
+			s.found()
+		}
+		return limit <= 0 || len(offsets) < limit
+	})
+	return offsets, err
+}
+
+type dumper struct {
+	p    *Parser
+	opts DumpOptions
+	seen map[int64]bool
+}
+
+// node decodes the record at offset unless it was already decoded earlier in
+// the dump, which also stops pointer cycles.
+func (d *dumper) node(offset int64, depth int) *DumpNode {
+	if d.seen[offset] {
+		n := d.decode(offset, -1)
+		n.Fields, n.Items, n.MoreItems, n.Hex, n.Annotations = nil, nil, 0, "", nil
+		n.Repeat = true
+		return n
+	}
+	d.seen[offset] = true
+	return d.decode(offset, depth)
+}
+
+// decode tries each kind of record in turn. Lists and strings come first
+// because their headers also read as valid typed records. A negative depth
+// only classifies the record, without following pointers or annotating.
+func (d *dumper) decode(offset int64, depth int) *DumpNode {
+	n := &DumpNode{Offset: offset}
+	switch {
+	case d.decodeList(n, depth):
+	case d.decodeUTF16String(n):
+	case d.decodeTypedRecord(n, depth):
+	case d.decodeVector(n, depth):
+	case d.decodeBytes(n):
+	default:
+		d.decodeHex(n, depth)
+	}
+	return n
+}
+
+func (d *dumper) follow(ptr int64, depth int) *DumpNode {
+	if depth <= 0 || !d.plausiblePointer(ptr) {
+		return nil
+	}
+	return d.node(ptr, depth-1)
+}
+
+func (d *dumper) plausiblePointer(v int64) bool {
+	return v >= int64(HeaderSize) && v < d.p.reader.Size()
+}
+
+func (d *dumper) decodeList(n *DumpNode, depth int) bool {
+	count, vecPtr, err := d.p.readListWrapper(n.Offset)
+	if err != nil {
+		return false
+	}
+
+	n.Kind = DumpList
+	n.Length = int64(len(listWrapperSignature) + 12)
+	n.Summary = fmt.Sprintf("%d item(s), vector at 0x%x", count, vecPtr)
+	if depth < 0 {
+		return true
+	}
+
+	ptrs, err := d.p.readPointerVector(vecPtr)
+	if err != nil {
+		n.Error = err.Error()
+	}
+	if int(count) < len(ptrs) {
+		ptrs = ptrs[:count]
+	}
+	d.addItems(n, ptrs, depth)
+	return true
+}
+
+func (d *dumper) decodeVector(n *DumpNode, depth int) bool {
+	ptrs, err := d.p.readPointerVector(n.Offset)
+	if err != nil && len(ptrs) == 0 {
+		return false
+	}
+
+	n.Kind = DumpVector
+	n.Length = int64(8 + 8*len(ptrs))
+	n.Summary = fmt.Sprintf("capacity %d", len(ptrs))
+	if err != nil {
+		n.Error = err.Error()
+	}
+	if depth >= 0 {
+		d.addItems(n, ptrs, depth)
+	}
+	return true
+}
+
+func (d *dumper) addItems(n *DumpNode, ptrs []int64, depth int) {
+	for i, ptr := range ptrs {
+		if d.opts.MaxItems > 0 && i >= d.opts.MaxItems {
+			n.MoreItems = len(ptrs) - i
+			break
+		}
+		n.Items = append(n.Items, DumpItem{Pointer: ptr, Target: d.follow(ptr, depth)})
+	}
+}
+
+func (d *dumper) decodeUTF16String(n *DumpNode) bool {
+	layout := d.p.layout.utf16String
+	s, ok, _ := d.p.tryReadUTF16BEStringRecord(layout, n.Offset)
+	if !ok {
+		return false
+	}
+	n.Kind = DumpUTF16String
+	n.Length = int64(layout.headerLen() + 2*len(utf16.Encode([]rune(s))))
+	n.Summary = strconv.Quote(s)
+	return true
+}
+
+func (d *dumper) decodeTypedRecord(n *DumpNode, depth int) bool {
+	rec, err := d.p.readTypedRecordHeader(n.Offset)
+	if err != nil {
+		return false
+	}
+
+	n.Kind = DumpTypedRecord
+	n.Type = rec.Type
+	n.Summary = fmt.Sprintf("type 0x%04x, %d field(s)", rec.Type, rec.FieldCount)
+	if name, ok := recordTypeNames[rec.Type]; ok {
+		n.Summary = fmt.Sprintf("type 0x%04x (%s), %d field(s)", rec.Type, name, rec.FieldCount)
+	} else if rec.Type == 0 {
+		// A v2 record's u32 field count reads as type 0.
+		n.Summary = fmt.Sprintf("type 0x0000 or v2 record, %d field(s)", rec.FieldCount)
+	}
+	if depth < 0 {
+		return true
+	}
+
+	for i, f := range rec.Fields {
+		size := 0
+		readLen := 8
+		if i+1 < len(rec.Fields) {
+			size = int(rec.Fields[i+1].Offset - f.Offset)
+			readLen = size
+		}
+
+		data, err := d.p.reader.ReadAt(n.Offset+int64(f.Offset), min(readLen, 64))
+		if err != nil {
+			n.Error = err.Error()
+			break
+		}
+
+		field := DumpField{ID: f.ID, Offset: f.Offset, Size: size, Hex: hex.EncodeToString(data)}
+		field.Value, field.Pointer = d.fieldValue(data, size)
+		if field.Pointer != 0 {
+			field.Target = d.follow(field.Pointer, depth)
+		}
+		n.Fields = append(n.Fields, field)
+	}
+	return true
+}
+
+// fieldValue interprets a field by its size. Eight-byte fields are shown as
+// pointers when they point into the file and as times when they look like
+// Burp timestamps. A field of unknown size is only taken as either of those.
+func (d *dumper) fieldValue(data []byte, size int) (string, int64) {
+	switch {
+	case size == 1 && len(data) == 1:
+		return fmt.Sprintf("u8 %d", data[0]), 0
+	case size == 2 && len(data) == 2:
+		return fmt.Sprintf("u16 %d", stdbinary.BigEndian.Uint16(data)), 0
+	case size == 4 && len(data) == 4:
+		return fmt.Sprintf("u32 %d", stdbinary.BigEndian.Uint32(data)), 0
+	case (size == 8 || size == 0) && len(data) == 8:
+		v := stdbinary.BigEndian.Uint64(data)
+		switch {
+		case v == 0 && size == 8:
+			return "u64 0 (null pointer?)", 0
+		case d.plausiblePointer(int64(v)):
+			return fmt.Sprintf("pointer 0x%x", v), int64(v)
+		case v >= minDumpTimestamp && v < maxDumpTimestamp:
+			return fmt.Sprintf("u64 %d (%s)", v, timeFromBurpMillis(v).UTC().Format(time.RFC3339)), 0
+		case size == 0:
+			return "", 0
+		default:
+			return fmt.Sprintf("u64 %d", v), 0
+		}
+	}
+	return "", 0
+}
+
+// decodeBytes recognises a length-prefixed record: a u32 total length that
+// is the data length plus eight, then the u32 data length. HTTP messages and
+// UTF-8 strings are stored this way.
+func (d *dumper) decodeBytes(n *DumpNode) bool {
+	hdr, err := d.p.reader.ReadAt(n.Offset, 8)
+	if err != nil || len(hdr) < 8 {
+		return false
+	}
+	total := stdbinary.BigEndian.Uint32(hdr[0:4])
+	length := stdbinary.BigEndian.Uint32(hdr[4:8])
+	if length == 0 || total != length+8 || n.Offset+int64(total) > d.p.reader.Size() {
+		return false
+	}
+
+	n.Kind = DumpBytes
+	n.Length = int64(total)
+
+	preview, _ := d.p.reader.ReadAt(n.Offset+8, int(min(length, 256)))
+	if line, _, _ := bytes.Cut(preview, []byte("\r\n")); looksLikeHTTPStartLine(string(line)) {
+		n.Summary = fmt.Sprintf("%d bytes, HTTP message %q", length, line)
+	} else if utf8.Valid(preview) && int(length) == len(preview) {
+		n.Summary = fmt.Sprintf("%d bytes, text %q", length, preview)
+	} else {
+		n.Summary = fmt.Sprintf("%d bytes", length)
+		n.Hex = hex.EncodeToString(preview[:min(len(preview), 32)])
+	}
+	return true
+}
+
+// decodeHex falls back to the raw bytes, noting any pointers to records that
+// can be decoded and any headers of known record types found among them.
+func (d *dumper) decodeHex(n *DumpNode, depth int) {
+	n.Kind = DumpHex
+
+	data, err := d.p.reader.ReadAt(n.Offset, d.opts.HexLength)
+	if err != nil {
+		n.Error = err.Error()
+		return
+	}
+	n.Hex = hex.EncodeToString(data)
+	n.Length = int64(len(data))
+	if depth < 0 {
+		return
+	}
+
+	for i := 0; i < len(data); i++ {
+		at := n.Offset + int64(i)
+
+		if i+4 <= len(data) {
+			if name, ok := recordTypeNames[stdbinary.BigEndian.Uint16(data[i:])]; ok {
+				if _, err := d.p.readTypedRecordHeader(at); err == nil {
+					n.Annotations = append(n.Annotations, DumpAnnotation{Offset: at, Note: name + " record header"})
+					continue
+				}
+			}
+		}
+
+		if i+8 > len(data) {
+			continue
+		}
+		ptr := int64(stdbinary.BigEndian.Uint64(data[i:]))
+		if !d.plausiblePointer(ptr) || ptr == at {
+			continue
+		}
+		if target := d.decode(ptr, -1); target.Kind != DumpHex {
+			n.Annotations = append(n.Annotations, DumpAnnotation{Offset: at, Note: fmt.Sprintf("pointer to %s at 0x%x", target.Kind, ptr)})
+			i += 7
+		}
+	}
+}
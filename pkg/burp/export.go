@@ -4,8 +4,10 @@ import (
 	"encoding/base64"
 	"encoding/json"
 	"io"
+	"strconv"
 	"strings"
 	"time"
+	"unicode/utf8"
 )
 
 type ExportFormat int
@@ -23,6 +25,9 @@ type ExportOptions struct {
 	PrettyPrint bool
 	MaxBodySize int64
 	IncludeRaw  bool
+	// RawBody exports bodies as stored, without removing transfer and
+	// content encodings.
+	RawBody bool
 }
 
 func DefaultExportOptions() ExportOptions {
@@ -41,6 +46,7 @@ type ExportedEntry struct {
 	Host          string           `json:"host"`
 	Port          int              `json:"port,omitempty"`
 	Protocol      string           `json:"protocol,omitempty"`
+	Scheme        string           `json:"scheme,omitempty"`
 	Method        string           `json:"method"`
 	Path          string           `json:"path"`
 	URL           string           `json:"url"`
@@ -48,6 +54,9 @@ type ExportedEntry struct {
 	StatusCode    int              `json:"status_code,omitempty"`
 	ContentLength int64            `json:"content_length,omitempty"`
 	MIMEType      string           `json:"mime_type,omitempty"`
+	Tool          string           `json:"tool,omitempty"`
+	Highlight     string           `json:"highlight,omitempty"`
+	Comment       string           `json:"comment,omitempty"`
 	Request       *ExportedMessage `json:"request,omitempty"`
 	Response      *ExportedMessage `json:"response,omitempty"`
 }
@@ -76,6 +85,438 @@ func Export(w io.Writer, entries []HTTPEntry, opts ExportOptions) error {
 	}
 }
 
+type ExportedRepeaterTab struct {
+	RecordOffset int64                  `json:"recordOffset"`
+	Name         string                 `json:"name"`
+	Host         string                 `json:"host,omitempty"`
+	Port         int                    `json:"port,omitempty"`
+	Protocol     string                 `json:"protocol,omitempty"`
+	Request      *ExportedMessage       `json:"request,omitempty"`
+	Response     *ExportedMessage       `json:"response,omitempty"`
+	History      []ExportedRepeaterSend `json:"history,omitempty"`
+}
+
+type ExportedRepeaterSend struct {
+	Timestamp string           `json:"timestamp,omitempty"`
+	Request   *ExportedMessage `json:"request,omitempty"`
+	Response  *ExportedMessage `json:"response,omitempty"`
+}
+
+// ExportRepeaterTabs writes Repeater tabs to the given writer. HAR output has
+// one entry per previous send, commented with the tab name.
+func ExportRepeaterTabs(w io.Writer, tabs []RepeaterTab, opts ExportOptions) error {
+	switch opts.Format {
+	case FormatHAR:
+		return exportHAR(w, RepeaterEntries(tabs), opts)
+	case FormatJSONLines:
+		encoder := json.NewEncoder(w)
+		for _, tab := range tabs {
+			if err := encoder.Encode(convertRepeaterTab(tab, opts)); err != nil {
+				return err
+			}
+		}
+		return nil
+	default:
+		exported := make([]ExportedRepeaterTab, 0, len(tabs))
+		for _, tab := range tabs {
+			exported = append(exported, convertRepeaterTab(tab, opts))
+		}
+
+		encoder := json.NewEncoder(w)
+		if opts.PrettyPrint {
+			encoder.SetIndent("", "  ")
+		}
+		return encoder.Encode(exported)
+	}
+}
+
+func convertRepeaterTab(tab RepeaterTab, opts ExportOptions) ExportedRepeaterTab {
+	exported := ExportedRepeaterTab{
+		RecordOffset: tab.RecordOffset,
+		Name:         tab.Name,
+		Host:         tab.Host,
+		Port:         tab.Port,
+		Protocol:     tab.Protocol,
+	}
+	if tab.Request != nil {
+		exported.Request = convertMessage(tab.Request, opts)
+	}
+	if tab.Response != nil {
+		exported.Response = convertMessage(tab.Response, opts)
+	}
+
+	for _, send := range tab.History {
+		exportedSend := ExportedRepeaterSend{}
+		if !send.Timestamp.IsZero() {
+			exportedSend.Timestamp = send.Timestamp.Format(time.RFC3339)
+		}
+		if send.Request != nil {
+			exportedSend.Request = convertMessage(send.Request, opts)
+		}
+		if send.Response != nil {
+			exportedSend.Response = convertMessage(send.Response, opts)
+		}
+		exported.History = append(exported.History, exportedSend)
+	}
+
+	return exported
+}
+
+type ExportedWebSocketConnection struct {
+	ID              int                        `json:"id"`
+	URL             string                     `json:"url"`
+	Host            string                     `json:"host,omitempty"`
+	Port            int                        `json:"port,omitempty"`
+	Protocol        string                     `json:"protocol,omitempty"`
+	Timestamp       string                     `json:"timestamp,omitempty"`
+	UpgradeRequest  *ExportedMessage           `json:"upgradeRequest,omitempty"`
+	UpgradeResponse *ExportedMessage           `json:"upgradeResponse,omitempty"`
+	Messages        []ExportedWebSocketMessage `json:"messages,omitempty"`
+}
+
+// ExportedWebSocketMessage holds a message payload as text, or base64 encoded
+// when it is binary or not valid UTF-8.
+type ExportedWebSocketMessage struct {
+	Direction       WebSocketDirection `json:"direction"`
+	Opcode          WebSocketOpcode    `json:"opcode"`
+	Timestamp       string             `json:"timestamp,omitempty"`
+	Edited          bool               `json:"edited,omitempty"`
+	PayloadSize     int                `json:"payloadSize"`
+	Payload         string             `json:"payload,omitempty"`
+	PayloadEncoding string             `json:"payloadEncoding,omitempty"`
+}
+
+// ExportWebSockets writes WebSocket connections with their messages to the
+// given writer as JSON or JSON lines. Payloads are cut to opts.MaxBodySize
+// when it is set.
+func ExportWebSockets(w io.Writer, conns []WebSocketConnection, opts ExportOptions) error {
+	encoder := json.NewEncoder(w)
+	if opts.Format == FormatJSONLines {
+		for _, conn := range conns {
+			if err := encoder.Encode(convertWebSocketConnection(conn, opts)); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	exported := make([]ExportedWebSocketConnection, 0, len(conns))
+	for _, conn := range conns {
+		exported = append(exported, convertWebSocketConnection(conn, opts))
+	}
+	if opts.PrettyPrint {
+		encoder.SetIndent("", "  ")
+	}
+	return encoder.Encode(exported)
+}
+
+func convertWebSocketConnection(conn WebSocketConnection, opts ExportOptions) ExportedWebSocketConnection {
+	exported := ExportedWebSocketConnection{
+		ID:       conn.ID,
+		URL:      conn.URL,
+		Host:     conn.Host,
+		Port:     conn.Port,
+		Protocol: conn.Protocol,
+	}
+	if !conn.Timestamp.IsZero() {
+		exported.Timestamp = conn.Timestamp.Format(time.RFC3339)
+	}
+	if conn.UpgradeRequest != nil {
+		exported.UpgradeRequest = convertMessage(conn.UpgradeRequest, opts)
+	}
+	if conn.UpgradeResponse != nil {
+		exported.UpgradeResponse = convertMessage(conn.UpgradeResponse, opts)
+	}
+
+	for _, msg := range conn.Messages {
+		exportedMsg := ExportedWebSocketMessage{
+			Direction:   msg.Direction,
+			Opcode:      msg.Opcode,
+			Edited:      msg.Edited,
+			PayloadSize: len(msg.Payload),
+		}
+		if !msg.Timestamp.IsZero() {
+			exportedMsg.Timestamp = msg.Timestamp.Format(time.RFC3339)
+		}
+
+		payload := msg.Payload
+		if opts.MaxBodySize > 0 && int64(len(payload)) > opts.MaxBodySize {
+			payload = payload[:opts.MaxBodySize]
+		}
+		if msg.Opcode == WebSocketBinary || !utf8.Valid(msg.Payload) {
+			exportedMsg.Payload = base64.StdEncoding.EncodeToString(payload)
+			exportedMsg.PayloadEncoding = "base64"
+		} else {
+			exportedMsg.Payload = string(payload)
+		}
+		exported.Messages = append(exported.Messages, exportedMsg)
+	}
+
+	return exported
+}
+
+// ExportedWebSocketSearchResult is a WebSocketSearchResult as exported. It
+// names the connection instead of repeating its messages; ExportWebSockets
+// writes those in full.
+type ExportedWebSocketSearchResult struct {
+	ID           int                   `json:"id"`
+	URL          string                `json:"url"`
+	MessageCount int                   `json:"messageCount"`
+	Score        int                   `json:"score"`
+	Matches      []ExportedSearchMatch `json:"matches"`
+}
+
+type ExportedSearchMatch struct {
+	Location string `json:"location"`
+	Context  string `json:"context"`
+	Offset   int    `json:"offset"`
+	Length   int    `json:"length"`
+}
+
+// ExportWebSocketSearchResults writes WebSocket search results to the given
+// writer as JSON or JSON lines.
+func ExportWebSocketSearchResults(w io.Writer, results []WebSocketSearchResult, opts ExportOptions) error {
+	encoder := json.NewEncoder(w)
+	if opts.Format == FormatJSONLines {
+		for _, result := range results {
+			if err := encoder.Encode(convertWebSocketSearchResult(result)); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	exported := make([]ExportedWebSocketSearchResult, 0, len(results))
+	for _, result := range results {
+		exported = append(exported, convertWebSocketSearchResult(result))
+	}
+	if opts.PrettyPrint {
+		encoder.SetIndent("", "  ")
+	}
+	return encoder.Encode(exported)
+}
+
+func convertWebSocketSearchResult(result WebSocketSearchResult) ExportedWebSocketSearchResult {
+	exported := ExportedWebSocketSearchResult{
+		ID:           result.Connection.ID,
+		URL:          result.Connection.URL,
+		MessageCount: len(result.Connection.Messages),
+		Score:        result.Score,
+		Matches:      make([]ExportedSearchMatch, 0, len(result.Matches)),
+	}
+	for _, match := range result.Matches {
+		exported.Matches = append(exported.Matches, ExportedSearchMatch(match))
+	}
+	return exported
+}
+
+// ExportedCarvedRecord is a CarvedRecord as exported. Reachable is always
+// false; it is written out so carved records cannot be mistaken for live ones
+// once they are mixed with other exports.
+type ExportedCarvedRecord struct {
+	Kind         CarvedKind        `json:"kind"`
+	Offset       int64             `json:"offset"`
+	Reachable    bool              `json:"reachable"`
+	Superseded   bool              `json:"superseded,omitempty"`
+	Unclassified bool              `json:"unclassified,omitempty"`
+	Reason       string            `json:"reason"`
+	TabName      string            `json:"tabName,omitempty"`
+	Entry        *ExportedEntry    `json:"entry,omitempty"`
+	Issue        *ScannerIssueMeta `json:"issue,omitempty"`
+}
+
+// ExportCarvedRecords writes carved records to the given writer as JSON or
+// JSON lines.
+func ExportCarvedRecords(w io.Writer, records []CarvedRecord, opts ExportOptions) error {
+	encoder := json.NewEncoder(w)
+	if opts.Format == FormatJSONLines {
+		for _, record := range records {
+			if err := encoder.Encode(convertCarvedRecord(record, opts)); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	exported := make([]ExportedCarvedRecord, 0, len(records))
+	for _, record := range records {
+		exported = append(exported, convertCarvedRecord(record, opts))
+	}
+	if opts.PrettyPrint {
+		encoder.SetIndent("", "  ")
+	}
+	return encoder.Encode(exported)
+}
+
+func convertCarvedRecord(record CarvedRecord, opts ExportOptions) ExportedCarvedRecord {
+	exported := ExportedCarvedRecord{
+		Kind:         record.Kind,
+		Offset:       record.Offset,
+		Superseded:   record.Superseded,
+		Unclassified: record.Unclassified,
+		Reason:       record.Reason,
+		TabName:      record.TabName,
+		Issue:        record.Issue,
+	}
+	if record.Entry != nil {
+		entry := convertToExported(*record.Entry, opts)
+		exported.Entry = &entry
+	}
+	return exported
+}
+
+// ExportedCollaboratorInteraction holds the interaction data as text, or
+// base64 encoded for DNS query packets and data that is not valid UTF-8.
+type ExportedCollaboratorInteraction struct {
+	PayloadID    string                      `json:"payloadId"`
+	Payload      string                      `json:"payload"`
+	Type         CollaboratorInteractionType `json:"type"`
+	Timestamp    string                      `json:"timestamp,omitempty"`
+	ClientIP     string                      `json:"clientIp,omitempty"`
+	Data         string                      `json:"data,omitempty"`
+	DataEncoding string                      `json:"dataEncoding,omitempty"`
+	HistoryIDs   []uint64                    `json:"historyIds,omitempty"`
+	IssueSerials []uint64                    `json:"issueSerials,omitempty"`
+}
+
+// ExportCollaboratorInteractions writes Collaborator interactions to the given
+// writer. CSV output leaves out the interaction data.
+func ExportCollaboratorInteractions(w io.Writer, interactions []CollaboratorInteraction, opts ExportOptions) error {
+	switch opts.Format {
+	case FormatCSV:
+		return exportCollaboratorCSV(w, interactions)
+	case FormatJSONLines:
+		encoder := json.NewEncoder(w)
+		for _, interaction := range interactions {
+			if err := encoder.Encode(convertCollaboratorInteraction(interaction)); err != nil {
+				return err
+			}
+		}
+		return nil
+	default:
+		exported := make([]ExportedCollaboratorInteraction, 0, len(interactions))
+		for _, interaction := range interactions {
+			exported = append(exported, convertCollaboratorInteraction(interaction))
+		}
+
+		encoder := json.NewEncoder(w)
+		if opts.PrettyPrint {
+			encoder.SetIndent("", "  ")
+		}
+		return encoder.Encode(exported)
+	}
+}
+
+func convertCollaboratorInteraction(interaction CollaboratorInteraction) ExportedCollaboratorInteraction {
+	exported := ExportedCollaboratorInteraction{
+		PayloadID:    interaction.PayloadID,
+		Payload:      interaction.Payload(),
+		Type:         interaction.Type,
+		ClientIP:     interaction.ClientIP,
+		HistoryIDs:   interaction.HistoryIDs,
+		IssueSerials: interaction.IssueSerials,
+	}
+	if !interaction.Timestamp.IsZero() {
+		exported.Timestamp = interaction.Timestamp.Format(time.RFC3339)
+	}
+	if interaction.Type == CollaboratorDNS || !utf8.Valid(interaction.Data) {
+		exported.Data = base64.StdEncoding.EncodeToString(interaction.Data)
+		exported.DataEncoding = "base64"
+	} else {
+		exported.Data = string(interaction.Data)
+	}
+	return exported
+}
+
+func exportCollaboratorCSV(w io.Writer, interactions []CollaboratorInteraction) error {
+	header := "timestamp,type,payload_id,payload,client_ip,history_ids,issue_serials\n"
+	if _, err := w.Write([]byte(header)); err != nil {
+		return err
+	}
+
+	joinIDs := func(ids []uint64) string {
+		parts := make([]string, 0, len(ids))
+		for _, id := range ids {
+			parts = append(parts, strconv.FormatUint(id, 10))
+		}
+		return strings.Join(parts, ";")
+	}
+
+	for _, interaction := range interactions {
+		timestamp := ""
+		if !interaction.Timestamp.IsZero() {
+			timestamp = interaction.Timestamp.Format(time.RFC3339)
+		}
+
+		line := timestamp + "," +
+			interaction.Type.String() + "," +
+			csvEscape(interaction.PayloadID) + "," +
+			csvEscape(interaction.Payload()) + "," +
+			csvEscape(interaction.ClientIP) + "," +
+			joinIDs(interaction.HistoryIDs) + "," +
+			joinIDs(interaction.IssueSerials) + "\n"
+
+		if _, err := w.Write([]byte(line)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ExportIntruderAttacks writes Intruder attacks to the given writer. CSV output
+// is the flattened results grid of every attack.
+func ExportIntruderAttacks(w io.Writer, attacks []IntruderAttack, opts ExportOptions) error {
+	switch opts.Format {
+	case FormatCSV:
+		return exportIntruderResultsCSV(w, attacks)
+	case FormatJSONLines:
+		encoder := json.NewEncoder(w)
+		for _, attack := range attacks {
+			if err := encoder.Encode(attack); err != nil {
+				return err
+			}
+		}
+		return nil
+	default:
+		encoder := json.NewEncoder(w)
+		if opts.PrettyPrint {
+			encoder.SetIndent("", "  ")
+		}
+		return encoder.Encode(attacks)
+	}
+}
+
+func exportIntruderResultsCSV(w io.Writer, attacks []IntruderAttack) error {
+	header := "attack,request,payloads,status_code,length,received_ms,completed_ms,error,grep_matches\n"
+	if _, err := w.Write([]byte(header)); err != nil {
+		return err
+	}
+
+	for _, attack := range attacks {
+		for _, result := range attack.Results {
+			errorFlag := "false"
+			if result.Error {
+				errorFlag = "true"
+			}
+
+			line := csvEscape(attack.Name) + "," +
+				intToString(result.Request) + "," +
+				csvEscape(strings.Join(result.Payloads, ";")) + "," +
+				intToString(result.StatusCode) + "," +
+				intToString(int(result.Length)) + "," +
+				intToString(int(result.ReceivedMs)) + "," +
+				intToString(int(result.CompletedMs)) + "," +
+				errorFlag + "," +
+				csvEscape(strings.Join(result.GrepMatches, ";")) + "\n"
+
+			if _, err := w.Write([]byte(line)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
 // ExportProject writes the complete project to the given writer.
 func ExportProject(w io.Writer, project *Project, opts ExportOptions) error {
 	return Export(w, project.HTTPHistory, opts)
@@ -106,21 +547,29 @@ func exportJSONLines(w io.Writer, entries []HTTPEntry, opts ExportOptions) error
 }
 
 func exportCSV(w io.Writer, entries []HTTPEntry, opts ExportOptions) error {
-	header := "id,timestamp,method,host,path,url,status_code,content_length,mime_type\n"
+	header := "id,timestamp,tool,method,host,path,url,status_code,content_length,mime_type,highlight,comment\n"
 	if _, err := w.Write([]byte(header)); err != nil {
 		return err
 	}
 
 	for _, entry := range entries {
+		timestamp := ""
+		if !entry.Timestamp.IsZero() {
+			timestamp = entry.Timestamp.Format(time.RFC3339)
+		}
+
 		line := csvEscape(intToString(int(entry.ID))) + "," +
-			csvEscape(entry.Timestamp.Format(time.RFC3339)) + "," +
+			csvEscape(timestamp) + "," +
+			csvEscape(exportedToolName(entry.ToolSource)) + "," +
 			csvEscape(entry.Method) + "," +
 			csvEscape(entry.Host) + "," +
 			csvEscape(entry.Path) + "," +
 			csvEscape(entry.URL) + "," +
 			intToString(entry.StatusCode) + "," +
 			intToString(int(entry.ContentLength)) + "," +
-			csvEscape(entry.MIMEType) + "\n"
+			csvEscape(entry.MIMEType) + "," +
+			csvEscape(entry.Highlight) + "," +
+			csvEscape(entry.Comment) + "\n"
 
 		if _, err := w.Write([]byte(line)); err != nil {
 			return err
@@ -137,6 +586,8 @@ func csvEscape(s string) string {
 	return s
 }
 
+const harTimeFormat = "2006-01-02T15:04:05.000Z07:00"
+
 type HARLog struct {
 	Log HARLogContent `json:"log"`
 }
@@ -152,13 +603,18 @@ type HARCreator struct {
 	Version string `json:"version"`
 }
 
+// HAREntry is one HAR log entry. StartedDateTime is left out for history
+// items Burp stored without a time, rather than exported as the zero time.
 type HAREntry struct {
-	StartedDateTime string      `json:"startedDateTime"`
+	StartedDateTime string      `json:"startedDateTime,omitempty"`
 	Time            float64     `json:"time"`
 	Request         HARRequest  `json:"request"`
 	Response        HARResponse `json:"response"`
 	Cache           struct{}    `json:"cache"`
 	Timings         HARTimings  `json:"timings"`
+	Comment         string      `json:"comment,omitempty"`
+	Tool            string      `json:"_tool,omitempty"`
+	Highlight       string      `json:"_highlight,omitempty"`
 }
 
 type HARRequest struct {
@@ -245,15 +701,20 @@ func exportHAR(w io.Writer, entries []HTTPEntry, opts ExportOptions) error {
 
 func convertToHAREntry(entry HTTPEntry, opts ExportOptions) HAREntry {
 	harEntry := HAREntry{
-		StartedDateTime: entry.Timestamp.Format(time.RFC3339),
-		Time:            0,
-		Request:         convertToHARRequest(entry, opts),
-		Response:        convertToHARResponse(entry, opts),
+		Time:     0,
+		Request:  convertToHARRequest(entry, opts),
+		Response: convertToHARResponse(entry, opts),
 		Timings: HARTimings{
 			Send:    -1,
 			Wait:    -1,
 			Receive: -1,
 		},
+		Comment:   entry.Comment,
+		Tool:      exportedToolName(entry.ToolSource),
+		Highlight: entry.Highlight,
+	}
+	if !entry.Timestamp.IsZero() {
+		harEntry.StartedDateTime = entry.Timestamp.Format(harTimeFormat)
 	}
 	return harEntry
 }
@@ -277,15 +738,11 @@ func convertToHARRequest(entry HTTPEntry, opts ExportOptions) HARRequest {
 			}
 		}
 
-		if opts.IncludeBody && entry.Request.Body != nil {
-			bodySize := len(entry.Request.Body)
-			if opts.MaxBodySize > 0 && int64(bodySize) > opts.MaxBodySize {
-				bodySize = int(opts.MaxBodySize)
-			}
-			req.BodySize = bodySize
+		if body, _ := exportBody(entry.Request, opts); body != nil {
+			req.BodySize = len(body)
 			req.PostData = &HARPostData{
 				MimeType: "application/octet-stream",
-				Text:     string(entry.Request.Body[:bodySize]),
+				Text:     string(body),
 			}
 		}
 	}
@@ -300,11 +757,25 @@ func convertToHARRequest(entry HTTPEntry, opts ExportOptions) HARRequest {
 	return req
 }
 
+// responseHTTPVersion returns the HTTP version from the response status line,
+// falling back to the request's version.
+func responseHTTPVersion(entry HTTPEntry) string {
+	if entry.Response != nil {
+		if fields := strings.Fields(entry.Response.StartLine); len(fields) > 0 && strings.HasPrefix(fields[0], "HTTP/") {
+			return fields[0]
+		}
+	}
+	if entry.Protocol != "" {
+		return entry.Protocol
+	}
+	return "HTTP/1.1"
+}
+
 func convertToHARResponse(entry HTTPEntry, opts ExportOptions) HARResponse {
 	resp := HARResponse{
 		Status:      entry.StatusCode,
 		StatusText:  getStatusText(entry.StatusCode),
-		HTTPVersion: "HTTP/1.1",
+		HTTPVersion: responseHTTPVersion(entry),
 		Headers:     make([]HARHeader, 0),
 		Cookies:     make([]HARCookie, 0),
 		Content: HARContent{
@@ -323,17 +794,14 @@ func convertToHARResponse(entry HTTPEntry, opts ExportOptions) HARResponse {
 			}
 		}
 
-		if opts.IncludeBody && entry.Response.Body != nil {
-			bodySize := len(entry.Response.Body)
-			if opts.MaxBodySize > 0 && int64(bodySize) > opts.MaxBodySize {
-				bodySize = int(opts.MaxBodySize)
-			}
+		if body, size := exportBody(entry.Response, opts); body != nil {
+			resp.Content.Size = size
 
 			if isBinaryContent(entry.MIMEType) {
-				resp.Content.Text = base64.StdEncoding.EncodeToString(entry.Response.Body[:bodySize])
+				resp.Content.Text = base64.StdEncoding.EncodeToString(body)
 				resp.Content.Encoding = "base64"
 			} else {
-				resp.Content.Text = string(entry.Response.Body[:bodySize])
+				resp.Content.Text = string(body)
 			}
 		}
 	}
@@ -347,6 +815,7 @@ func convertToExported(entry HTTPEntry, opts ExportOptions) ExportedEntry {
 		Host:          entry.Host,
 		Port:          entry.Port,
 		Protocol:      entry.Protocol,
+		Scheme:        entry.Scheme,
 		Method:        entry.Method,
 		Path:          entry.Path,
 		URL:           entry.URL,
@@ -354,6 +823,9 @@ func convertToExported(entry HTTPEntry, opts ExportOptions) ExportedEntry {
 		StatusCode:    entry.StatusCode,
 		ContentLength: entry.ContentLength,
 		MIMEType:      entry.MIMEType,
+		Tool:          exportedToolName(entry.ToolSource),
+		Highlight:     entry.Highlight,
+		Comment:       entry.Comment,
 	}
 
 	if !entry.Timestamp.IsZero() {
@@ -371,6 +843,13 @@ func convertToExported(entry HTTPEntry, opts ExportOptions) ExportedEntry {
 	return exported
 }
 
+func exportedToolName(t ToolType) string {
+	if t == ToolUnknown {
+		return ""
+	}
+	return t.String()
+}
+
 func convertMessage(msg *HTTPMessage, opts ExportOptions) *ExportedMessage {
 	exported := &ExportedMessage{
 		StartLine: msg.StartLine,
@@ -383,25 +862,57 @@ func convertMessage(msg *HTTPMessage, opts ExportOptions) *ExportedMessage {
 		}
 	}
 
-	if opts.IncludeBody && msg.Body != nil {
-		bodySize := len(msg.Body)
-		exported.BodySize = bodySize
+	if body, size := exportBody(msg, opts); body != nil {
+		exported.BodySize = size
+		exported.Body = string(body)
+	}
 
-		if opts.MaxBodySize > 0 && int64(bodySize) > opts.MaxBodySize {
-			bodySize = int(opts.MaxBodySize)
+	if opts.IncludeRaw && msg.Size() > 0 {
+		limit := int64(-1)
+		if opts.MaxBodySize > 0 {
+			limit = opts.MaxBodySize * 2
 		}
-		exported.Body = string(msg.Body[:bodySize])
+		exported.Raw = string(readPrefix(msg.RawReader(), limit))
+	}
+
+	return exported
+}
+
+// exportBody returns the body to export for msg, decoded unless opts.RawBody
+// is set and cut to opts.MaxBodySize, together with its full size. Bodies
+// that need no decoding are streamed from the project, so only the exported
+// part is read however large the body is.
+func exportBody(msg *HTTPMessage, opts ExportOptions) ([]byte, int) {
+	if msg == nil || msg.BodySize() == 0 || !opts.IncludeBody {
+		return nil, 0
 	}
 
-	if opts.IncludeRaw && msg.Raw != nil {
-		rawSize := len(msg.Raw)
-		if opts.MaxBodySize > 0 && int64(rawSize) > opts.MaxBodySize*2 {
-			rawSize = int(opts.MaxBodySize * 2)
+	if opts.RawBody || !msg.hasBodyCodings() {
+		limit := int64(-1)
+		if opts.MaxBodySize > 0 {
+			limit = opts.MaxBodySize
 		}
-		exported.Raw = string(msg.Raw[:rawSize])
+		return readPrefix(msg.BodyReader(), limit), msg.BodySize()
 	}
 
-	return exported
+	body := msg.DecodedBody()
+	size := len(body)
+	if opts.MaxBodySize > 0 && int64(size) > opts.MaxBodySize {
+		body = body[:opts.MaxBodySize]
+	}
+	return body, size
+}
+
+// readPrefix reads up to limit bytes from r, or all of it when limit is
+// negative.
+func readPrefix(r *io.SectionReader, limit int64) []byte {
+	n := r.Size()
+	if limit >= 0 && n > limit {
+		n = limit
+	}
+	buf := make([]byte, n)
+	read, _ := io.ReadFull(r, buf)
+	return buf[:read]
 }
 
 func parseQueryString(qs string) map[string]string {
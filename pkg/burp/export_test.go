@@ -0,0 +1,34 @@
+package burp
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestHARLeavesOutMissingTimes(t *testing.T) {
+	entries := []HTTPEntry{
+		{Method: "GET", URL: "http://a.example/", Timestamp: time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)},
+		{Method: "GET", URL: "http://a.example/undated"},
+	}
+	var buf bytes.Buffer
+	if err := exportHAR(&buf, entries, ExportOptions{Format: FormatHAR}); err != nil {
+		t.Fatal(err)
+	}
+
+	var har struct {
+		Log struct {
+			Entries []map[string]any `json:"entries"`
+		} `json:"log"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &har); err != nil {
+		t.Fatal(err)
+	}
+	if got := har.Log.Entries[0]["startedDateTime"]; got != "2025-01-02T03:04:05.000Z" {
+		t.Errorf("dated entry startedDateTime = %v", got)
+	}
+	if got, ok := har.Log.Entries[1]["startedDateTime"]; ok {
+		t.Errorf("undated entry startedDateTime = %v, want it left out", got)
+	}
+}
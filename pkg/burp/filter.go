@@ -1,6 +1,7 @@
 package burp
 
 import (
+	"context"
 	"regexp"
 	"strings"
 	"time"
@@ -21,6 +22,9 @@ type Filter struct {
 	timeTo          time.Time
 	hasResponse     *bool
 	tools           []ToolType
+	highlights      []string
+	commentContains string
+	scope           *TargetScope
 	contentContains string
 	headerContains  string
 	bodyContains    string
@@ -102,20 +106,23 @@ func (f *Filter) WithMaxSize(max int64) *Filter {
 	return f
 }
 
-// WithTimeRange filters by timestamp range.
+// WithTimeRange filters by timestamp range. Like WithTimeFrom and
+// WithTimeTo, it leaves out entries without a timestamp.
 func (f *Filter) WithTimeRange(from, to time.Time) *Filter {
 	f.timeFrom = from
 	f.timeTo = to
 	return f
 }
 
-// WithTimeFrom filters entries after the given time.
+// WithTimeFrom filters entries after the given time, leaving out entries
+// without a timestamp.
 func (f *Filter) WithTimeFrom(from time.Time) *Filter {
 	f.timeFrom = from
 	return f
 }
 
-// WithTimeTo filters entries before the given time.
+// WithTimeTo filters entries before the given time, leaving out entries
+// without a timestamp.
 func (f *Filter) WithTimeTo(to time.Time) *Filter {
 	f.timeTo = to
 	return f
@@ -133,7 +140,31 @@ func (f *Filter) WithTool(tools ...ToolType) *Filter {
 	return f
 }
 
-// WithContentContains filters entries containing the string in request or response.
+// WithHighlight filters by highlight colour (e.g. "red"). The value "any"
+// matches every highlighted entry and "none" matches unhighlighted ones.
+func (f *Filter) WithHighlight(colours ...string) *Filter {
+	f.highlights = make([]string, len(colours))
+	for i, c := range colours {
+		f.highlights[i] = strings.ToLower(strings.TrimSpace(c))
+	}
+	return f
+}
+
+// WithCommentContains filters entries whose comment contains the string (case-insensitive).
+func (f *Filter) WithCommentContains(s string) *Filter {
+	f.commentContains = s
+	return f
+}
+
+// WithScope filters entries to those in the given Target scope.
+func (f *Filter) WithScope(scope *TargetScope) *Filter {
+	f.scope = scope
+	return f
+}
+
+// WithContentContains filters entries containing the string in request or
+// response. Bodies are matched after decoding, as are those for
+// WithBodyContains.
 func (f *Filter) WithContentContains(s string) *Filter {
 	f.contentContains = s
 	return f
@@ -221,6 +252,10 @@ func (f *Filter) Match(entry HTTPEntry) bool {
 		return false
 	}
 
+	if (!f.timeFrom.IsZero() || !f.timeTo.IsZero()) && entry.Timestamp.IsZero() {
+		return false
+	}
+
 	if !f.timeFrom.IsZero() && entry.Timestamp.Before(f.timeFrom) {
 		return false
 	}
@@ -249,15 +284,43 @@ func (f *Filter) Match(entry HTTPEntry) bool {
 		}
 	}
 
+	if len(f.highlights) > 0 {
+		found := false
+		for _, c := range f.highlights {
+			switch c {
+			case "any":
+				found = entry.Highlight != ""
+			case "none":
+				found = entry.Highlight == ""
+			default:
+				found = strings.EqualFold(entry.Highlight, c)
+			}
+			if found {
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+
+	if f.commentContains != "" && !strings.Contains(strings.ToLower(entry.Comment), strings.ToLower(f.commentContains)) {
+		return false
+	}
+
+	if f.scope != nil && !f.scope.InScope(entry) {
+		return false
+	}
+
 	if f.contentContains != "" {
 		found := false
 		if entry.Request != nil {
-			if strings.Contains(string(entry.Request.Raw), f.contentContains) {
+			if strings.Contains(string(entry.Request.DecodedRaw()), f.contentContains) {
 				found = true
 			}
 		}
 		if !found && entry.Response != nil {
-			if strings.Contains(string(entry.Response.Raw), f.contentContains) {
+			if strings.Contains(string(entry.Response.DecodedRaw()), f.contentContains) {
 				found = true
 			}
 		}
@@ -303,13 +366,13 @@ func (f *Filter) Match(entry HTTPEntry) bool {
 
 	if f.bodyContains != "" {
 		found := false
-		if entry.Request != nil && entry.Request.Body != nil {
-			if strings.Contains(string(entry.Request.Body), f.bodyContains) {
+		if entry.Request.BodySize() > 0 {
+			if strings.Contains(string(entry.Request.DecodedBody()), f.bodyContains) {
 				found = true
 			}
 		}
-		if !found && entry.Response != nil && entry.Response.Body != nil {
-			if strings.Contains(string(entry.Response.Body), f.bodyContains) {
+		if !found && entry.Response.BodySize() > 0 {
+			if strings.Contains(string(entry.Response.DecodedBody()), f.bodyContains) {
 				found = true
 			}
 		}
@@ -336,6 +399,30 @@ func FilterHTTPHistory(entries []HTTPEntry, f *Filter) []HTTPEntry {
 	return result
 }
 
+// FilterHTTPHistoryStream filters streamed HTTP entries, closing the returned
+// channel when the input is drained or ctx is cancelled.
+func FilterHTTPHistoryStream(ctx context.Context, entryChan <-chan HTTPEntry, f *Filter) <-chan HTTPEntry {
+	if f == nil {
+		return entryChan
+	}
+
+	out := make(chan HTTPEntry, 100)
+	go func() {
+		defer close(out)
+		for entry := range entryChan {
+			if !f.Match(entry) {
+				continue
+			}
+			select {
+			case out <- entry:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return out
+}
+
 // ParseStatusCodes parses a status code string like "200,301-399,500".
 func ParseStatusCodes(s string) (codes []int, minCode int, maxCode int) {
 	parts := strings.Split(s, ",")
@@ -0,0 +1,45 @@
+package burp
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestWithHighlightKeepsCallerSlice(t *testing.T) {
+	colours := []string{" Red ", "ANY"}
+	f := NewFilter().WithHighlight(colours...)
+
+	if want := []string{" Red ", "ANY"}; !slices.Equal(colours, want) {
+		t.Errorf("WithHighlight changed its argument to %q", colours)
+	}
+	if !f.Match(HTTPEntry{Highlight: "red"}) {
+		t.Error("filter does not match a red entry")
+	}
+}
+
+func TestHighlightColoursReturnsCopy(t *testing.T) {
+	HighlightColours()[0] = "black"
+	if got := highlightFromBurpByte(1); got != "red" {
+		t.Errorf("highlightFromBurpByte(1) = %q after changing the returned slice", got)
+	}
+}
+
+func TestTimeFilterLeavesOutUndatedEntries(t *testing.T) {
+	from := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	dated := HTTPEntry{Timestamp: from.Add(time.Hour)}
+	undated := HTTPEntry{}
+
+	for name, f := range map[string]*Filter{
+		"from":  NewFilter().WithTimeFrom(from),
+		"to":    NewFilter().WithTimeTo(from.Add(24 * time.Hour)),
+		"range": NewFilter().WithTimeRange(from, from.Add(24*time.Hour)),
+	} {
+		if !f.Match(dated) {
+			t.Errorf("%s: dated entry left out", name)
+		}
+		if f.Match(undated) {
+			t.Errorf("%s: undated entry matched", name)
+		}
+	}
+}
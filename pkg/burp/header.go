@@ -0,0 +1,69 @@
+package burp
+
+import (
+	stdbinary "encoding/binary"
+	"fmt"
+	"strings"
+)
+
+// Layout of the fixed project header. Fields Burp has not written (older
+// files, or projects that were never saved) are left zeroed.
+const (
+	headerFormatVersionOffset  = 0x04
+	headerCreatedOffset        = 0x08
+	headerSavedOffset          = 0x10
+	headerEditionOffset        = 0x18
+	headerBurpVersionLenOffset = 0x1a
+	headerBurpVersionOffset    = 0x1c
+	headerProjectOptionsOffset = 0x60
+
+	maxHeaderBurpVersionLen = headerProjectOptionsOffset - headerBurpVersionOffset
+)
+
+// MaxSupportedFormatVersion is the newest project file format version this
+// package has a layout for. Files of other versions are rejected with
+// ErrUnsupportedFormat unless opened in recovery mode or with an assumed
+// version.
+const MaxSupportedFormatVersion uint32 = 2
+
+// readProjectHeader decodes the fixed-size header at the start of the file
+// into meta.
+func (p *Parser) readProjectHeader(meta *ProjectMetadata) error {
+	header, err := p.reader.ReadAt(0, HeaderSize)
+	if err != nil || len(header) < HeaderSize {
+		return fmt.Errorf("read project header: %w", err)
+	}
+
+	meta.FormatVersion = stdbinary.BigEndian.Uint32(header[headerFormatVersionOffset:])
+	meta.CreatedAt = timeFromBurpMillis(stdbinary.BigEndian.Uint64(header[headerCreatedOffset:]))
+	meta.ModifiedAt = timeFromBurpMillis(stdbinary.BigEndian.Uint64(header[headerSavedOffset:]))
+	meta.BurpEdition = burpEditionFromByte(header[headerEditionOffset])
+
+	versionLen := int(stdbinary.BigEndian.Uint16(header[headerBurpVersionLenOffset:]))
+	if versionLen > 0 && versionLen <= maxHeaderBurpVersionLen {
+		version := header[headerBurpVersionOffset : headerBurpVersionOffset+versionLen]
+		meta.BurpVersion = strings.TrimSpace(strings.TrimRight(string(version), "\x00"))
+	}
+
+	optionsPtr := int64(stdbinary.BigEndian.Uint64(header[headerProjectOptionsOffset:]))
+	if optionsPtr >= int64(HeaderSize) && optionsPtr < p.reader.Size() {
+		if options, err := p.readUTF8StringRecord(optionsPtr); err == nil {
+			meta.ProjectOptions = strings.TrimSpace(options)
+		}
+	}
+
+	return nil
+}
+
+func burpEditionFromByte(b byte) string {
+	switch b {
+	case 1:
+		return "Community"
+	case 2:
+		return "Professional"
+	case 3:
+		return "Enterprise"
+	default:
+		return ""
+	}
+}
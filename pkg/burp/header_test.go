@@ -0,0 +1,65 @@
+package burp
+
+import (
+	stdbinary "encoding/binary"
+	"testing"
+	"time"
+)
+
+func TestReadProjectHeader(t *testing.T) {
+	created := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	saved := time.Date(2025, 10, 9, 9, 1, 40, 0, time.UTC)
+	version := "2025.9.4"
+
+	b := newProjectBuilder()
+	options := b.message(` {"proxy":{"intercept":false}} `)
+	header := b.buf[:HeaderSize]
+	stdbinary.BigEndian.PutUint32(header[headerFormatVersionOffset:], 2)
+	stdbinary.BigEndian.PutUint64(header[headerCreatedOffset:], uint64(created.UnixMilli()))
+	stdbinary.BigEndian.PutUint64(header[headerSavedOffset:], uint64(saved.UnixMilli()))
+	header[headerEditionOffset] = 2
+	stdbinary.BigEndian.PutUint16(header[headerBurpVersionLenOffset:], uint16(len(version)))
+	copy(header[headerBurpVersionOffset:], version)
+	stdbinary.BigEndian.PutUint64(header[headerProjectOptionsOffset:], uint64(options))
+
+	r, err := Open(b.write(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	meta := r.Metadata()
+	want := ProjectMetadata{
+		CreatedAt:      created,
+		ModifiedAt:     saved,
+		BurpVersion:    version,
+		BurpEdition:    "Professional",
+		FormatVersion:  2,
+		ProjectOptions: `{"proxy":{"intercept":false}}`,
+		FileSize:       meta.FileSize,
+	}
+	if *meta != want {
+		t.Fatalf("Metadata = %+v, want %+v", *meta, want)
+	}
+}
+
+func TestReadProjectHeaderLeavesUnwrittenFieldsZero(t *testing.T) {
+	b := newProjectBuilder()
+	header := b.buf[:HeaderSize]
+	// A version length running past the version field and an options
+	// pointer into the header are both ignored.
+	stdbinary.BigEndian.PutUint16(header[headerBurpVersionLenOffset:], maxHeaderBurpVersionLen+1)
+	stdbinary.BigEndian.PutUint64(header[headerProjectOptionsOffset:], 0x10)
+
+	r, err := Open(b.write(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	meta := r.Metadata()
+	if !meta.CreatedAt.IsZero() || !meta.ModifiedAt.IsZero() || meta.BurpVersion != "" ||
+		meta.BurpEdition != "" || meta.FormatVersion != 0 || meta.ProjectOptions != "" {
+		t.Fatalf("Metadata = %+v, want only FileSize set", *meta)
+	}
+}
@@ -0,0 +1,276 @@
+package burp
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+)
+
+// Record categories used in health reports.
+const (
+	CategoryHeader       = "header"
+	CategoryHistory      = "history"
+	CategoryRepeater     = "repeater"
+	CategoryIntruder     = "intruder"
+	CategoryScope        = "scope"
+	CategoryWebSockets   = "websockets"
+	CategoryCollaborator = "collaborator"
+	CategoryIssues       = "issues"
+	CategoryMessages     = "messages"
+)
+
+// errRecordTruncated marks a record that runs past the end of the file,
+// which is what a project cut short by a crash or a full disk looks like.
+var errRecordTruncated = errors.New("record runs past end of file")
+
+// DamagedRegion is a record that could not be read, or that was Salvaged: read
+// in part, such as a message cut off by the end of the file. Offset is where
+// the record starts in the project file.
+type DamagedRegion struct {
+	Category  string `json:"category"`
+	Offset    int64  `json:"offset"`
+	Reason    string `json:"reason"`
+	Truncated bool   `json:"truncated,omitempty"`
+	Salvaged  bool   `json:"salvaged,omitempty"`
+}
+
+// CategoryHealth counts the records of one category that were read, those
+// that could only be read in part, such as messages cut off by the end of the
+// file, and those that had to be given up on. Salvaged records are also
+// counted as Recovered.
+type CategoryHealth struct {
+	Category      string `json:"category"`
+	Recovered     int    `json:"recovered"`
+	Salvaged      int    `json:"salvaged"`
+	Unrecoverable int    `json:"unrecoverable"`
+}
+
+// HealthReport is the result of Check.
+type HealthReport struct {
+	Path       string           `json:"path"`
+	FileSize   int64            `json:"fileSize"`
+	HeaderOK   bool             `json:"headerOk"`
+	Truncated  bool             `json:"truncated"`
+	Categories []CategoryHealth `json:"categories"`
+	Damaged    []DamagedRegion  `json:"damaged,omitempty"`
+}
+
+// Healthy reports whether the check found no damage at all.
+func (h *HealthReport) Healthy() bool {
+	return h.HeaderOK && len(h.Damaged) == 0
+}
+
+// damageLog collects the damaged regions met while reading a project in
+// recovery mode. A region is recorded once however often it is read. A nil
+// log records nothing, which is the normal, non-recovering case.
+type damageLog struct {
+	mu      sync.Mutex
+	regions map[damageKey]DamagedRegion
+}
+
+type damageKey struct {
+	category string
+	offset   int64
+}
+
+func newDamageLog() *damageLog {
+	return &damageLog{regions: make(map[damageKey]DamagedRegion)}
+}
+
+func (l *damageLog) note(category string, offset int64, err error, salvaged bool) {
+	if l == nil || err == nil {
+		return
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	key := damageKey{category, offset}
+	if _, ok := l.regions[key]; ok {
+		return
+	}
+	l.regions[key] = DamagedRegion{
+		Category:  category,
+		Offset:    offset,
+		Reason:    err.Error(),
+		Truncated: errors.Is(err, errRecordTruncated),
+		Salvaged:  salvaged,
+	}
+}
+
+// snapshot returns the recorded regions ordered by offset.
+func (l *damageLog) snapshot() []DamagedRegion {
+	if l == nil {
+		return nil
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	regions := make([]DamagedRegion, 0, len(l.regions))
+	for _, r := range l.regions {
+		regions = append(regions, r)
+	}
+	sort.Slice(regions, func(i, j int) bool {
+		if regions[i].Offset != regions[j].Offset {
+			return regions[i].Offset < regions[j].Offset
+		}
+		return regions[i].Category < regions[j].Category
+	})
+	return regions
+}
+
+// recovering reports whether the parser salvages damaged files instead of
+// failing on them.
+func (p *Parser) recovering() bool {
+	return p.damage != nil
+}
+
+// noteDamage records a record that could not be read when recovering.
+func (p *Parser) noteDamage(category string, offset int64, err error) {
+	p.damage.note(category, offset, err, false)
+}
+
+// noteSalvaged records a record that could only be read in part when
+// recovering.
+func (p *Parser) noteSalvaged(category string, offset int64, err error) {
+	p.damage.note(category, offset, err, true)
+}
+
+// DamagedRegions returns the damaged regions met so far. It is always empty
+// unless the reader was opened with ReaderOptions.Recover.
+func (r *Reader) DamagedRegions() []DamagedRegion {
+	return r.parser.damage.snapshot()
+}
+
+// Check opens the project at path in recovery mode, reads every kind of
+// record it knows about and reports which could be recovered and where the
+// file is damaged. The index and history preloading options are ignored.
+// Cancelling ctx stops the check and returns ctx.Err() without a report, as
+// one covering only part of the file would say nothing about the rest.
+func Check(ctx context.Context, path string, opts ReaderOptions) (*HealthReport, error) {
+	opts.Recover = true
+	opts.IndexPath = ""
+	opts.PreloadHistory = false
+
+	r, err := OpenWithOptions(path, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	return CheckReader(ctx, r)
+}
+
+// CheckReader is Check for a reader that is already open, such as one reading
+// from stdin. The reader must have been opened with ReaderOptions.Recover and
+// without an index; otherwise damage stops the check instead of being
+// reported.
+func CheckReader(ctx context.Context, r *Reader) (*HealthReport, error) {
+	if !r.parser.recovering() {
+		return nil, errors.New("check needs a reader opened with ReaderOptions.Recover")
+	}
+
+	report := &HealthReport{
+		Path:     r.path,
+		FileSize: r.metadata.FileSize,
+	}
+	add := func(category string, recovered int) {
+		report.Categories = append(report.Categories, CategoryHealth{Category: category, Recovered: recovered})
+	}
+
+	// A scan that fails outright is damage too; it is noted against the
+	// start of the file as there is no one record to blame. A scan stopped
+	// by ctx is not damage and ends the check.
+	noteErr := func(category string, err error) error {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return ctxErr
+		}
+		if err != nil {
+			r.parser.noteDamage(category, 0, err)
+		}
+		return nil
+	}
+
+	entries, err := r.HTTPHistoryContext(ctx)
+	if err := noteErr(CategoryHistory, err); err != nil {
+		return nil, err
+	}
+	add(CategoryHistory, len(entries))
+
+	messages := 0
+	for _, entry := range entries {
+		if entry.Request != nil {
+			messages++
+		}
+		if entry.Response != nil {
+			messages++
+		}
+	}
+	add(CategoryMessages, messages)
+
+	tabs, err := r.RepeaterTabsContext(ctx)
+	if err := noteErr(CategoryRepeater, err); err != nil {
+		return nil, err
+	}
+	add(CategoryRepeater, len(tabs))
+
+	attacks, err := r.IntruderAttacksContext(ctx)
+	if err := noteErr(CategoryIntruder, err); err != nil {
+		return nil, err
+	}
+	add(CategoryIntruder, len(attacks))
+
+	scope, err := r.TargetScopeContext(ctx)
+	if err := noteErr(CategoryScope, err); err != nil {
+		return nil, err
+	}
+	if scope != nil {
+		add(CategoryScope, 1)
+	} else {
+		add(CategoryScope, 0)
+	}
+
+	conns, err := r.WebSocketHistoryContext(ctx)
+	if err := noteErr(CategoryWebSockets, err); err != nil {
+		return nil, err
+	}
+	add(CategoryWebSockets, len(conns))
+
+	interactions, err := r.parser.ScanCollaboratorInteractionsContext(ctx)
+	if err := noteErr(CategoryCollaborator, err); err != nil {
+		return nil, err
+	}
+	add(CategoryCollaborator, len(interactions))
+
+	issues, err := r.ScannerIssueMetasContext(ctx)
+	if err := noteErr(CategoryIssues, err); err != nil {
+		return nil, err
+	}
+	add(CategoryIssues, len(issues))
+
+	report.Damaged = r.DamagedRegions()
+	report.HeaderOK = true
+	for _, region := range report.Damaged {
+		if region.Category == CategoryHeader {
+			report.HeaderOK = false
+		}
+		if region.Truncated {
+			report.Truncated = true
+		}
+		for i := range report.Categories {
+			c := &report.Categories[i]
+			if c.Category != region.Category {
+				continue
+			}
+			if region.Salvaged {
+				c.Salvaged++
+			} else {
+				c.Unrecoverable++
+			}
+		}
+	}
+
+	return report, nil
+}
@@ -0,0 +1,49 @@
+package burp
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestCheckReportsRecords(t *testing.T) {
+	b := newProjectBuilder()
+	b.list(b.historyItem("GET /a HTTP/1.1\r\nHost: a\r\n\r\n", "HTTP/1.1 200 OK\r\n\r\n", ""))
+	path := b.write(t)
+
+	report, err := Check(context.Background(), path, DefaultReaderOptions())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !report.Healthy() {
+		t.Errorf("report has damage: %+v", report.Damaged)
+	}
+	for _, c := range report.Categories {
+		want := 0
+		switch c.Category {
+		case CategoryHistory:
+			want = 1
+		case CategoryMessages:
+			want = 2
+		}
+		if c.Recovered != want {
+			t.Errorf("%s: recovered %d, want %d", c.Category, c.Recovered, want)
+		}
+	}
+}
+
+func TestCheckStopsWhenCancelled(t *testing.T) {
+	b := newProjectBuilder()
+	b.list(b.historyItem("GET /a HTTP/1.1\r\nHost: a\r\n\r\n", "", ""))
+	path := b.write(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	report, err := Check(ctx, path, DefaultReaderOptions())
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("Check returned %v, want context.Canceled", err)
+	}
+	if report != nil {
+		t.Errorf("Check returned a report for a cancelled check: %+v", report)
+	}
+}
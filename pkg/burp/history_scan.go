@@ -0,0 +1,336 @@
+package burp
+
+import (
+	stdbinary "encoding/binary"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Proxy history items are typed records (see readTypedRecordHeader) referenced
+// from a list wrapper whose pointer vector keeps them in the order Burp shows
+// them. The request and response fields point at length-prefixed message
+// records: u32 total length, u32 data length, then the raw message bytes.
+const (
+	proxyHistoryItemType uint16 = 0x000c
+
+	historyItemFieldRequest  byte = 0x00
+	historyItemFieldResponse byte = 0x01
+	historyItemFieldTime     byte = 0x02
+	historyItemFieldTool     byte = 0x03
+	historyItemFieldComment  byte = 0x04
+	historyItemFieldColour   byte = 0x05
+	historyItemFieldService  byte = 0x06
+
+	messageRecordHeaderLen = 8
+	maxHistoryItemCount    = 10_000_000
+)
+
+// History items start with one of two fixed headers, depending on whether
+// Burp recorded the target service. Only recovery looks for them directly;
+// normally items are reached through the history table.
+var (
+	historyItemSignature            = []byte{0x00, 0x0c, 0x00, 0x06, 0x00, 0x00, 0x16}
+	historyItemWithServiceSignature = []byte{0x00, 0x0c, 0x00, 0x07, 0x00, 0x00, 0x19}
+)
+
+// findProxyHistoryTable locates the list wrapper holding the Proxy history
+// items. Burp may leave superseded copies of the table behind when the history
+// grows, so the candidate with the most items wins and later offsets win ties.
+// It returns -1 when the project has no recognisable history table.
+func (p *Parser) findProxyHistoryTable(s *scanRun) (int64, error) {
+	wrappers, err := s.offsets(sigListWrapper)
+	if err != nil {
+		return -1, err
+	}
+
+	best := int64(-1)
+	var bestCount uint32
+
+	for _, abs := range wrappers {
+		if err := s.at(abs); err != nil {
+			return -1, err
+		}
+		if count, ok := p.probeProxyHistoryTable(abs); ok && count >= bestCount {
+			best = abs
+			bestCount = count
+		}
+	}
+
+	return best, nil
+}
+
+// probeProxyHistoryTable checks whether the list wrapper at offset points at
+// Proxy history items without reading the whole pointer vector.
+func (p *Parser) probeProxyHistoryTable(offset int64) (uint32, bool) {
+	count, vecPtr, err := p.readListWrapper(offset)
+	if err != nil || count == 0 || count > maxHistoryItemCount {
+		return 0, false
+	}
+
+	head, err := p.reader.ReadAt(vecPtr, 16)
+	if err != nil || len(head) < 16 {
+		return 0, false
+	}
+	capacity := stdbinary.BigEndian.Uint32(head[4:8])
+	if capacity < count {
+		return 0, false
+	}
+
+	first := int64(stdbinary.BigEndian.Uint64(head[8:16]))
+	if first < int64(HeaderSize) || first >= p.reader.Size() {
+		return 0, false
+	}
+
+	rec, err := p.readTypedRecordHeader(first)
+	if err != nil || rec.Type != proxyHistoryItemType {
+		return 0, false
+	}
+	if _, ok := rec.fieldOffset(historyItemFieldRequest); !ok {
+		return 0, false
+	}
+
+	return count, true
+}
+
+// readProxyHistoryTable reads the history items listed by the table at
+// tableOffset. skipped counts the listed items that could not be read,
+// including those lost with the end of a cut-off pointer vector. When s is
+// cancelled, the items read so far are returned with the context's error.
+func (p *Parser) readProxyHistoryTable(s *scanRun, tableOffset int64) (locations []HTTPRecordLocation, skipped int, err error) {
+	count, vecPtr, err := p.readListWrapper(tableOffset)
+	if err != nil {
+		return nil, 0, fmt.Errorf("read proxy history list wrapper at 0x%x: %w", tableOffset, err)
+	}
+
+	ptrs, err := p.readPointerVector(vecPtr)
+	if err != nil {
+		// A vector cut off by the end of the file still holds the
+		// pointers before the cut.
+		if !p.recovering() || len(ptrs) == 0 {
+			return nil, 0, fmt.Errorf("read proxy history pointer vector at 0x%x: %w", vecPtr, err)
+		}
+		p.noteSalvaged(CategoryHistory, vecPtr, err)
+	}
+	if uint32(len(ptrs)) < count {
+		err := fmt.Errorf("proxy history vector too small: have=%d need=%d", len(ptrs), count)
+		if !p.recovering() {
+			return nil, 0, err
+		}
+		p.noteDamage(CategoryHistory, vecPtr, err)
+		skipped = int(count) - len(ptrs)
+		count = uint32(len(ptrs))
+	}
+
+	locations = make([]HTTPRecordLocation, 0, count)
+	for i := uint32(0); i < count; i++ {
+		if err := s.err(); err != nil {
+			return locations, skipped, err
+		}
+		loc, err := p.readProxyHistoryItem(ptrs[i])
+		if err != nil {
+			p.noteDamage(CategoryHistory, ptrs[i], err)
+			skipped++
+			continue
+		}
+		locations = append(locations, loc)
+		s.found()
+	}
+
+	return locations, skipped, nil
+}
+
+func (p *Parser) readProxyHistoryItem(itemPtr int64) (HTTPRecordLocation, error) {
+	loc := HTTPRecordLocation{ItemOffset: itemPtr}
+
+	if itemPtr < int64(HeaderSize) || itemPtr >= p.reader.Size() {
+		return loc, fmt.Errorf("invalid history item pointer: 0x%x", itemPtr)
+	}
+
+	rec, err := p.readTypedRecordHeader(itemPtr)
+	if err != nil {
+		return loc, fmt.Errorf("read history item header at 0x%x: %w", itemPtr, err)
+	}
+	if rec.Type != proxyHistoryItemType {
+		return loc, fmt.Errorf("unexpected history item type at 0x%x: %d", itemPtr, rec.Type)
+	}
+
+	reqOff, ok := rec.fieldOffset(historyItemFieldRequest)
+	if !ok {
+		return loc, errors.New("history item missing request field")
+	}
+	reqPtr, err := p.readPointerAt(itemPtr + int64(reqOff))
+	if err != nil {
+		return loc, fmt.Errorf("read history item request pointer: %w", err)
+	}
+	loc.RequestOffset, loc.RequestLength, err = p.readMessageRecord(reqPtr)
+	if err != nil {
+		p.noteDamage(CategoryMessages, reqPtr, err)
+		return loc, fmt.Errorf("read history item request at 0x%x: %w", reqPtr, err)
+	}
+
+	respOff, ok := rec.fieldOffset(historyItemFieldResponse)
+	if !ok {
+		return loc, nil
+	}
+	rawRespPtr, err := p.reader.ReadUint64At(itemPtr + int64(respOff))
+	if err != nil || rawRespPtr == 0 {
+		return loc, nil
+	}
+	respOffset, respLength, err := p.readMessageRecord(int64(rawRespPtr))
+	if err != nil {
+		// The request is still worth having without its response.
+		p.noteDamage(CategoryMessages, int64(rawRespPtr), err)
+		return loc, nil
+	}
+	loc.ResponseOffset = respOffset
+	loc.ResponseLength = respLength
+
+	return loc, nil
+}
+
+// readMessageRecord returns the offset and exact length of the message bytes
+// stored in the length-prefixed record at offset. An empty record is
+// rejected, as no HTTP message is empty.
+func (p *Parser) readMessageRecord(offset int64) (int64, int, error) {
+	return p.readLengthPrefixedRecord(offset, false)
+}
+
+// readLengthPrefixedRecord is readMessageRecord that also accepts an empty
+// record when allowEmpty is set, as for a WebSocket frame with no payload.
+func (p *Parser) readLengthPrefixedRecord(offset int64, allowEmpty bool) (int64, int, error) {
+	if offset < int64(HeaderSize) || offset >= p.reader.Size() {
+		return 0, 0, fmt.Errorf("invalid message record offset: 0x%x", offset)
+	}
+
+	hdr, err := p.reader.ReadAt(offset, messageRecordHeaderLen)
+	if err != nil || len(hdr) < messageRecordHeaderLen {
+		return 0, 0, fmt.Errorf("read message record header: %w", err)
+	}
+
+	totalLen := stdbinary.BigEndian.Uint32(hdr[0:4])
+	dataLen := stdbinary.BigEndian.Uint32(hdr[4:8])
+	if uint64(totalLen) != uint64(dataLen)+messageRecordHeaderLen || (dataLen == 0 && !allowEmpty) {
+		return 0, 0, fmt.Errorf("invalid message record length: total=%d data=%d", totalLen, dataLen)
+	}
+
+	dataOffset := offset + messageRecordHeaderLen
+	if !p.withinRecordLimit(int64(dataLen)) {
+		return 0, 0, fmt.Errorf("message record at 0x%x exceeds maximum record size: %d", offset, dataLen)
+	}
+	if dataOffset+int64(dataLen) > p.reader.Size() {
+		err := fmt.Errorf("message record at 0x%x has length %d: %w", offset, dataLen, errRecordTruncated)
+		available := p.reader.Size() - dataOffset
+		if !p.recovering() || available <= 0 {
+			return 0, 0, err
+		}
+		p.noteSalvaged(CategoryMessages, offset, err)
+		return dataOffset, int(available), nil
+	}
+
+	return dataOffset, int(dataLen), nil
+}
+
+// populateHistoryItemMetadata fills the entry fields Burp keeps on the history
+// item record itself rather than in the request and response messages.
+func (p *Parser) populateHistoryItemMetadata(entry *HTTPEntry, itemPtr int64) {
+	if itemPtr <= 0 {
+		return
+	}
+
+	rec, err := p.readTypedRecordHeader(itemPtr)
+	if err != nil || rec.Type != proxyHistoryItemType {
+		return
+	}
+
+	if off, ok := rec.fieldOffset(historyItemFieldTime); ok {
+		if ms, err := p.reader.ReadUint64At(itemPtr + int64(off)); err == nil {
+			entry.Timestamp = timeFromBurpMillis(ms)
+		}
+	}
+
+	// Items without a tool flag, or with one this package does not know,
+	// are left as ToolUnknown rather than guessed at.
+	if off, ok := rec.fieldOffset(historyItemFieldTool); ok {
+		if flag, err := p.reader.ReadUint32At(itemPtr + int64(off)); err == nil {
+			entry.ToolSource = toolTypeFromBurpFlag(flag)
+		}
+	}
+
+	if off, ok := rec.fieldOffset(historyItemFieldComment); ok {
+		if ptr, err := p.reader.ReadUint64At(itemPtr + int64(off)); err == nil && ptr != 0 {
+			if comment, err := p.readUTF16BEStringRecord(int64(ptr)); err == nil {
+				entry.Comment = strings.TrimSpace(comment)
+			}
+		}
+	}
+
+	if off, ok := rec.fieldOffset(historyItemFieldColour); ok {
+		if buf, err := p.reader.ReadAt(itemPtr+int64(off), 1); err == nil && len(buf) == 1 {
+			entry.Highlight = highlightFromBurpByte(buf[0])
+		}
+	}
+
+	// The service Burp actually connected to is authoritative; the Host
+	// header can name a different host or omit a non-default port.
+	if ptr, ok := p.readOptionalPointerField(itemPtr, rec, historyItemFieldService); ok {
+		if svc, err := p.readHTTPService(ptr); err == nil {
+			svc.applyTo(entry)
+		}
+	}
+}
+
+// highlightColours lists the highlight colours Burp offers, in the order of
+// the byte it stores on each history item (0 means no highlight).
+var highlightColours = [...]string{"red", "orange", "yellow", "green", "cyan", "blue", "pink", "magenta", "gray"}
+
+// HighlightColours returns the highlight colours Burp offers, in the order
+// Burp numbers them.
+func HighlightColours() []string {
+	return slices.Clone(highlightColours[:])
+}
+
+func highlightFromBurpByte(b byte) string {
+	if b == 0 || int(b) > len(highlightColours) {
+		return ""
+	}
+	return highlightColours[b-1]
+}
+
+// toolTypeFromBurpFlag maps the tool flags Burp uses in its extender API
+// (IBurpExtenderCallbacks.TOOL_*) to a ToolType, and any other flag to
+// ToolUnknown.
+func toolTypeFromBurpFlag(flag uint32) ToolType {
+	switch flag {
+	case 0x0002:
+		return ToolTarget
+	case 0x0004:
+		return ToolProxy
+	case 0x0008:
+		return ToolSpider
+	case 0x0010:
+		return ToolScanner
+	case 0x0020:
+		return ToolIntruder
+	case 0x0040:
+		return ToolRepeater
+	case 0x0080:
+		return ToolSequencer
+	case 0x0400:
+		return ToolExtension
+	default:
+		return ToolUnknown
+	}
+}
+
+// timeFromBurpMillis converts a Java epoch-millisecond timestamp, returning the
+// zero time for values that are unset or implausibly far in the future.
+func timeFromBurpMillis(ms uint64) time.Time {
+	const maxMillis = uint64(1) << 52
+	if ms == 0 || ms > maxMillis {
+		return time.Time{}
+	}
+	return time.UnixMilli(int64(ms)).UTC()
+}
@@ -0,0 +1,100 @@
+package burp
+
+import (
+	"slices"
+	"testing"
+)
+
+// historyItem writes a Proxy history item for request and response.
+func (b *projectBuilder) historyItem(request, response, comment string) int64 {
+	req := b.message(request)
+	var resp, note int64
+	if response != "" {
+		resp = b.message(response)
+	}
+	if comment != "" {
+		note = b.utf16String(comment)
+	}
+	return b.typed(proxyHistoryItemType,
+		ptrField(historyItemFieldRequest, req),
+		ptrField(historyItemFieldResponse, resp),
+		ptrField(historyItemFieldTime, 1760000000000),
+		u32Field(historyItemFieldTool, 4),
+		ptrField(historyItemFieldComment, note),
+		byteField(historyItemFieldColour, 0),
+	)
+}
+
+func TestProxyHistoryTableWalk(t *testing.T) {
+	b := newProjectBuilder()
+	first := b.historyItem("GET /first HTTP/1.1\r\nHost: a.example\r\n\r\n", "HTTP/1.1 200 OK\r\nContent-Length: 2\r\n\r\nok", "")
+	second := b.historyItem("POST /second HTTP/1.1\r\nHost: b.example\r\n\r\n", "", "note")
+	broken := b.typed(proxyHistoryItemType, ptrField(historyItemFieldRequest, 0x10))
+	// The table, not the file, decides the order.
+	b.list(second, broken, first)
+	path := b.write(t)
+
+	r, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	entries, err := r.HTTPHistory()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, e := range entries {
+		got = append(got, e.Method+" "+e.Host+e.Path)
+	}
+	want := []string{"POST b.example/second", "GET a.example/first"}
+	if !slices.Equal(got, want) {
+		t.Fatalf("history = %q, want %q", got, want)
+	}
+	if entries[0].Comment != "note" {
+		t.Errorf("comment = %q, want %q", entries[0].Comment, "note")
+	}
+	if entries[0].Response != nil || entries[1].StatusCode != 200 {
+		t.Errorf("responses not matched to their requests: %+v", entries)
+	}
+	if n := r.SkippedHistoryItems(); n != 1 {
+		t.Errorf("SkippedHistoryItems = %d, want 1", n)
+	}
+
+	// A reader answering from the index still knows what was skipped.
+	opts := DefaultReaderOptions()
+	opts.IndexPath = IndexPathFor(path)
+	for range 2 {
+		r, err := OpenWithOptions(path, opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := r.HTTPHistorySummary(); err != nil {
+			t.Fatal(err)
+		}
+		if n := r.SkippedHistoryItems(); n != 1 {
+			t.Errorf("SkippedHistoryItems with index = %d, want 1", n)
+		}
+		r.Close()
+	}
+}
+
+func TestToolTypeFromBurpFlag(t *testing.T) {
+	tests := []struct {
+		flag uint32
+		want ToolType
+	}{
+		{0x0004, ToolProxy},
+		{0x0040, ToolRepeater},
+		{0x0400, ToolExtension},
+		{0, ToolUnknown},
+		{0x0100, ToolUnknown},
+		{0x0044, ToolUnknown},
+	}
+	for _, tt := range tests {
+		if got := toolTypeFromBurpFlag(tt.flag); got != tt.want {
+			t.Errorf("toolTypeFromBurpFlag(0x%x) = %v, want %v", tt.flag, got, tt.want)
+		}
+	}
+}
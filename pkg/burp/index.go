@@ -0,0 +1,183 @@
+package burp
+
+import (
+	"encoding/gob"
+	"fmt"
+	"hash/crc32"
+	"os"
+	"path/filepath"
+)
+
+// IndexSuffix is appended to a project file's path to name its index.
+const IndexSuffix = ".bi-index"
+
+// indexFormatVersion must be bumped whenever projectIndex changes or the
+// parser starts producing different entries, so stale indexes are rebuilt.
+const indexFormatVersion = 6
+
+// IndexPathFor returns the default index path for a project file.
+func IndexPathFor(projectPath string) string {
+	return projectPath + IndexSuffix
+}
+
+// projectIndex is the on-disk index sidecar. It records where the history
+// records and tool signatures are in the project, plus each history entry
+// without its request and response, so later runs can skip the full scan.
+// The key fields tie it to one exact version of the project file.
+type projectIndex struct {
+	Version   int
+	FileSize  int64
+	ModTime   int64
+	HeaderSum uint32
+
+	// Layout is the format version whose layout the signatures were
+	// matched with.
+	Layout uint32
+
+	Locations      []HTTPRecordLocation
+	SkippedHistory int
+	Entries        []HTTPEntry
+	Signatures     [numSignatures][]int64
+}
+
+type indexKey struct {
+	fileSize  int64
+	modTime   int64
+	headerSum uint32
+	layout    uint32
+}
+
+func (r *Reader) currentIndexKey() (indexKey, error) {
+	key := indexKey{fileSize: r.parser.reader.Size(), layout: r.parser.layout.version}
+	if r.path != "" {
+		stat, err := os.Stat(r.path)
+		if err != nil {
+			return indexKey{}, err
+		}
+		key.fileSize = stat.Size()
+		key.modTime = stat.ModTime().UnixNano()
+	}
+
+	sum, err := r.parser.headerChecksum()
+	if err != nil {
+		return indexKey{}, err
+	}
+	key.headerSum = sum
+	return key, nil
+}
+
+// loadIndex reads the index at path and seeds the reader's caches from it.
+// It returns false when the index is missing, unreadable or was built for a
+// different version of the project.
+func (r *Reader) loadIndex(path string, key indexKey) bool {
+	f, err := os.Open(path)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+
+	var idx projectIndex
+	if err := gob.NewDecoder(f).Decode(&idx); err != nil {
+		return false
+	}
+	if idx.Version != indexFormatVersion || idx.FileSize != key.fileSize || idx.ModTime != key.modTime || idx.HeaderSum != key.headerSum || idx.Layout != key.layout {
+		return false
+	}
+
+	if idx.Locations == nil {
+		idx.Locations = []HTTPRecordLocation{}
+	}
+	r.cache.locations = idx.Locations
+	r.cache.skippedHistory = idx.SkippedHistory
+	r.cache.summaries = idx.Entries
+	r.metadata.RecordCount = len(idx.Entries)
+	r.parser.setSignatureOffsets(idx.Signatures)
+	return true
+}
+
+// buildIndex parses the whole project and returns its index. The parsed
+// history stays cached on the reader.
+func (r *Reader) buildIndex(key indexKey) (*projectIndex, error) {
+	entries, err := r.HTTPHistory()
+	if err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	idx := &projectIndex{
+		Version:        indexFormatVersion,
+		FileSize:       key.fileSize,
+		ModTime:        key.modTime,
+		HeaderSum:      key.headerSum,
+		Layout:         key.layout,
+		Locations:      r.cache.locations,
+		SkippedHistory: r.cache.skippedHistory,
+		Entries:        make([]HTTPEntry, len(entries)),
+		Signatures:     r.parser.allSignatureOffsets(),
+	}
+	for i, entry := range entries {
+		entry.Request = nil
+		entry.Response = nil
+		idx.Entries[i] = entry
+	}
+
+	return idx, nil
+}
+
+// writeIndexFile writes idx through a temporary file so that a concurrent
+// reader never sees a partial index.
+func writeIndexFile(path string, idx *projectIndex) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("create index: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if err := gob.NewEncoder(tmp).Encode(idx); err != nil {
+		tmp.Close()
+		return fmt.Errorf("write index: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("write index: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("write index: %w", err)
+	}
+	return nil
+}
+
+// openIndex loads the index at path, rebuilding it when it is missing or
+// stale.
+func (r *Reader) openIndex(path string) error {
+	key, err := r.currentIndexKey()
+	if err != nil {
+		return err
+	}
+	if r.loadIndex(path, key) {
+		return nil
+	}
+
+	idx, err := r.buildIndex(key)
+	if err != nil {
+		return err
+	}
+
+	// The index is only a cache. If it cannot be written, for example
+	// because the project sits in a read-only directory, the next run scans
+	// the project again.
+	_ = writeIndexFile(path, idx)
+	return nil
+}
+
+func (p *Parser) headerChecksum() (uint32, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	header, err := p.reader.View(0, HeaderSize)
+	if err != nil {
+		return 0, err
+	}
+	return crc32.ChecksumIEEE(header), nil
+}
@@ -0,0 +1,434 @@
+package burp
+
+import (
+	"context"
+	stdbinary "encoding/binary"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// Each tab of the Intruder tool is a type 0x11 attack record; the base
+// request, payload sets and the rows of the results table hang off it.
+var intruderAttackSignature = []byte{
+	0x00, 0x11, 0x00, 0x07,
+	0x00, 0x00, 0x19,
+	0x01, 0x00, 0x21,
+	0x02, 0x00, 0x29,
+	0x03, 0x00, 0x31,
+	0x04, 0x00, 0x32,
+	0x05, 0x00, 0x3a,
+	0x06, 0x00, 0x42,
+}
+
+const (
+	intruderAttackRecordType     uint16 = 0x0011
+	intruderPayloadSetRecordType uint16 = 0x0012
+	intruderResultRecordType     uint16 = 0x0013
+
+	intruderAttackFieldName        byte = 0x00
+	intruderAttackFieldBaseRequest byte = 0x01
+	intruderAttackFieldService     byte = 0x02
+	intruderAttackFieldType        byte = 0x03
+	intruderAttackFieldPayloadSets byte = 0x04
+	intruderAttackFieldResults     byte = 0x05
+	intruderAttackFieldGrepMatch   byte = 0x06
+
+	intruderPayloadSetFieldType     byte = 0x00
+	intruderPayloadSetFieldPayloads byte = 0x01
+
+	intruderResultFieldIndex     byte = 0x00
+	intruderResultFieldPayloads  byte = 0x01
+	intruderResultFieldStatus    byte = 0x02
+	intruderResultFieldLength    byte = 0x03
+	intruderResultFieldReceived  byte = 0x04
+	intruderResultFieldCompleted byte = 0x05
+	intruderResultFieldGrepMask  byte = 0x06
+	intruderResultFieldError     byte = 0x07
+
+	maxListPointerCount = 10_000_000
+
+	// intruderPositionMarker is the section sign Burp places around payload
+	// positions, stored in the base request as the ISO-8859-1 byte 0xa7 or,
+	// in requests edited as UTF-8, as the encoded rune.
+	intruderPositionMarker = '\u00a7'
+)
+
+type IntruderAttackType int
+
+const (
+	IntruderSniper IntruderAttackType = iota
+	IntruderBatteringRam
+	IntruderPitchfork
+	IntruderClusterBomb
+)
+
+func (t IntruderAttackType) String() string {
+	switch t {
+	case IntruderSniper:
+		return "Sniper"
+	case IntruderBatteringRam:
+		return "Battering ram"
+	case IntruderPitchfork:
+		return "Pitchfork"
+	case IntruderClusterBomb:
+		return "Cluster bomb"
+	default:
+		return "Unknown"
+	}
+}
+
+func (t IntruderAttackType) MarshalText() ([]byte, error) {
+	return []byte(t.String()), nil
+}
+
+type IntruderAttack struct {
+	RecordOffset int64                     `json:"recordOffset"`
+	Name         string                    `json:"name"`
+	AttackType   IntruderAttackType        `json:"attackType"`
+	Host         string                    `json:"host,omitempty"`
+	Port         int                       `json:"port,omitempty"`
+	Protocol     string                    `json:"protocol,omitempty"`
+	BaseRequest  string                    `json:"baseRequest,omitempty"`
+	Positions    []IntruderPayloadPosition `json:"positions,omitempty"`
+	PayloadSets  []IntruderPayloadSet      `json:"payloadSets,omitempty"`
+	GrepMatch    []string                  `json:"grepMatch,omitempty"`
+	Results      []IntruderResult          `json:"results,omitempty"`
+}
+
+// IntruderPayloadPosition is a marked insertion point. Start and End are byte
+// offsets into the base request with the position markers removed.
+type IntruderPayloadPosition struct {
+	Index int    `json:"index"`
+	Start int    `json:"start"`
+	End   int    `json:"end"`
+	Value string `json:"value,omitempty"`
+}
+
+type IntruderPayloadSet struct {
+	Index    int      `json:"index"`
+	Type     string   `json:"type,omitempty"`
+	Payloads []string `json:"payloads,omitempty"`
+}
+
+type IntruderResult struct {
+	Request     int      `json:"request"`
+	Payloads    []string `json:"payloads,omitempty"`
+	StatusCode  int      `json:"statusCode,omitempty"`
+	Length      int64    `json:"length,omitempty"`
+	ReceivedMs  int64    `json:"receivedMs,omitempty"`
+	CompletedMs int64    `json:"completedMs,omitempty"`
+	GrepMatches []string `json:"grepMatches,omitempty"`
+	Error       bool     `json:"error,omitempty"`
+}
+
+// ScanIntruderAttacks returns every saved Intruder attack. When Burp has left
+// several copies of an attack behind, the last readable copy wins. Copies are
+// matched on the tab name together with the attack type, target, base request
+// and payload sets, so different attacks in tabs that share a name are kept
+// apart.
+func (p *Parser) ScanIntruderAttacks() ([]IntruderAttack, error) {
+	return p.ScanIntruderAttacksContext(context.Background())
+}
+
+// ScanIntruderAttacksContext is ScanIntruderAttacks that stops when ctx is
+// cancelled, returning the attacks found so far with ctx.Err().
+func (p *Parser) ScanIntruderAttacksContext(ctx context.Context) ([]IntruderAttack, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	s := p.startScan(ctx, ScanIntruder)
+	defer s.done()
+
+	offsets, err := s.offsets(sigIntruderAttack)
+	if err != nil {
+		return nil, err
+	}
+
+	var attacks []IntruderAttack
+	index := make(map[intruderAttackKey]int)
+
+	for _, abs := range offsets {
+		if err := s.at(abs); err != nil {
+			return attacks, err
+		}
+		attack, err := p.readIntruderAttack(abs)
+		if err != nil {
+			p.noteDamage(CategoryIntruder, abs, err)
+			continue
+		}
+		key := attack.key()
+		if j, ok := index[key]; ok {
+			attacks[j] = attack
+			continue
+		}
+		index[key] = len(attacks)
+		attacks = append(attacks, attack)
+		s.found()
+	}
+
+	return attacks, nil
+}
+
+// intruderAttackKey identifies an attack across the copies Burp writes as its
+// results fill in.
+type intruderAttackKey struct {
+	name        string
+	attackType  IntruderAttackType
+	target      string
+	baseRequest string
+	payloadSets string
+}
+
+func (a *IntruderAttack) key() intruderAttackKey {
+	var sets strings.Builder
+	for _, set := range a.PayloadSets {
+		fmt.Fprintf(&sets, "%s\x00%q\x00", set.Type, set.Payloads)
+	}
+	return intruderAttackKey{
+		name:        a.Name,
+		attackType:  a.AttackType,
+		target:      fmt.Sprintf("%s://%s:%d", a.Protocol, a.Host, a.Port),
+		baseRequest: a.BaseRequest,
+		payloadSets: sets.String(),
+	}
+}
+
+func (p *Parser) readIntruderAttack(offset int64) (IntruderAttack, error) {
+	attack := IntruderAttack{RecordOffset: offset}
+
+	rec, err := p.readTypedRecordHeader(offset)
+	if err != nil {
+		return attack, fmt.Errorf("read intruder attack record at 0x%x: %w", offset, err)
+	}
+
+	if ptr, ok := p.readOptionalPointerField(offset, rec, intruderAttackFieldName); ok {
+		name, err := p.readUTF16BEStringRecord(ptr)
+		if err == nil {
+			attack.Name = strings.TrimSpace(name)
+		}
+	}
+
+	base := p.readMessageField(offset, rec, intruderAttackFieldBaseRequest)
+	if base == nil {
+		return attack, errors.New("intruder attack has no base request")
+	}
+	attack.BaseRequest = string(base.Raw())
+	attack.Positions = parseIntruderPositions(base.Raw())
+
+	if attack.Name == "" {
+		attack.Name = fmt.Sprintf("Attack at 0x%x", offset)
+	}
+
+	if ptr, ok := p.readOptionalPointerField(offset, rec, intruderAttackFieldService); ok {
+		if svc, err := p.readHTTPService(ptr); err == nil {
+			attack.Host = svc.Host
+			attack.Port = svc.Port
+			attack.Protocol = svc.protocol()
+		}
+	}
+
+	if off, ok := rec.fieldOffset(intruderAttackFieldType); ok {
+		if b, err := p.reader.ReadAt(offset+int64(off), 1); err == nil && len(b) == 1 {
+			attack.AttackType = IntruderAttackType(b[0])
+		}
+	}
+
+	if ptr, ok := p.readOptionalPointerField(offset, rec, intruderAttackFieldGrepMatch); ok {
+		for _, strPtr := range p.readListPointers(ptr) {
+			if s, err := p.readUTF16BEStringRecord(strPtr); err == nil {
+				attack.GrepMatch = append(attack.GrepMatch, s)
+			}
+		}
+	}
+
+	if ptr, ok := p.readOptionalPointerField(offset, rec, intruderAttackFieldPayloadSets); ok {
+		for i, setPtr := range p.readListPointers(ptr) {
+			set, err := p.readIntruderPayloadSet(setPtr)
+			if err != nil {
+				continue
+			}
+			set.Index = i + 1
+			attack.PayloadSets = append(attack.PayloadSets, set)
+		}
+	}
+
+	if ptr, ok := p.readOptionalPointerField(offset, rec, intruderAttackFieldResults); ok {
+		for _, resultPtr := range p.readListPointers(ptr) {
+			result, err := p.readIntruderResult(resultPtr, attack.GrepMatch)
+			if err != nil {
+				continue
+			}
+			attack.Results = append(attack.Results, result)
+		}
+	}
+
+	return attack, nil
+}
+
+func (p *Parser) readIntruderPayloadSet(offset int64) (IntruderPayloadSet, error) {
+	var set IntruderPayloadSet
+
+	rec, err := p.readTypedRecordHeader(offset)
+	if err != nil {
+		return set, err
+	}
+	if rec.Type != intruderPayloadSetRecordType {
+		return set, fmt.Errorf("unexpected payload set record type at 0x%x: %d", offset, rec.Type)
+	}
+
+	if ptr, ok := p.readOptionalPointerField(offset, rec, intruderPayloadSetFieldType); ok {
+		set.Type, _ = p.readUTF16BEStringRecord(ptr)
+	}
+	if ptr, ok := p.readOptionalPointerField(offset, rec, intruderPayloadSetFieldPayloads); ok {
+		set.Payloads = p.readUTF8StringList(ptr)
+	}
+
+	return set, nil
+}
+
+func (p *Parser) readIntruderResult(offset int64, grepMatch []string) (IntruderResult, error) {
+	var result IntruderResult
+
+	rec, err := p.readTypedRecordHeader(offset)
+	if err != nil {
+		return result, err
+	}
+	if rec.Type != intruderResultRecordType {
+		return result, fmt.Errorf("unexpected intruder result record type at 0x%x: %d", offset, rec.Type)
+	}
+
+	maxOffset := 0
+	for _, f := range rec.Fields {
+		if int(f.Offset) > maxOffset {
+			maxOffset = int(f.Offset)
+		}
+	}
+	buf, err := p.reader.ReadAt(offset, maxOffset+8)
+	if err != nil || len(buf) < maxOffset+1 {
+		return result, fmt.Errorf("read intruder result at 0x%x: %w", offset, err)
+	}
+
+	u32 := func(id byte) (uint32, bool) {
+		off, ok := rec.fieldOffset(id)
+		if !ok || int(off)+4 > len(buf) {
+			return 0, false
+		}
+		return stdbinary.BigEndian.Uint32(buf[off : off+4]), true
+	}
+	u64 := func(id byte) (uint64, bool) {
+		off, ok := rec.fieldOffset(id)
+		if !ok || int(off)+8 > len(buf) {
+			return 0, false
+		}
+		return stdbinary.BigEndian.Uint64(buf[off : off+8]), true
+	}
+
+	if v, ok := u32(intruderResultFieldIndex); ok {
+		result.Request = int(v)
+	}
+	if v, ok := u32(intruderResultFieldStatus); ok {
+		result.StatusCode = int(v)
+	}
+	if v, ok := u64(intruderResultFieldLength); ok {
+		result.Length = int64(v)
+	}
+	if v, ok := u32(intruderResultFieldReceived); ok {
+		result.ReceivedMs = int64(v)
+	}
+	if v, ok := u32(intruderResultFieldCompleted); ok {
+		result.CompletedMs = int64(v)
+	}
+	if mask, ok := u64(intruderResultFieldGrepMask); ok {
+		for i, s := range grepMatch {
+			if i < 64 && mask&(1<<uint(i)) != 0 {
+				result.GrepMatches = append(result.GrepMatches, s)
+			}
+		}
+	}
+	if off, ok := rec.fieldOffset(intruderResultFieldError); ok && int(off) < len(buf) {
+		result.Error = buf[off] != 0
+	}
+	if ptr, ok := p.readOptionalPointerField(offset, rec, intruderResultFieldPayloads); ok {
+		result.Payloads = p.readUTF8StringList(ptr)
+	}
+
+	return result, nil
+}
+
+// readListPointers returns the live pointers of the list wrapper at offset.
+func (p *Parser) readListPointers(offset int64) []int64 {
+	count, vecPtr, err := p.readListWrapper(offset)
+	if err != nil || count == 0 || count > maxListPointerCount {
+		return nil
+	}
+
+	ptrs, err := p.readPointerVector(vecPtr)
+	if err != nil {
+		return nil
+	}
+	if int(count) < len(ptrs) {
+		ptrs = ptrs[:count]
+	}
+	return ptrs
+}
+
+func (p *Parser) readUTF8StringList(offset int64) []string {
+	var values []string
+	for _, ptr := range p.readListPointers(offset) {
+		s, err := p.readUTF8StringRecord(ptr)
+		if err != nil {
+			continue
+		}
+		values = append(values, s)
+	}
+	return values
+}
+
+// parseIntruderPositions finds the marker-delimited payload positions in an
+// Intruder base request. A trailing unmatched marker is ignored.
+func parseIntruderPositions(raw []byte) []IntruderPayloadPosition {
+	var positions []IntruderPayloadPosition
+
+	stripped := 0
+	start, startAt := -1, 0
+	for i := 0; i < len(raw); {
+		size := intruderMarkerAt(raw[i:])
+		if size == 0 {
+			_, n := utf8.DecodeRune(raw[i:])
+			stripped += n
+			i += n
+			continue
+		}
+		if start == -1 {
+			start, startAt = stripped, i+size
+		} else {
+			positions = append(positions, IntruderPayloadPosition{
+				Index: len(positions) + 1,
+				Start: start,
+				End:   stripped,
+				Value: string(raw[startAt:i]),
+			})
+			start = -1
+		}
+		i += size
+	}
+
+	return positions
+}
+
+// intruderMarkerAt returns the length of the position marker at the start of
+// b, or 0 if there is none. The marker is normally one ISO-8859-1 byte, but a
+// request edited as UTF-8 holds it as two; a 0xa7 byte that ends some other
+// UTF-8 character, such as the "ç" in a form field, is not a marker.
+func intruderMarkerAt(b []byte) int {
+	r, size := utf8.DecodeRune(b)
+	switch {
+	case r == intruderPositionMarker:
+		return size
+	case r == utf8.RuneError && size == 1 && b[0] == intruderPositionMarker:
+		return 1
+	}
+	return 0
+}
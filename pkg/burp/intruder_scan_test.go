@@ -0,0 +1,181 @@
+package burp
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestParseIntruderPositions(t *testing.T) {
+	tests := []struct {
+		name string
+		raw  string
+		want []IntruderPayloadPosition
+	}{
+		{
+			name: "latin-1 markers",
+			raw:  "GET /?id=\xa71\xa7&x=\xa7abc\xa7 HTTP/1.1",
+			want: []IntruderPayloadPosition{
+				{Index: 1, Start: 9, End: 10, Value: "1"},
+				{Index: 2, Start: 13, End: 16, Value: "abc"},
+			},
+		},
+		{
+			name: "utf-8 markers",
+			raw:  "GET /?id=§1§&x=§abc§ HTTP/1.1",
+			want: []IntruderPayloadPosition{
+				{Index: 1, Start: 9, End: 10, Value: "1"},
+				{Index: 2, Start: 13, End: 16, Value: "abc"},
+			},
+		},
+		{
+			// "ç" is 0xc3 0xa7 in UTF-8; its second byte is not a marker.
+			name: "utf-8 text containing 0xa7",
+			raw:  "name=fran\xc3\xa7ois&q=§garçon§",
+			want: []IntruderPayloadPosition{
+				{Index: 1, Start: 17, End: 24, Value: "garçon"},
+			},
+		},
+		{
+			name: "unmatched marker",
+			raw:  "a=\xa7x\xa7&b=\xa7y",
+			want: []IntruderPayloadPosition{
+				{Index: 1, Start: 2, End: 3, Value: "x"},
+			},
+		},
+		{
+			name: "no markers",
+			raw:  "GET / HTTP/1.1",
+		},
+	}
+	for _, tt := range tests {
+		got := parseIntruderPositions([]byte(tt.raw))
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("%s: got %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+}
+
+// intruderAttack writes an Intruder attack record with one payload set and
+// results for the first done payloads, and returns the record's offset.
+func (b *projectBuilder) intruderAttack(name, base string, grep []string, done int, payloads ...string) int64 {
+	var values, results []int64
+	for i, payload := range payloads {
+		values = append(values, b.message(payload))
+		if i >= done {
+			continue
+		}
+		results = append(results, b.typed(intruderResultRecordType,
+			u32Field(intruderResultFieldIndex, uint32(i+1)),
+			ptrField(intruderResultFieldPayloads, b.list(b.message(payload))),
+			u32Field(intruderResultFieldStatus, 200),
+			ptrField(intruderResultFieldLength, int64(100+i)),
+			u32Field(intruderResultFieldReceived, 10),
+			u32Field(intruderResultFieldCompleted, 12),
+			ptrField(intruderResultFieldGrepMask, 1),
+			byteField(intruderResultFieldError, 0),
+		))
+	}
+	set := b.typed(intruderPayloadSetRecordType,
+		ptrField(intruderPayloadSetFieldType, b.utf16String("Simple list")),
+		ptrField(intruderPayloadSetFieldPayloads, b.list(values...)),
+	)
+	var greps []int64
+	for _, g := range grep {
+		greps = append(greps, b.utf16String(g))
+	}
+
+	nameStr := b.utf16String(name)
+	request := b.message(base)
+	setList, resultList, grepList := b.list(set), b.list(results...), b.list(greps...)
+
+	return b.typed(intruderAttackRecordType,
+		ptrField(intruderAttackFieldName, nameStr),
+		ptrField(intruderAttackFieldBaseRequest, request),
+		ptrField(intruderAttackFieldService, 0),
+		byteField(intruderAttackFieldType, byte(IntruderSniper)),
+		ptrField(intruderAttackFieldPayloadSets, setList),
+		ptrField(intruderAttackFieldResults, resultList),
+		ptrField(intruderAttackFieldGrepMatch, grepList),
+	)
+}
+
+func TestScanIntruderAttacks(t *testing.T) {
+	const base = "GET /?id=\xa71\xa7 HTTP/1.1\r\nHost: a\r\n\r\n"
+
+	b := newProjectBuilder()
+	b.intruderAttack("1", base, []string{"error"}, 1, "x", "y")
+	// A newer copy of the same attack, with more results, replaces the first.
+	newer := b.intruderAttack("1", base, []string{"error"}, 2, "x", "y")
+	path := b.write(t)
+
+	r, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	attacks, err := r.IntruderAttacks()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(attacks) != 1 {
+		t.Fatalf("found %d attacks, want 1: %+v", len(attacks), attacks)
+	}
+
+	attack := attacks[0]
+	if attack.RecordOffset != newer || attack.Name != "1" || attack.AttackType != IntruderSniper {
+		t.Errorf("attack = %q (%s) at 0x%x, want the Sniper copy of %q at 0x%x",
+			attack.Name, attack.AttackType, attack.RecordOffset, "1", newer)
+	}
+	if attack.BaseRequest != base || len(attack.Positions) != 1 || attack.Positions[0].Value != "1" {
+		t.Errorf("base request %q with positions %+v, want one position around %q", attack.BaseRequest, attack.Positions, "1")
+	}
+	wantSets := []IntruderPayloadSet{{Index: 1, Type: "Simple list", Payloads: []string{"x", "y"}}}
+	if len(attack.PayloadSets) != 1 || attack.PayloadSets[0].Type != wantSets[0].Type ||
+		!slices.Equal(attack.PayloadSets[0].Payloads, wantSets[0].Payloads) {
+		t.Errorf("payload sets = %+v, want %+v", attack.PayloadSets, wantSets)
+	}
+	if len(attack.Results) != 2 {
+		t.Fatalf("found %d results, want 2", len(attack.Results))
+	}
+	for i, result := range attack.Results {
+		if result.Request != i+1 || result.StatusCode != 200 || result.Length != int64(100+i) ||
+			!slices.Equal(result.Payloads, []string{wantSets[0].Payloads[i]}) ||
+			!slices.Equal(result.GrepMatches, []string{"error"}) {
+			t.Errorf("result %d = %+v", i, result)
+		}
+	}
+}
+
+func TestScanIntruderAttacksKeepsSameNamedAttacksApart(t *testing.T) {
+	const (
+		baseA = "GET /?id=\xa71\xa7 HTTP/1.1\r\nHost: a\r\n\r\n"
+		baseB = "GET /?q=\xa7x\xa7 HTTP/1.1\r\nHost: b\r\n\r\n"
+	)
+
+	b := newProjectBuilder()
+	first := b.intruderAttack("1", baseA, nil, 1, "a")
+	// Another session's attack in a tab of the same name.
+	second := b.intruderAttack("1", baseB, nil, 1, "b")
+	// The same request with other payloads is a different attack too.
+	third := b.intruderAttack("1", baseA, nil, 1, "c")
+	path := b.write(t)
+
+	r, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	attacks, err := r.IntruderAttacks()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []int64
+	for _, attack := range attacks {
+		got = append(got, attack.RecordOffset)
+	}
+	if want := []int64{first, second, third}; !slices.Equal(got, want) {
+		t.Fatalf("attacks read from %v, want %v", got, want)
+	}
+}
@@ -2,6 +2,7 @@ package burp
 
 import (
 	"bytes"
+	"context"
 	stdbinary "encoding/binary"
 	"errors"
 	"fmt"
@@ -62,76 +63,50 @@ type ScannerIssueEvidence struct {
 }
 
 func (p *Parser) ScanScannerIssueMetas(filterSerialNumbers map[uint64]struct{}) ([]ScannerIssueMeta, error) {
+	return p.ScanScannerIssueMetasContext(context.Background(), filterSerialNumbers)
+}
+
+// ScanScannerIssueMetasContext is ScanScannerIssueMetas that stops when ctx
+// is cancelled, returning the issues found so far with ctx.Err().
+func (p *Parser) ScanScannerIssueMetasContext(ctx context.Context, filterSerialNumbers map[uint64]struct{}) ([]ScannerIssueMeta, error) {
 	p.mu.RLock()
 	defer p.mu.RUnlock()
 
-	fileSize := p.reader.Size()
-	bufSize := 1024 * 1024
+	s := p.startScan(ctx, ScanIssues)
+	defer s.done()
 
-	const (
-		issueEntryPtrOffset = 0x2f
-		minEntryRecordLen   = issueEntryPtrOffset + 8
-
-		serialOffset      = 0x3a
-		taskIDOffset      = 0x42
-		p4aOffset         = 0x4a
-		p52Offset         = 0x52
-		sevOffset         = 0x6a
-		confOffset        = 0x6b
-		p73Offset         = 0x73
-		typeOffset        = 0x8b
-		minIssueRecordLen = 0x98
-	)
+	offsets, err := s.offsets(sigScannerIssueIndexEntry)
+	if err != nil {
+		return nil, err
+	}
+
+	ptrOffset := p.layout.issue.indexEntryPtrOffset
+	minEntryRecordLen := ptrOffset + 8
 
 	seenSerials := make(map[uint64]struct{})
 	var metas []ScannerIssueMeta
 
-	offset := int64(HeaderSize)
-	for offset < fileSize {
-		readSize := bufSize
-		remaining := fileSize - offset
-		if remaining < int64(bufSize) {
-			readSize = int(remaining)
+	for _, abs := range offsets {
+		if err := s.at(abs); err != nil {
+			return metas, err
 		}
-
-		data, err := p.reader.ReadAt(offset, readSize)
-		if err != nil || len(data) == 0 {
-			break
+		rec, err := p.reader.View(abs, minEntryRecordLen)
+		if err != nil || len(rec) < minEntryRecordLen {
+			p.noteDamage(CategoryIssues, abs, fmt.Errorf("issue index entry: %w", errRecordTruncated))
+			continue
 		}
 
-		searchIdx := 0
-		for {
-			pos := bytes.Index(data[searchIdx:], scannerIssueIndexEntrySignature)
-			if pos == -1 {
-				break
-			}
-			i := searchIdx + pos
-			abs := offset + int64(i)
-
-			rec, err := p.reader.ReadAt(abs, minEntryRecordLen)
-			if err != nil || len(rec) < minEntryRecordLen {
-				searchIdx = i + 1
-				continue
-			}
-			if !bytes.HasPrefix(rec, scannerIssueIndexEntrySignature) {
-				searchIdx = i + 1
-				continue
-			}
-
-			issuePtr := int64(stdbinary.BigEndian.Uint64(rec[issueEntryPtrOffset : issueEntryPtrOffset+8]))
-			meta, ok := p.readScannerIssueMetaAtOffset(issuePtr, minIssueRecordLen, serialOffset, taskIDOffset, p4aOffset, p52Offset, sevOffset, confOffset, p73Offset, typeOffset, filterSerialNumbers, seenSerials)
-			if ok {
-				metas = append(metas, meta)
-			}
-
-			searchIdx = i + 1
+		// Index entries are what Burp lists, so an issue they point at that
+		// cannot be read is damage.
+		issuePtr := int64(stdbinary.BigEndian.Uint64(rec[ptrOffset : ptrOffset+8]))
+		meta, ok, err := p.readScannerIssueMetaAtOffset(issuePtr, filterSerialNumbers, seenSerials)
+		if err != nil {
+			p.noteDamage(CategoryIssues, abs, err)
+			continue
 		}
-
-		overlap := 1024
-		if len(data) > overlap {
-			offset += int64(len(data) - overlap)
-		} else {
-			offset += int64(len(data))
+		if ok {
+			metas = append(metas, meta)
+			s.found()
 		}
 	}
 
@@ -139,106 +114,85 @@ func (p *Parser) ScanScannerIssueMetas(filterSerialNumbers map[uint64]struct{})
 }
 
 func (p *Parser) ScanScannerIssueMetasRaw(filterSerialNumbers map[uint64]struct{}) ([]ScannerIssueMeta, error) {
+	return p.ScanScannerIssueMetasRawContext(context.Background(), filterSerialNumbers)
+}
+
+// ScanScannerIssueMetasRawContext is ScanScannerIssueMetasRaw that stops
+// when ctx is cancelled, returning the issues found so far with ctx.Err().
+func (p *Parser) ScanScannerIssueMetasRawContext(ctx context.Context, filterSerialNumbers map[uint64]struct{}) ([]ScannerIssueMeta, error) {
 	p.mu.RLock()
 	defer p.mu.RUnlock()
 
-	fileSize := p.reader.Size()
-	bufSize := 1024 * 1024
+	s := p.startScan(ctx, ScanIssues)
+	defer s.done()
 
-	const (
-		serialOffset      = 0x3a
-		taskIDOffset      = 0x42
-		p4aOffset         = 0x4a
-		p52Offset         = 0x52
-		sevOffset         = 0x6a
-		confOffset        = 0x6b
-		p73Offset         = 0x73
-		typeOffset        = 0x8b
-		minIssueRecordLen = 0x98
-	)
+	offsets, err := s.offsets(sigScannerIssueEntry)
+	if err != nil {
+		return nil, err
+	}
 
 	seenSerials := make(map[uint64]struct{})
 	var metas []ScannerIssueMeta
 
-	offset := int64(HeaderSize)
-	for offset < fileSize {
-		readSize := bufSize
-		remaining := fileSize - offset
-		if remaining < int64(bufSize) {
-			readSize = int(remaining)
+	for _, abs := range offsets {
+		if err := s.at(abs); err != nil {
+			return metas, err
 		}
-
-		data, err := p.reader.ReadAt(offset, readSize)
-		if err != nil || len(data) == 0 {
-			break
-		}
-
-		searchIdx := 0
-		for {
-			pos := bytes.Index(data[searchIdx:], scannerIssueEntrySignature)
-			if pos == -1 {
-				break
-			}
-			i := searchIdx + pos
-			abs := offset + int64(i)
-
-			meta, ok := p.readScannerIssueMetaAtOffset(abs, minIssueRecordLen, serialOffset, taskIDOffset, p4aOffset, p52Offset, sevOffset, confOffset, p73Offset, typeOffset, filterSerialNumbers, seenSerials)
-			if ok {
-				metas = append(metas, meta)
-			}
-
-			searchIdx = i + 1
-		}
-
-		overlap := 1024
-		if len(data) > overlap {
-			offset += int64(len(data) - overlap)
-		} else {
-			offset += int64(len(data))
+		meta, ok, _ := p.readScannerIssueMetaAtOffset(abs, filterSerialNumbers, seenSerials)
+		if ok {
+			metas = append(metas, meta)
+			s.found()
 		}
 	}
 
 	return metas, nil
 }
 
-func (p *Parser) readScannerIssueMetaAtOffset(abs int64, minIssueRecordLen int, serialOffset int, taskIDOffset int, p4aOffset int, p52Offset int, sevOffset int, confOffset int, p73Offset int, typeOffset int, filterSerialNumbers map[uint64]struct{}, seenSerials map[uint64]struct{}) (ScannerIssueMeta, bool) {
+// readScannerIssueMetaAtOffset reads the issue record at abs. ok is false for
+// issues left out by filterSerialNumbers or already in seenSerials, and err
+// is set when abs does not hold a readable issue record.
+func (p *Parser) readScannerIssueMetaAtOffset(abs int64, filterSerialNumbers map[uint64]struct{}, seenSerials map[uint64]struct{}) (meta ScannerIssueMeta, ok bool, err error) {
 	if abs <= 0 || abs >= p.reader.Size() {
-		return ScannerIssueMeta{}, false
+		return ScannerIssueMeta{}, false, fmt.Errorf("invalid issue record pointer: 0x%x", abs)
 	}
 
-	rec, err := p.reader.ReadAt(abs, minIssueRecordLen)
-	if err != nil || len(rec) < minIssueRecordLen {
-		return ScannerIssueMeta{}, false
+	layout := p.layout.issue
+	rec, err := p.reader.ReadAt(abs, layout.minRecordLen)
+	if err != nil {
+		return ScannerIssueMeta{}, false, fmt.Errorf("read issue record at 0x%x: %w", abs, err)
 	}
-	if !bytes.HasPrefix(rec, scannerIssueEntrySignature) {
-		return ScannerIssueMeta{}, false
+	if len(rec) < layout.minRecordLen {
+		return ScannerIssueMeta{}, false, fmt.Errorf("issue record at 0x%x: %w", abs, errRecordTruncated)
+	}
+	if !bytes.HasPrefix(rec, p.layout.signatures[sigScannerIssueEntry]) {
+		return ScannerIssueMeta{}, false, fmt.Errorf("no issue record at 0x%x", abs)
 	}
 
-	serial := stdbinary.BigEndian.Uint64(rec[serialOffset : serialOffset+8])
+	serial := stdbinary.BigEndian.Uint64(rec[layout.serialOffset : layout.serialOffset+8])
 	if filterSerialNumbers != nil {
 		if _, ok := filterSerialNumbers[serial]; !ok {
-			return ScannerIssueMeta{}, false
+			return ScannerIssueMeta{}, false, nil
 		}
 	}
 	if _, ok := seenSerials[serial]; ok {
-		return ScannerIssueMeta{}, false
+		return ScannerIssueMeta{}, false, nil
 	}
 	seenSerials[serial] = struct{}{}
 
-	sev, ok := severityFromBurpByte(rec[sevOffset])
+	sev, ok := severityFromBurpByte(rec[layout.severityOffset])
 	if !ok {
-		return ScannerIssueMeta{}, false
+		return ScannerIssueMeta{}, false, fmt.Errorf("issue record at 0x%x has unknown severity %d", abs, rec[layout.severityOffset])
 	}
-	conf, ok := confidenceFromBurpByte(rec[confOffset])
+	conf, ok := confidenceFromBurpByte(rec[layout.confidenceOffset])
 	if !ok {
-		return ScannerIssueMeta{}, false
+		return ScannerIssueMeta{}, false, fmt.Errorf("issue record at 0x%x has unknown confidence %d", abs, rec[layout.confidenceOffset])
 	}
 
-	taskID := stdbinary.BigEndian.Uint64(rec[taskIDOffset : taskIDOffset+8])
-	typeID := stdbinary.BigEndian.Uint32(rec[typeOffset : typeOffset+4])
-	pathPtr := int64(stdbinary.BigEndian.Uint64(rec[p4aOffset : p4aOffset+8]))
-	locationPtr := int64(stdbinary.BigEndian.Uint64(rec[p52Offset : p52Offset+8]))
-	reqRecPtr := int64(stdbinary.BigEndian.Uint64(rec[p73Offset : p73Offset+8]))
+	taskID := stdbinary.BigEndian.Uint64(rec[layout.taskIDOffset : layout.taskIDOffset+8])
+	typeID := stdbinary.BigEndian.Uint32(rec[layout.typeOffset : layout.typeOffset+4])
+	pathPtr := int64(stdbinary.BigEndian.Uint64(rec[layout.pathOffset : layout.pathOffset+8]))
+	locationPtr := int64(stdbinary.BigEndian.Uint64(rec[layout.locationOffset : layout.locationOffset+8]))
+	reqRecPtr := int64(stdbinary.BigEndian.Uint64(rec[layout.requestOffset : layout.requestOffset+8]))
 
 	path, _ := p.readUTF8StringRecord(pathPtr)
 	location, _ := p.readUTF16BEStringRecord(locationPtr)
@@ -274,7 +228,7 @@ func (p *Parser) readScannerIssueMetaAtOffset(abs int64, minIssueRecordLen int,
 		Location:     location,
 		Definition:   def,
 		Evidence:     evidence,
-	}, true
+	}, true, nil
 }
 
 func severityFromBurpByte(b byte) (Severity, bool) {
@@ -342,15 +296,7 @@ func (p *Parser) readUTF16BEStringRecord(pointerOffset int64) (string, error) {
 	var lastErr error
 
 	for _, start := range candidates {
-		s, ok, err := p.tryReadUTF16BEStringRecordV1(start)
-		if ok {
-			return s, nil
-		}
-		if err != nil {
-			lastErr = err
-		}
-
-		s, ok, err = p.tryReadUTF16BEStringRecordV2(start)
+		s, ok, err := p.tryReadUTF16BEStringRecord(p.layout.utf16String, start)
 		if ok {
 			return s, nil
 		}
@@ -365,6 +311,15 @@ func (p *Parser) readUTF16BEStringRecord(pointerOffset int64) (string, error) {
 	return "", lastErr
 }
 
+// tryReadUTF16BEStringRecord reads a UTF-16 string record at start using one
+// of the known string record layouts.
+func (p *Parser) tryReadUTF16BEStringRecord(layout utf16StringLayout, start int64) (string, bool, error) {
+	if layout == utf16StringV2 {
+		return p.tryReadUTF16BEStringRecordV2(start)
+	}
+	return p.tryReadUTF16BEStringRecordV1(start)
+}
+
 func (p *Parser) tryReadUTF16BEStringRecordV1(start int64) (string, bool, error) {
 	const headerLen = 32
 
@@ -682,20 +637,10 @@ func (p *Parser) tryHTTPMessageFromRawRecord(offset int64) *HTTPMessage {
 }
 
 func findHTTPMessageStart(chunk []byte) int {
-	start := -1
+	start := indexHTTPRequestLine(chunk)
 
-	patterns := make([][]byte, 0, len(httpMethodPatterns)+1)
-	patterns = append(patterns, httpMethodPatterns...)
-	patterns = append(patterns, []byte("HTTP/"))
-
-	for _, pat := range patterns {
-		pos := bytes.Index(chunk, pat)
-		if pos == -1 {
-			continue
-		}
-		if start == -1 || pos < start {
-			start = pos
-		}
+	if pos := bytes.Index(chunk, []byte("HTTP/")); pos != -1 && (start == -1 || pos < start) {
+		start = pos
 	}
 
 	return start
@@ -746,15 +691,6 @@ func looksLikeHTTPStartLine(line string) bool {
 	return isHTTPMethodStart(line)
 }
 
-func isHTTPMethodStart(line string) bool {
-	for _, pat := range httpMethodPatterns {
-		if strings.HasPrefix(line, string(pat)) {
-			return true
-		}
-	}
-	return false
-}
-
 func (p *Parser) extractHostAndPathFromRequestRecord(recordOffset int64) (string, string) {
 	if recordOffset <= 0 {
 		return "", ""
@@ -766,16 +702,7 @@ func (p *Parser) extractHostAndPathFromRequestRecord(recordOffset int64) (string
 		return "", ""
 	}
 
-	methodPos := -1
-	for _, pat := range httpMethodPatterns {
-		pos := bytes.Index(chunk, pat)
-		if pos == -1 {
-			continue
-		}
-		if methodPos == -1 || pos < methodPos {
-			methodPos = pos
-		}
-	}
+	methodPos := indexHTTPRequestLine(chunk)
 	if methodPos < 8 {
 		return "", ""
 	}
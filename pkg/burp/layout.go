@@ -0,0 +1,289 @@
+package burp
+
+import (
+	stdbinary "encoding/binary"
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/bmm-sec/burp-insights/internal/binary"
+)
+
+// ErrUnsupportedFormat is returned when a project's header names a file
+// format version that no layout is known for.
+var ErrUnsupportedFormat = errors.New("unsupported project file format version")
+
+// Feature is a kind of project content the parser can extract.
+type Feature string
+
+const (
+	FeatureHeaderMetadata Feature = "header-metadata"
+	FeatureHistory        Feature = "history"
+	FeatureRepeater       Feature = "repeater"
+	FeatureIntruder       Feature = "intruder"
+	FeatureScope          Feature = "scope"
+	FeatureScannerIssues  Feature = "scanner-issues"
+	FeatureScannerTasks   Feature = "scanner-tasks"
+	FeatureUITasks        Feature = "ui-tasks"
+	FeatureWebSockets     Feature = "websockets"
+	FeatureCollaborator   Feature = "collaborator"
+)
+
+// Features lists every feature in display order.
+var Features = []Feature{
+	FeatureHeaderMetadata,
+	FeatureHistory,
+	FeatureRepeater,
+	FeatureIntruder,
+	FeatureScope,
+	FeatureScannerIssues,
+	FeatureScannerTasks,
+	FeatureUITasks,
+	FeatureWebSockets,
+	FeatureCollaborator,
+}
+
+// FeatureSupport says whether a feature can be extracted from projects of one
+// format version.
+type FeatureSupport struct {
+	Feature   Feature `json:"feature"`
+	Supported bool    `json:"supported"`
+}
+
+// formatLayout describes where projects of one file format version keep the
+// records the scanners read: the signatures that find them, fixed offsets
+// and field positions inside records whose descriptor tables are not read.
+type formatLayout struct {
+	version  uint32
+	features map[Feature]bool
+
+	signatures [numSignatures][]byte
+	matcher    *binary.Matcher
+
+	uiTasksListOffset int64
+	repeaterTab       repeaterTabLayout
+	issue             issueRecordLayout
+	scannerTask       scannerTaskLayout
+
+	// utf16String is the layout of the project's UTF-16 string records.
+	utf16String utf16StringLayout
+}
+
+// repeaterTabLayout locates the parts of a Repeater tab name record.
+type repeaterTabLayout struct {
+	nameOffset   int
+	nameChars    int
+	markerOffset int
+	marker       []byte
+}
+
+// contentOffset is where the typed record holding the tab contents starts,
+// relative to the name record.
+func (l repeaterTabLayout) contentOffset() int64 {
+	return int64(l.markerOffset + len(l.marker))
+}
+
+// issueRecordLayout gives the field offsets of Scanner issue records and of
+// the issue index entries that point at them.
+type issueRecordLayout struct {
+	indexEntryPtrOffset int
+
+	serialOffset     int
+	taskIDOffset     int
+	pathOffset       int
+	locationOffset   int
+	severityOffset   int
+	confidenceOffset int
+	requestOffset    int
+	typeOffset       int
+	minRecordLen     int
+}
+
+// scannerTaskLayout gives the field offsets of Scanner task records.
+type scannerTaskLayout struct {
+	signature       []byte
+	hostPtrOffset   int
+	portOffset      int
+	secureOffset    int
+	timestampOffset int
+	minRecordLen    int
+}
+
+// utf16StringLayout is one of the layouts Burp has used for UTF-16 string
+// records. utf16StringV1 records start with a 32-byte header and are the
+// ones found in version 2 projects; utf16StringV2 records carry a shorter
+// 0x1e-byte header and are read from version 1 projects.
+type utf16StringLayout int
+
+const (
+	utf16StringV1 utf16StringLayout = iota
+	utf16StringV2
+)
+
+// headerLen is the length of the record header that precedes the string's
+// characters.
+func (l utf16StringLayout) headerLen() int {
+	if l == utf16StringV2 {
+		return 0x1e
+	}
+	return 32
+}
+
+// newFormatLayout fills in the matcher for a layout's signatures.
+func newFormatLayout(l formatLayout) *formatLayout {
+	l.matcher = binary.NewMatcher(l.signatures[:])
+	return &l
+}
+
+// version2Layout is the layout of projects written by current Burp releases.
+func version2Layout() formatLayout {
+	features := make(map[Feature]bool, len(Features))
+	for _, f := range Features {
+		features[f] = true
+	}
+
+	return formatLayout{
+		version:  2,
+		features: features,
+		signatures: [numSignatures][]byte{
+			sigListWrapper:             listWrapperSignature,
+			sigRepeaterTabName:         repeaterTabNameRecordHeader,
+			sigIntruderAttack:          intruderAttackSignature,
+			sigTargetScope:             targetScopeSignature,
+			sigScannerIssueIndexEntry:  scannerIssueIndexEntrySignature,
+			sigScannerIssueEntry:       scannerIssueEntrySignature,
+			sigHTTPVersion:             httpVersionMarker,
+			sigWebSocketConnection:     webSocketConnectionSignature,
+			sigCollaboratorInteraction: collaboratorInteractionSignature,
+			sigHistoryItem:             historyItemSignature,
+			sigHistoryItemWithService:  historyItemWithServiceSignature,
+		},
+		uiTasksListOffset: 0x1f4,
+		repeaterTab: repeaterTabLayout{
+			nameOffset:   8,
+			nameChars:    32,
+			markerOffset: 0xb8,
+			marker:       repeaterRecordMarker,
+		},
+		issue: issueRecordLayout{
+			indexEntryPtrOffset: 0x2f,
+			serialOffset:        0x3a,
+			taskIDOffset:        0x42,
+			pathOffset:          0x4a,
+			locationOffset:      0x52,
+			severityOffset:      0x6a,
+			confidenceOffset:    0x6b,
+			requestOffset:       0x73,
+			typeOffset:          0x8b,
+			minRecordLen:        0x98,
+		},
+		scannerTask: scannerTaskLayout{
+			signature:       scannerTaskSignature,
+			hostPtrOffset:   0x19,
+			portOffset:      0x21,
+			secureOffset:    0x25,
+			timestampOffset: 0x27,
+			minRecordLen:    0x2f + 8,
+		},
+		utf16String: utf16StringV1,
+	}
+}
+
+// version1Layout is the layout of projects written by older Burp releases.
+// Their records sit where version 2 keeps them, but strings use the shorter
+// utf16StringV2 header.
+func version1Layout() formatLayout {
+	l := version2Layout()
+	l.version = 1
+	l.utf16String = utf16StringV2
+	return l
+}
+
+// version0Layout is the layout of files whose header carries no version at
+// all, from projects that were never saved. Their records are read like
+// version 2, but the header holds no metadata.
+func version0Layout() formatLayout {
+	l := version2Layout()
+	l.version = 0
+	l.features[FeatureHeaderMetadata] = false
+	return l
+}
+
+// formatLayouts maps each known format version to its layout.
+var formatLayouts = map[uint32]*formatLayout{
+	0: newFormatLayout(version0Layout()),
+	1: newFormatLayout(version1Layout()),
+	2: newFormatLayout(version2Layout()),
+}
+
+// SupportedFormatVersions returns the file format versions the parser has
+// layouts for, in ascending order. Version 0 stands for files whose header
+// carries no version.
+func SupportedFormatVersions() []uint32 {
+	versions := make([]uint32, 0, len(formatLayouts))
+	for v := range formatLayouts {
+		versions = append(versions, v)
+	}
+	slices.Sort(versions)
+	return versions
+}
+
+// FormatCompatibility returns which features can be extracted from projects
+// of the given format version.
+func FormatCompatibility(version uint32) ([]FeatureSupport, error) {
+	layout, ok := formatLayouts[version]
+	if !ok {
+		return nil, unsupportedFormatError(version)
+	}
+	return layout.compatibility(), nil
+}
+
+func (l *formatLayout) compatibility() []FeatureSupport {
+	support := make([]FeatureSupport, 0, len(Features))
+	for _, f := range Features {
+		support = append(support, FeatureSupport{Feature: f, Supported: l.features[f]})
+	}
+	return support
+}
+
+func unsupportedFormatError(version uint32) error {
+	return fmt.Errorf("%w %d (known versions: %v)", ErrUnsupportedFormat, version, SupportedFormatVersions())
+}
+
+// readFormatVersion returns the format version stored in the project header.
+func (p *Parser) readFormatVersion() (uint32, error) {
+	header, err := p.reader.ReadAt(headerFormatVersionOffset, 4)
+	if err != nil || len(header) < 4 {
+		return 0, fmt.Errorf("read format version: %w", err)
+	}
+	return stdbinary.BigEndian.Uint32(header), nil
+}
+
+// selectLayout picks the layout for the project's format version, or for
+// assumed when it is non-zero. When recovering, an unknown or unreadable
+// version falls back to the newest layout.
+func (p *Parser) selectLayout(assumed uint32) error {
+	version := assumed
+	if version == 0 {
+		v, err := p.readFormatVersion()
+		if err != nil {
+			if !p.recovering() {
+				return err
+			}
+			p.noteDamage(CategoryHeader, headerFormatVersionOffset, err)
+		}
+		version = v
+	}
+
+	layout, ok := formatLayouts[version]
+	if !ok {
+		err := unsupportedFormatError(version)
+		if !p.recovering() {
+			return err
+		}
+		p.noteDamage(CategoryHeader, headerFormatVersionOffset, err)
+		layout = formatLayouts[MaxSupportedFormatVersion]
+	}
+	p.layout = layout
+	return nil
+}
@@ -0,0 +1,114 @@
+package burp
+
+import (
+	stdbinary "encoding/binary"
+	"errors"
+	"testing"
+	"unicode/utf16"
+)
+
+// utf16StringV2Record returns a string record with the short header used by
+// version 1 projects.
+func utf16StringV2Record(s string) []byte {
+	units := utf16.Encode([]rune(s))
+	rec := stdbinary.BigEndian.AppendUint32(nil, 2)
+	rec = append(rec, 0x00, 0x00, 0x0a, 0x01, 0x00, 0x12)
+	rec = append(rec, make([]byte, 8)...)
+	rec = stdbinary.BigEndian.AppendUint64(rec, uint64(8+2*len(units)))
+	rec = stdbinary.BigEndian.AppendUint32(rec, uint32(len(units)))
+	for _, u := range units {
+		rec = stdbinary.BigEndian.AppendUint16(rec, u)
+	}
+	return rec
+}
+
+// versionedProject writes a project whose header names version, holding one
+// string record of each layout, and returns its path and the two offsets.
+func versionedProject(t *testing.T, version uint32) (path string, v1, v2 int64) {
+	t.Helper()
+
+	b := newProjectBuilder()
+	stdbinary.BigEndian.PutUint32(b.buf[headerFormatVersionOffset:], version)
+	v1 = b.utf16String("long header")
+	v2 = b.raw(utf16StringV2Record("short header"))
+	return b.write(t), v1, v2
+}
+
+func TestOpenRejectsUnknownFormatVersion(t *testing.T) {
+	path, _, _ := versionedProject(t, 7)
+
+	if _, err := Open(path); !errors.Is(err, ErrUnsupportedFormat) {
+		t.Fatalf("Open = %v, want ErrUnsupportedFormat", err)
+	}
+
+	for name, opts := range map[string]ReaderOptions{
+		"assumed":    {AssumeFormatVersion: 2},
+		"recovering": {Recover: true},
+	} {
+		r, err := OpenWithOptions(path, opts)
+		if err != nil {
+			t.Fatalf("%s: OpenWithOptions: %v", name, err)
+		}
+		if r.FormatVersion() != 2 || r.Metadata().FormatVersion != 7 {
+			t.Errorf("%s: read version %d as %d, want 7 read as 2", name, r.Metadata().FormatVersion, r.FormatVersion())
+		}
+		r.Close()
+	}
+
+	if _, err := OpenWithOptions(path, ReaderOptions{AssumeFormatVersion: 9}); !errors.Is(err, ErrUnsupportedFormat) {
+		t.Fatalf("assuming version 9 = %v, want ErrUnsupportedFormat", err)
+	}
+}
+
+func TestLayoutReadsOneStringEncoding(t *testing.T) {
+	tests := []struct {
+		version   uint32
+		want      string
+		wantShort bool
+	}{
+		{version: 0, want: "long header"},
+		{version: 1, want: "short header", wantShort: true},
+		{version: 2, want: "long header"},
+	}
+	for _, tt := range tests {
+		path, v1, v2 := versionedProject(t, tt.version)
+		r, err := Open(path)
+		if err != nil {
+			t.Fatalf("version %d: %v", tt.version, err)
+		}
+
+		read, other := v1, v2
+		if tt.wantShort {
+			read, other = v2, v1
+		}
+		if s, err := r.parser.readUTF16BEStringRecord(read); err != nil || s != tt.want {
+			t.Errorf("version %d: string = %q, %v; want %q", tt.version, s, err, tt.want)
+		}
+		if s, err := r.parser.readUTF16BEStringRecord(other); err == nil {
+			t.Errorf("version %d read %q from the other string layout", tt.version, s)
+		}
+		r.Close()
+	}
+}
+
+func TestReaderCompatibility(t *testing.T) {
+	for _, version := range SupportedFormatVersions() {
+		path, _, _ := versionedProject(t, version)
+		r, err := Open(path)
+		if err != nil {
+			t.Fatalf("version %d: %v", version, err)
+		}
+
+		support := r.Compatibility()
+		if len(support) != len(Features) {
+			t.Fatalf("version %d: %d features reported, want %d", version, len(support), len(Features))
+		}
+		for i, s := range support {
+			want := version != 0 || s.Feature != FeatureHeaderMetadata
+			if s.Feature != Features[i] || s.Supported != want || r.Supports(s.Feature) != want {
+				t.Errorf("version %d: %s supported = %v, want %v", version, s.Feature, s.Supported, want)
+			}
+		}
+		r.Close()
+	}
+}
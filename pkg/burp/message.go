@@ -0,0 +1,193 @@
+package burp
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+)
+
+// NewHTTPMessage parses data as an HTTP request or response. The message
+// keeps a reference to data.
+func NewHTTPMessage(data []byte) *HTTPMessage {
+	return parseHTTPMessage(data)
+}
+
+// Raw returns the message exactly as stored by Burp. Messages from the HTTP
+// history are read from the project file on first use, so Raw needs the
+// Reader to still be open and returns nil once it has been closed or when
+// the read fails; use ReadRaw to see why.
+func (m *HTTPMessage) Raw() []byte {
+	raw, _ := m.ReadRaw()
+	return raw
+}
+
+// ReadRaw is like Raw but returns the error from reading the project file.
+func (m *HTTPMessage) ReadRaw() ([]byte, error) {
+	if m == nil {
+		return nil, nil
+	}
+	if m.source != nil {
+		return m.source.load()
+	}
+	return m.raw, nil
+}
+
+// Body returns the message body as stored by Burp, or nil when the message
+// has no body. See Raw for when the bytes are available.
+func (m *HTTPMessage) Body() []byte {
+	body, _ := m.ReadBody()
+	return body
+}
+
+// ReadBody is like Body but returns the error from reading the project file.
+func (m *HTTPMessage) ReadBody() ([]byte, error) {
+	if m == nil || m.bodyStart >= m.size {
+		return nil, nil
+	}
+	raw, err := m.ReadRaw()
+	if err != nil {
+		return nil, err
+	}
+	if m.bodyStart >= len(raw) {
+		return nil, nil
+	}
+	return raw[m.bodyStart:], nil
+}
+
+// Size returns the length of the raw message without loading it.
+func (m *HTTPMessage) Size() int {
+	if m == nil {
+		return 0
+	}
+	return m.size
+}
+
+// BodySize returns the length of the stored body without loading it.
+func (m *HTTPMessage) BodySize() int {
+	if m == nil || m.bodyStart >= m.size {
+		return 0
+	}
+	return m.size - m.bodyStart
+}
+
+// RawReader returns a reader over the raw message. Messages from the HTTP
+// history read straight from the project file, so large messages can be
+// streamed without loading them into memory; once the Reader is closed, reads
+// fail with an error.
+func (m *HTTPMessage) RawReader() *io.SectionReader {
+	return m.sectionReader(0, int64(m.Size()))
+}
+
+// BodyReader returns a reader over the stored body. See RawReader.
+func (m *HTTPMessage) BodyReader() *io.SectionReader {
+	if m.BodySize() == 0 {
+		return m.sectionReader(0, 0)
+	}
+	return m.sectionReader(int64(m.bodyStart), int64(m.BodySize()))
+}
+
+func (m *HTTPMessage) sectionReader(off, n int64) *io.SectionReader {
+	switch {
+	case m == nil:
+		return io.NewSectionReader(bytes.NewReader(nil), 0, 0)
+	case m.source != nil:
+		return m.source.sectionReader(off, n)
+	default:
+		return io.NewSectionReader(bytes.NewReader(m.raw), off, n)
+	}
+}
+
+// hasBodyCodings reports whether the body carries a transfer or content
+// coding that DecodedBody would undo.
+func (m *HTTPMessage) hasBodyCodings() bool {
+	return len(headerCodings(m.Headers, "Transfer-Encoding")) > 0 || len(headerCodings(m.Headers, "Content-Encoding")) > 0
+}
+
+// messageSource locates a message in the project file so that its bytes can
+// be loaded on demand instead of being held by every parsed entry.
+type messageSource struct {
+	parser *Parser
+	offset int64
+	length int
+}
+
+func (s *messageSource) load() ([]byte, error) {
+	if data, ok := s.parser.bodies.get(s.key(false)); ok {
+		return data, nil
+	}
+
+	data, err := s.parser.readMessageBytes(s.offset, s.length)
+	if err != nil {
+		return nil, fmt.Errorf("read message at 0x%x: %w", s.offset, err)
+	}
+
+	s.parser.bodies.add(s.key(false), data)
+	return data, nil
+}
+
+// decodedBody returns the decoded body of m, the message at s, keeping it in
+// the body cache next to the stored bytes.
+func (s *messageSource) decodedBody(m *HTTPMessage) []byte {
+	if data, ok := s.parser.bodies.get(s.key(true)); ok {
+		return data
+	}
+
+	body, _ := decodeBody(m.Headers, m.Body())
+	s.parser.bodies.add(s.key(true), body)
+	return body
+}
+
+func (s *messageSource) key(decoded bool) bodyCacheKey {
+	return bodyCacheKey{offset: s.offset, decoded: decoded}
+}
+
+// sectionReader serves from the body cache when the message is already
+// loaded and from the project file otherwise.
+func (s *messageSource) sectionReader(off, n int64) *io.SectionReader {
+	if data, ok := s.parser.bodies.get(s.key(false)); ok {
+		return io.NewSectionReader(bytes.NewReader(data), off, n)
+	}
+	return io.NewSectionReader(lockedReaderAt{s.parser}, s.offset+off, n)
+}
+
+// lockedReaderAt reads the project file under the parser's read lock, so a
+// read racing Close returns an error instead of touching the unmapped file.
+type lockedReaderAt struct {
+	p *Parser
+}
+
+func (l lockedReaderAt) ReadAt(b []byte, off int64) (int, error) {
+	l.p.mu.RLock()
+	defer l.p.mu.RUnlock()
+
+	return l.p.reader.SectionReader(off, int64(len(b))).ReadAt(b, 0)
+}
+
+// readMessageBytes returns a copy of the message bytes at offset.
+func (p *Parser) readMessageBytes(offset int64, length int) ([]byte, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	data, err := p.reader.ReadAt(offset, length)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < length {
+		return nil, ErrParseError
+	}
+	return data, nil
+}
+
+// loadHTTPMessage parses the headers of the message at offset and returns a
+// message whose bytes are loaded lazily. The caller must hold p.mu.
+func (p *Parser) loadHTTPMessage(offset int64, length int) (*HTTPMessage, error) {
+	data, err := p.reader.View(offset, length)
+	if err != nil {
+		return nil, err
+	}
+
+	msg := parseHTTPMessage(data)
+	msg.raw = nil
+	msg.source = &messageSource{parser: p, offset: offset, length: len(data)}
+	return msg, nil
+}
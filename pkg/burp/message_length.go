@@ -0,0 +1,298 @@
+package burp
+
+import (
+	"bytes"
+	stdbinary "encoding/binary"
+	"strconv"
+	"strings"
+)
+
+// Messages found by pattern scanning have no history item recording their
+// length. When the bytes in front of a message form a message record header
+// the length is taken from there; otherwise it is worked out from the
+// message framing, reading as far into the file as the message goes.
+const (
+	messageHeadWindow    = 64 * 1024
+	maxMessageHeadLen    = 4 * 1024 * 1024
+	responseSearchWindow = 64 * 1024
+	messageScanChunk     = 1024 * 1024
+	maxChunkSizeLineLen  = 1024
+)
+
+// messageLengthAt returns the length of the HTTP message starting at offset,
+// or 0 when no message can be delimited there. For a response, method is the
+// method of the request it answers.
+func (p *Parser) messageLengthAt(offset int64, response bool, method string) int {
+	if n, ok := p.recordLengthBefore(offset); ok {
+		return n
+	}
+
+	headers, bodyStart, ok := p.readMessageHead(offset)
+	if !ok {
+		if response {
+			return 0
+		}
+		// A request cut off inside its headers runs up to the next message.
+		return p.lengthUntilNextMessage(offset, 0)
+	}
+
+	// Responses to HEAD, and 1xx, 204 and 304 responses, never have a
+	// body, whatever their Content-Length says.
+	if response && responseHasNoBody(method, headers) {
+		return bodyStart
+	}
+
+	remaining := p.reader.Size() - offset
+	if cl, ok := headerContentLength(headers); ok {
+		// Burp keeps truncated captures, so the body may stop short.
+		return int(min(int64(bodyStart)+cl, remaining))
+	}
+
+	if isChunked(headers) {
+		if n, ok := p.chunkedBodyLength(offset + int64(bodyStart)); ok {
+			return bodyStart + n
+		}
+	}
+
+	// Requests without framing have no body; responses without framing run
+	// until the connection closed, i.e. up to the next message.
+	if !response {
+		return bodyStart
+	}
+	return p.lengthUntilNextMessage(offset, bodyStart)
+}
+
+// recordLengthBefore returns the data length from a message record header
+// directly in front of offset.
+func (p *Parser) recordLengthBefore(offset int64) (int, bool) {
+	start := offset - messageRecordHeaderLen
+	if start < int64(HeaderSize) {
+		return 0, false
+	}
+
+	hdr, err := p.reader.View(start, messageRecordHeaderLen)
+	if err != nil || len(hdr) < messageRecordHeaderLen || !hasMessageRecordHeader(hdr, 1) {
+		return 0, false
+	}
+
+	dataLen := int64(stdbinary.BigEndian.Uint32(hdr[4:8]))
+	if offset+dataLen > p.reader.Size() || !p.withinRecordLimit(dataLen) {
+		return 0, false
+	}
+	return int(dataLen), true
+}
+
+// readMessageHead returns the header section of the message at offset and
+// the offset of its body, reading more of the file until the blank line
+// ending the headers is found.
+func (p *Parser) readMessageHead(offset int64) (string, int, bool) {
+	for window := messageHeadWindow; ; window *= 2 {
+		data, err := p.reader.View(offset, window)
+		if err != nil {
+			return "", 0, false
+		}
+
+		if idx := bytes.Index(data, []byte("\r\n\r\n")); idx >= 0 {
+			return string(data[:idx]), idx + 4, true
+		}
+		if idx := bytes.Index(data, []byte("\n\n")); idx >= 0 {
+			return string(data[:idx]), idx + 2, true
+		}
+
+		if len(data) < window || window >= maxMessageHeadLen {
+			return "", 0, false
+		}
+	}
+}
+
+// responseHasNoBody reports whether a response with the given header
+// section, answering a request with method, has no body (RFC 9110, 6.4.1).
+func responseHasNoBody(method string, headers string) bool {
+	if strings.EqualFold(method, "HEAD") {
+		return true
+	}
+
+	statusLine, _, _ := strings.Cut(headers, "\n")
+	fields := strings.Fields(statusLine)
+	if len(fields) < 2 {
+		return false
+	}
+	status, err := strconv.Atoi(fields[1])
+	if err != nil {
+		return false
+	}
+	return (status >= 100 && status < 200) || status == 204 || status == 304
+}
+
+func headerContentLength(headers string) (int64, bool) {
+	for _, line := range strings.Split(headers, "\n") {
+		name, value, ok := strings.Cut(strings.TrimSpace(line), ":")
+		if !ok || !strings.EqualFold(strings.TrimSpace(name), "Content-Length") {
+			continue
+		}
+		n, err := strconv.ParseInt(strings.TrimSpace(value), 10, 64)
+		if err != nil || n < 0 {
+			return 0, false
+		}
+		return n, true
+	}
+	return 0, false
+}
+
+func isChunked(headers string) bool {
+	for _, line := range strings.Split(headers, "\n") {
+		name, value, ok := strings.Cut(strings.TrimSpace(line), ":")
+		if ok && strings.EqualFold(strings.TrimSpace(name), "Transfer-Encoding") &&
+			strings.Contains(strings.ToLower(value), "chunked") {
+			return true
+		}
+	}
+	return false
+}
+
+// chunkedBodyLength returns the length of the chunked body starting at
+// offset, including the final chunk and trailers. It fails when the body
+// runs past the end of the file or is malformed.
+func (p *Parser) chunkedBodyLength(offset int64) (int, bool) {
+	pos := offset
+	for {
+		line, next, ok := p.readLineAt(pos)
+		if !ok {
+			return 0, false
+		}
+		sizeField, _, _ := strings.Cut(line, ";")
+		size, err := strconv.ParseInt(strings.TrimSpace(sizeField), 16, 64)
+		if err != nil || size < 0 {
+			return 0, false
+		}
+		pos = next
+
+		if size == 0 {
+			break
+		}
+
+		pos += size
+		if pos > p.reader.Size() {
+			return 0, false
+		}
+		// Skip the CRLF after the chunk data.
+		if _, next, ok := p.readLineAt(pos); ok {
+			pos = next
+		} else {
+			return 0, false
+		}
+	}
+
+	// Trailers end at an empty line; a capture may stop right after the
+	// last chunk.
+	for pos < p.reader.Size() {
+		line, next, ok := p.readLineAt(pos)
+		if !ok {
+			break
+		}
+		pos = next
+		if line == "" {
+			break
+		}
+	}
+
+	return int(pos - offset), true
+}
+
+// readLineAt returns the line at offset without its terminator and the
+// offset of the next line.
+func (p *Parser) readLineAt(offset int64) (string, int64, bool) {
+	data, err := p.reader.View(offset, maxChunkSizeLineLen)
+	if err != nil {
+		return "", 0, false
+	}
+	idx := bytes.IndexByte(data, '\n')
+	if idx == -1 {
+		return "", 0, false
+	}
+	return strings.TrimRight(string(data[:idx]), "\r"), offset + int64(idx) + 1, true
+}
+
+// lengthUntilNextMessage returns the distance from offset to the next HTTP
+// request or response found at or after offset+from, or to the end of the
+// file. A from of 0 skips the message's own start line.
+func (p *Parser) lengthUntilNextMessage(offset int64, from int) int {
+	searchFrom := offset + int64(from)
+	if from == 0 {
+		_, next, ok := p.readLineAt(offset)
+		if !ok {
+			return int(p.reader.Size() - offset)
+		}
+		searchFrom = next
+	}
+
+	if next, ok := p.nextMessageStart(searchFrom); ok {
+		return int(next - offset)
+	}
+	return int(p.reader.Size() - offset)
+}
+
+// nextMessageStart finds the first request line or status line at or after
+// offset, reading the file in chunks.
+func (p *Parser) nextMessageStart(offset int64) (int64, bool) {
+	// Chunks overlap by enough for a request line straddling the boundary;
+	// matches in the overlap are left for the next chunk so that an earlier
+	// request line whose marker lies past the end is not missed.
+	const overlap = maxRequestTargetLen + maxHTTPMethodLen + 64
+
+	fileSize := p.reader.Size()
+	for start := offset; start < fileSize; {
+		data, err := p.reader.View(start, messageScanChunk)
+		if err != nil || len(data) == 0 {
+			break
+		}
+
+		last := start+int64(len(data)) >= fileSize
+		limit := len(data)
+		if !last {
+			limit = len(data) - overlap
+		}
+
+		next := indexHTTPRequestLine(data)
+		if pos := indexHTTPResponseStart(data); pos >= 0 && (next == -1 || pos < next) {
+			next = pos
+		}
+		if next >= 0 && next < limit {
+			return start + int64(next), true
+		}
+
+		if last {
+			break
+		}
+		start += int64(limit)
+	}
+	return 0, false
+}
+
+// findResponseAfter looks for the response that follows a request ending at
+// offset. The search stops at the next request line so that a request with
+// no response is not paired with a later one.
+func (p *Parser) findResponseAfter(offset int64) (int64, bool) {
+	if offset >= p.reader.Size() {
+		return 0, false
+	}
+	data, err := p.reader.View(offset, responseSearchWindow)
+	if err != nil {
+		return 0, false
+	}
+	if idx := indexHTTPRequestLine(data); idx >= 0 {
+		data = data[:idx]
+	}
+
+	idx := indexHTTPResponseStart(data)
+	if idx < 0 {
+		return 0, false
+	}
+	return offset + int64(idx), true
+}
+
+// withinRecordLimit reports whether a message of length n is allowed by the
+// reader's MaxRecordSize.
+func (p *Parser) withinRecordLimit(n int64) bool {
+	return p.maxRecordSize <= 0 || n <= p.maxRecordSize
+}
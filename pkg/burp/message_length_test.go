@@ -0,0 +1,60 @@
+package burp
+
+import (
+	"testing"
+)
+
+func TestPatternScanResponsesWithoutBody(t *testing.T) {
+	// Each response declares a Content-Length but, answering HEAD or being
+	// a 204, has no body; the following request must not be swallowed.
+	path := writeTestProject(t, []byte(
+		"HEAD / HTTP/1.1\r\nHost: a\r\n\r\nHTTP/1.1 200 OK\r\nContent-Length: 100\r\n\r\n\x00\x00\x00\x00"+
+			"GET /next HTTP/1.1\r\nHost: a\r\n\r\nHTTP/1.1 204 No Content\r\nContent-Length: 5\r\n\r\n\x00\x00\x00\x00"+
+			"GET /third HTTP/1.1\r\nHost: a\r\n\r\nHTTP/1.1 200 OK\r\nContent-Length: 2\r\n\r\nok"))
+
+	p, err := NewParser(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+
+	locs, err := p.ScanHTTPRecords()
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantResponseLengths := []int{
+		len("HTTP/1.1 200 OK\r\nContent-Length: 100\r\n\r\n"),
+		len("HTTP/1.1 204 No Content\r\nContent-Length: 5\r\n\r\n"),
+		len("HTTP/1.1 200 OK\r\nContent-Length: 2\r\n\r\nok"),
+	}
+	if len(locs) != len(wantResponseLengths) {
+		t.Fatalf("found %d records, want %d: %+v", len(locs), len(wantResponseLengths), locs)
+	}
+	for i, loc := range locs {
+		if loc.ResponseLength != wantResponseLengths[i] {
+			t.Errorf("record %d: response length %d, want %d", i, loc.ResponseLength, wantResponseLengths[i])
+		}
+	}
+}
+
+func TestResponseHasNoBody(t *testing.T) {
+	tests := []struct {
+		method  string
+		headers string
+		want    bool
+	}{
+		{"GET", "HTTP/1.1 200 OK\r\nContent-Length: 3", false},
+		{"HEAD", "HTTP/1.1 200 OK\r\nContent-Length: 3", true},
+		{"head", "HTTP/1.1 200 OK", true},
+		{"GET", "HTTP/1.1 204 No Content", true},
+		{"GET", "HTTP/1.1 304 Not Modified", true},
+		{"GET", "HTTP/1.1 101 Switching Protocols", true},
+		{"GET", "HTTP/2 404", false},
+		{"GET", "garbage", false},
+	}
+	for _, tt := range tests {
+		if got := responseHasNoBody(tt.method, tt.headers); got != tt.want {
+			t.Errorf("responseHasNoBody(%q, %q) = %v, want %v", tt.method, tt.headers, got, tt.want)
+		}
+	}
+}
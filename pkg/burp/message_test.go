@@ -0,0 +1,85 @@
+package burp
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"testing"
+)
+
+func TestReadRawAfterClose(t *testing.T) {
+	request := "GET / HTTP/1.1\r\nHost: a\r\n\r\n"
+	path := writeTestProject(t, []byte(request+"HTTP/1.1 200 OK\r\nContent-Length: 2\r\n\r\nok"))
+
+	r, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	entries, err := r.HTTPHistory()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Fatalf("found %d entries, want 1", len(entries))
+	}
+	msg := entries[0].Request
+	raw, err := msg.ReadRaw()
+	if err != nil || string(raw) != request {
+		t.Fatalf("ReadRaw = %q, %v; want %q", raw, err, request)
+	}
+
+	// A fresh message has not been loaded, so reading it must reach the
+	// closed file.
+	entries, err = r.HTTPHistory()
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.Close()
+	if _, err := entries[0].Response.ReadBody(); err == nil {
+		t.Fatal("ReadBody after Close returned no error")
+	}
+	if body := entries[0].Response.Body(); body != nil {
+		t.Fatalf("Body after Close = %q, want nil", body)
+	}
+}
+
+func TestDecodedBodyIsKept(t *testing.T) {
+	var gz bytes.Buffer
+	w := gzip.NewWriter(&gz)
+	w.Write([]byte("decoded text"))
+	w.Close()
+	response := fmt.Sprintf("HTTP/1.1 200 OK\r\nContent-Encoding: gzip\r\nContent-Length: %d\r\n\r\n", gz.Len()) + gz.String()
+
+	// In memory, the decoded body is kept on the message.
+	msg := NewHTTPMessage([]byte(response))
+	first, second := msg.DecodedBody(), msg.DecodedBody()
+	if string(first) != "decoded text" {
+		t.Fatalf("DecodedBody = %q, want %q", first, "decoded text")
+	}
+	if &first[0] != &second[0] {
+		t.Fatal("DecodedBody decoded the body again")
+	}
+
+	// Loaded from the project, it is kept in the body cache, so it
+	// outlives access to the file.
+	path := writeTestProject(t, []byte("GET / HTTP/1.1\r\nHost: a\r\n\r\n"+response))
+	r, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	entries, err := r.HTTPHistory()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Fatalf("found %d entries, want 1", len(entries))
+	}
+	msg = entries[0].Response
+	if body := msg.DecodedBody(); string(body) != "decoded text" {
+		t.Fatalf("DecodedBody = %q, want %q", body, "decoded text")
+	}
+	r.Close()
+	if body := msg.DecodedBody(); string(body) != "decoded text" {
+		t.Fatalf("DecodedBody after Close = %q, want the cached body", body)
+	}
+}
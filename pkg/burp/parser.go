@@ -2,9 +2,14 @@ package burp
 
 import (
 	"bytes"
+	"context"
 	stdbinary "encoding/binary"
 	"errors"
+	"io"
+	"os"
 	"regexp"
+	"slices"
+	"strconv"
 	"strings"
 	"sync"
 	"unicode/utf16"
@@ -19,42 +24,84 @@ const (
 )
 
 var (
-	ErrInvalidFile     = errors.New("invalid burp project file")
-	ErrInvalidMagic    = errors.New("invalid magic bytes")
-	ErrParseError      = errors.New("parse error")
-	httpMethodPatterns = [][]byte{
-		[]byte("GET "),
-		[]byte("POST "),
-		[]byte("PUT "),
-		[]byte("DELETE "),
-		[]byte("PATCH "),
-		[]byte("HEAD "),
-		[]byte("OPTIONS "),
-	}
-	httpResponsePattern = []byte("HTTP/1.")
-	hostHeaderPattern   = regexp.MustCompile(`(?i)^Host:\s*(.+)$`)
-	contentTypePattern  = regexp.MustCompile(`(?i)^Content-Type:\s*(.+)$`)
+	ErrInvalidFile       = errors.New("invalid burp project file")
+	ErrInvalidMagic      = errors.New("invalid magic bytes")
+	ErrParseError        = errors.New("parse error")
+	httpResponsePatterns = [][]byte{
+		[]byte("HTTP/1."),
+		[]byte("HTTP/2"),
+	}
+	hostHeaderPattern  = regexp.MustCompile(`(?i)^Host:\s*(.+)$`)
+	contentTypePattern = regexp.MustCompile(`(?i)^Content-Type:\s*(.+)$`)
 )
 
 type Parser struct {
-	reader *binary.Reader
-	path   string
-	mu     sync.RWMutex
+	reader     *binary.Reader
+	spill      *os.File // temporary file an archived project was unpacked to
+	mu         sync.RWMutex
+	signatures signatureIndex
+	bodies     *bodyCache
+
+	// maxRecordSize rejects message records claiming to be larger; zero
+	// means no limit.
+	maxRecordSize int64
+
+	// damage is non-nil in recovery mode, where damaged records are logged
+	// and skipped or salvaged instead of failing the read.
+	damage *damageLog
+
+	// layout holds the offsets and signatures for the project's format
+	// version.
+	layout *formatLayout
+
+	// scanProgress, when set, receives progress reports from the scans.
+	scanProgress ScanProgressFunc
 }
 
+// NewParser opens the project at path. Gzip, zip and tar archives holding a
+// project are unpacked to a temporary file first.
 func NewParser(path string) (*Parser, error) {
-	reader, err := binary.NewReader(path)
+	src, err := openProjectFile(path, "")
 	if err != nil {
 		return nil, err
 	}
+	return newParser(src, false, 0)
+}
+
+// NewParserReaderAt parses the project held in the first size bytes of src,
+// which may also be a gzip, zip or tar archive. Closing the parser does not
+// close src.
+func NewParserReaderAt(src io.ReaderAt, size int64) (*Parser, error) {
+	s, err := unpackProject(src, size, "")
+	if err != nil {
+		return nil, err
+	}
+	return newParser(s, false, 0)
+}
 
+// newParser reads src and picks the layout for its format version, or for
+// formatVersion when it is non-zero. When recovering, a bad header is logged
+// rather than rejected, so that whatever records survive can still be read.
+// src is closed when an error is returned.
+func newParser(src projectSource, recovering bool, formatVersion uint32) (*Parser, error) {
 	p := &Parser{
-		reader: reader,
-		path:   path,
+		reader: src.reader,
+		spill:  src.spill,
+	}
+	if recovering {
+		p.damage = newDamageLog()
 	}
 
 	if err := p.validateHeader(); err != nil {
-		reader.Close()
+		if !recovering {
+			src.close()
+			return nil, err
+		}
+		p.noteDamage(CategoryHeader, 0, err)
+	}
+
+	if err := p.selectLayout(formatVersion); err != nil {
+		src.close()
 		return nil, err
 	}
 
@@ -64,7 +111,7 @@ func NewParser(path string) (*Parser, error) {
 func (p *Parser) Close() error {
 	p.mu.Lock()
 	defer p.mu.Unlock()
-	return p.reader.Close()
+	return projectSource{reader: p.reader, spill: p.spill}.close()
 }
 
 func (p *Parser) validateHeader() error {
@@ -84,75 +131,203 @@ func (p *Parser) GetMetadata() (*ProjectMetadata, error) {
 	p.mu.RLock()
 	defer p.mu.RUnlock()
 
-	return &ProjectMetadata{
+	meta := &ProjectMetadata{
 		FileSize: p.reader.Size(),
-	}, nil
+	}
+	if err := p.readProjectHeader(meta); err != nil {
+		if !p.recovering() {
+			return nil, err
+		}
+		p.noteDamage(CategoryHeader, 0, err)
+	}
+
+	return meta, nil
 }
 
 type HTTPRecordLocation struct {
+	ItemOffset     int64
 	RequestOffset  int64
 	RequestLength  int
 	ResponseOffset int64
 	ResponseLength int
 }
 
+// recordOffset returns the offset of the history item, or of the request
+// for records found by pattern scanning.
+func (loc HTTPRecordLocation) recordOffset() int64 {
+	if loc.ItemOffset > 0 {
+		return loc.ItemOffset
+	}
+	return loc.RequestOffset
+}
+
+// ScanHTTPRecords returns the Proxy history items in the order Burp shows
+// them. Projects without a recognisable history table fall back to scanning
+// for HTTP request lines.
+//
+// When recovering from a table that is missing or damaged, history items the
+// table does not refer to are added at the end: a project cut short may have
+// lost the newest copy of the table, leaving an older one that misses the
+// latest items. Healthy projects also hold unreferenced items, superseded or
+// deleted ones (see CarveDeleted), so they are left alone while the table
+// reads cleanly.
+//
+// The signature pass and the scans for history items and request lines
+// report their progress as ScanSignatures and ScanHistory.
 func (p *Parser) ScanHTTPRecords() ([]HTTPRecordLocation, error) {
+	return p.ScanHTTPRecordsContext(context.Background())
+}
+
+// ScanHTTPRecordsContext is ScanHTTPRecords that stops when ctx is
+// cancelled, returning the locations found so far with ctx.Err().
+func (p *Parser) ScanHTTPRecordsContext(ctx context.Context) ([]HTTPRecordLocation, error) {
+	locations, _, err := p.scanHTTPRecords(ctx)
+	return locations, err
+}
+
+// scanHTTPRecords is ScanHTTPRecordsContext that also returns how many of the
+// items listed in the history table could not be read.
+func (p *Parser) scanHTTPRecords(ctx context.Context) (locations []HTTPRecordLocation, skipped int, err error) {
 	p.mu.RLock()
 	defer p.mu.RUnlock()
 
-	fileSize := p.reader.Size()
-	bufSize := 1024 * 1024
-
-	var allOffsets []int64
-	offset := int64(HeaderSize)
+	s := p.startScan(ctx, ScanHistory)
+	defer s.done()
 
-	for offset < fileSize {
-		readSize := bufSize
-		remaining := fileSize - offset
-		if remaining < int64(bufSize) {
-			readSize = int(remaining)
+	tableOffset, err := p.findProxyHistoryTable(s)
+	if err != nil {
+		return nil, 0, err
+	}
+	if tableOffset < 0 {
+		// A project cut short before Burp last wrote the table still has
+		// its items, which carry more than the bare requests. Requests
+		// whose item was lost are kept as well.
+		if p.recovering() {
+			items, err := p.unreferencedHistoryItems(s, nil)
+			if err != nil {
+				return items, 0, err
+			}
+			if len(items) > 0 {
+				p.noteSalvaged(CategoryHistory, items[0].ItemOffset, errors.New("no history table; history items found by scanning"))
+				locations, err = p.appendUnlistedRequests(s, items)
+				return locations, 0, err
+			}
 		}
+		locations, err = p.scanHTTPRecordsByPattern(s)
+		return locations, 0, err
+	}
 
-		data, err := p.reader.ReadAt(offset, readSize)
-		if err != nil || len(data) == 0 {
-			break
+	locations, skipped, err = p.readProxyHistoryTable(s, tableOffset)
+	if err != nil || !p.recovering() || skipped == 0 {
+		return locations, skipped, err
+	}
+
+	orphans, err := p.unreferencedHistoryItems(s, locations)
+	return append(locations, orphans...), skipped, err
+}
+
+// historyItemCandidates returns the offsets of everything that looks like
+// the start of a history item, in file order.
+func (p *Parser) historyItemCandidates(s *scanRun) ([]int64, error) {
+	items, err := s.offsets(sigHistoryItem)
+	if err != nil {
+		return nil, err
+	}
+	withService, err := s.offsets(sigHistoryItemWithService)
+	if err != nil {
+		return nil, err
+	}
+
+	candidates := append(slices.Clone(items), withService...)
+	slices.Sort(candidates)
+	return candidates, nil
+}
+
+// unreferencedHistoryItems returns the readable history items, in file
+// order, that are missing from locations. They are not noted as damage:
+// whatever damaged the table is already recorded.
+func (p *Parser) unreferencedHistoryItems(s *scanRun, locations []HTTPRecordLocation) ([]HTTPRecordLocation, error) {
+	referenced := make(map[int64]bool, len(locations))
+	for _, loc := range locations {
+		referenced[loc.ItemOffset] = true
+	}
+
+	candidates, err := p.historyItemCandidates(s)
+	if err != nil {
+		return nil, err
+	}
+
+	var orphans []HTTPRecordLocation
+	for _, off := range candidates {
+		if err := s.at(off); err != nil {
+			return orphans, err
+		}
+		if referenced[off] {
+			continue
+		}
+		loc, err := p.readProxyHistoryItem(off)
+		if err != nil {
+			p.noteDamage(CategoryHistory, off, err)
+			continue
 		}
+		orphans = append(orphans, loc)
+		s.found()
+	}
+	return orphans, nil
+}
 
-		for _, pattern := range httpMethodPatterns {
-			idx := 0
-			for {
-				pos := bytes.Index(data[idx:], pattern)
-				if pos == -1 {
-					break
-				}
-				actualPos := idx + pos
-				if actualPos > 0 && data[actualPos-1] != '\n' && data[actualPos-1] != 0 && actualPos >= 8 {
-					allOffsets = append(allOffsets, offset+int64(actualPos))
-				} else if actualPos == 0 {
-					allOffsets = append(allOffsets, offset+int64(actualPos))
-				}
-				idx = actualPos + len(pattern)
-				if idx >= len(data) {
-					break
-				}
-			}
+// appendUnlistedRequests adds the requests found by scanning for request
+// lines that none of locations refers to.
+func (p *Parser) appendUnlistedRequests(s *scanRun, locations []HTTPRecordLocation) ([]HTTPRecordLocation, error) {
+	listed := make(map[int64]bool, len(locations))
+	for _, loc := range locations {
+		listed[loc.RequestOffset] = true
+	}
+
+	found, err := p.scanHTTPRecordsByPattern(s)
+	for _, loc := range found {
+		if listed[loc.RequestOffset] {
+			continue
 		}
+		p.noteSalvaged(CategoryHistory, loc.RequestOffset, errors.New("request without a history item"))
+		locations = append(locations, loc)
+	}
+	return locations, err
+}
 
-		overlap := 20
-		if len(data) > overlap {
-			offset += int64(len(data) - overlap)
-		} else {
-			offset += int64(len(data))
+func (p *Parser) scanHTTPRecordsByPattern(s *scanRun) ([]HTTPRecordLocation, error) {
+	markers, err := s.offsets(sigHTTPVersion)
+	if err != nil {
+		return nil, err
+	}
+
+	var allOffsets []int64
+	for _, marker := range markers {
+		if start, ok := p.requestLineStartAt(marker); ok {
+			allOffsets = append(allOffsets, start)
 		}
 	}
 
 	allOffsets = deduplicateOffsets(allOffsets)
 
 	var locations []HTTPRecordLocation
+	var coveredUntil int64
 	for _, off := range allOffsets {
+		if err := s.at(off); err != nil {
+			return locations, err
+		}
+
+		// A request line inside an earlier message belongs to that message,
+		// e.g. a request echoed in a response body.
+		if off < coveredUntil {
+			continue
+		}
+
 		loc := p.parseRecordAtOffset(off)
 		if loc.RequestLength > 0 {
 			locations = append(locations, loc)
+			coveredUntil = max(loc.RequestOffset+int64(loc.RequestLength), loc.ResponseOffset+int64(loc.ResponseLength))
+			s.found()
 		}
 	}
 
@@ -178,130 +353,39 @@ func deduplicateOffsets(offsets []int64) []int64 {
 func (p *Parser) parseRecordAtOffset(offset int64) HTTPRecordLocation {
 	loc := HTTPRecordLocation{RequestOffset: offset}
 
-	maxReadSize := 128 * 1024
-	data, err := p.reader.ReadAt(offset, maxReadSize)
-	if err != nil || len(data) < 10 {
+	reqLen := p.messageLengthAt(offset, false, "")
+	if reqLen <= 0 {
 		return loc
 	}
+	loc.RequestLength = reqLen
 
-	reqEnd := findHTTPRequestEnd(data)
-	if reqEnd <= 0 || reqEnd > len(data) {
-		headerEnd := bytes.Index(data, []byte("\r\n\r\n"))
-		if headerEnd > 0 {
-			reqEnd = headerEnd + 4
-		} else {
-			reqEnd = 1024
-			if reqEnd > len(data) {
-				reqEnd = len(data)
-			}
-		}
-	}
-	loc.RequestLength = reqEnd
-
-	searchStart := reqEnd
-	if searchStart > len(data)-10 {
+	respOffset, ok := p.findResponseAfter(offset + int64(reqLen))
+	if !ok {
 		return loc
 	}
-
-	respIdx := bytes.Index(data[searchStart:], httpResponsePattern)
-	if respIdx >= 0 {
-		loc.ResponseOffset = offset + int64(searchStart) + int64(respIdx)
-		respData := data[searchStart+respIdx:]
-
-		respEnd := findHTTPResponseEnd(respData)
-		if respEnd > 0 {
-			loc.ResponseLength = respEnd
-		}
-	}
-
-	return loc
-}
-
-func findHTTPRequestEnd(data []byte) int {
-	idx := bytes.Index(data, []byte("\r\n\r\n"))
-	if idx == -1 {
-		idx = bytes.Index(data, []byte("\n\n"))
-		if idx != -1 {
-			return idx + 2
-		}
-		return -1
-	}
-
-	headerEnd := idx + 4
-	headers := string(data[:idx])
-	contentLength := extractContentLength(headers)
-
-	if contentLength > 0 && contentLength < 100000 {
-		bodyEnd := headerEnd + contentLength
-		if bodyEnd <= len(data) {
-			return bodyEnd
-		}
-	}
-
-	return headerEnd
-}
-
-func findHTTPResponseEnd(data []byte) int {
-	idx := bytes.Index(data, []byte("\r\n\r\n"))
-	if idx == -1 {
-		idx = bytes.Index(data, []byte("\n\n"))
-		if idx != -1 {
-			return idx + 2
-		}
-		return -1
-	}
-
-	headerEnd := idx + 4
-	headers := string(data[:idx])
-	contentLength := extractContentLength(headers)
-
-	if contentLength > 0 && contentLength < 500000 {
-		bodyEnd := headerEnd + contentLength
-		if bodyEnd <= len(data) {
-			return bodyEnd
-		}
-		return len(data)
-	}
-
-	searchData := data[headerEnd:]
-	nextHTTP := -1
-	for _, pattern := range httpMethodPatterns {
-		pos := bytes.Index(searchData, pattern)
-		if pos > 0 && (nextHTTP == -1 || pos < nextHTTP) {
-			nextHTTP = pos
-		}
-	}
-
-	httpPos := bytes.Index(searchData, []byte("HTTP/1."))
-	if httpPos > 0 && (nextHTTP == -1 || httpPos < nextHTTP) {
-		nextHTTP = httpPos
+	var method string
+	if line, _, ok := p.readLineAt(offset); ok {
+		method, _, _ = strings.Cut(line, " ")
 	}
-
-	if nextHTTP > 0 {
-		return headerEnd + nextHTTP
+	if respLen := p.messageLengthAt(respOffset, true, method); respLen > 0 {
+		loc.ResponseOffset = respOffset
+		loc.ResponseLength = respLen
 	}
 
-	maxBody := 50000
-	if len(searchData) < maxBody {
-		return len(data)
-	}
-	return headerEnd + maxBody
+	return loc
 }
 
-func extractContentLength(headers string) int {
-	lines := strings.Split(headers, "\n")
-	for _, line := range lines {
-		line = strings.TrimSpace(line)
-		if strings.HasPrefix(strings.ToLower(line), "content-length:") {
-			parts := strings.SplitN(line, ":", 2)
-			if len(parts) == 2 {
-				var length int
-				_, _ = parseIntFromString(strings.TrimSpace(parts[1]), &length)
-				return length
-			}
+// indexHTTPResponseStart returns the index of the first HTTP/1.x or HTTP/2
+// status line in data, or -1.
+func indexHTTPResponseStart(data []byte) int {
+	first := -1
+	for _, pattern := range httpResponsePatterns {
+		pos := bytes.Index(data, pattern)
+		if pos >= 0 && (first == -1 || pos < first) {
+			first = pos
 		}
 	}
-	return 0
+	return first
 }
 
 func parseIntFromString(s string, result *int) (bool, error) {
@@ -326,36 +410,52 @@ func (p *Parser) ParseHTTPEntry(loc HTTPRecordLocation) (*HTTPEntry, error) {
 	}
 
 	if loc.RequestLength > 0 {
-		reqData, err := p.reader.ReadAt(loc.RequestOffset, loc.RequestLength)
+		reqMsg, err := p.loadHTTPMessage(loc.RequestOffset, loc.RequestLength)
 		if err != nil {
 			return nil, err
 		}
 
-		entry.Request = parseHTTPMessage(reqData)
-		parseRequestLine(entry, entry.Request.StartLine)
-		extractHostFromHeaders(entry, entry.Request.Headers)
+		setEntryRequest(entry, reqMsg)
 	}
 
+	// A response that cannot be read leaves the entry without one; the
+	// item's metadata and URL are still filled in below.
 	if loc.ResponseLength > 0 && loc.ResponseOffset > 0 {
-		respData, err := p.reader.ReadAt(loc.ResponseOffset, loc.ResponseLength)
-		if err != nil {
-			return entry, nil
+		respMsg, err := p.loadHTTPMessage(loc.ResponseOffset, loc.ResponseLength)
+		if err == nil {
+			setEntryResponse(entry, respMsg)
+		} else {
+			p.noteDamage(CategoryMessages, loc.ResponseOffset, err)
 		}
-
-		entry.Response = parseHTTPMessage(respData)
-		parseStatusLine(entry, entry.Response.StartLine)
-		extractContentTypeFromHeaders(entry, entry.Response.Headers)
 	}
 
+	p.populateHistoryItemMetadata(entry, loc.ItemOffset)
 	buildURL(entry)
 
 	return entry, nil
 }
 
+func setEntryRequest(entry *HTTPEntry, msg *HTTPMessage) {
+	entry.Request = msg
+	parseRequestLine(entry, msg.StartLine)
+	extractHostFromHeaders(entry, msg.Headers)
+}
+
+func setEntryResponse(entry *HTTPEntry, msg *HTTPMessage) {
+	entry.Response = msg
+	parseStatusLine(entry, msg.StartLine)
+	extractContentTypeFromHeaders(entry, msg.Headers)
+	// HTTP/2 responses often omit Content-Length.
+	if entry.ContentLength == 0 && msg.BodySize() > 0 && headerValue(msg.Headers, "Content-Length") == "" {
+		entry.ContentLength = int64(msg.BodySize())
+	}
+}
+
 func parseHTTPMessage(data []byte) *HTTPMessage {
 	msg := &HTTPMessage{
-		Raw:     data,
 		Headers: make(map[string][]string),
+		raw:     data,
+		size:    len(data),
 	}
 
 	headerEnd := bytes.Index(data, []byte("\r\n\r\n"))
@@ -373,37 +473,106 @@ func parseHTTPMessage(data []byte) *HTTPMessage {
 	headerSection := string(data[:headerEnd])
 	lines := strings.Split(headerSection, "\n")
 
+	first := 1
 	if len(lines) > 0 {
 		msg.StartLine = strings.TrimSpace(lines[0])
+		// HTTP/2 messages can be stored as pseudo-headers without a start line.
+		if strings.HasPrefix(msg.StartLine, ":") {
+			msg.StartLine = ""
+			first = 0
+		}
 	}
 
-	for i := 1; i < len(lines); i++ {
+	for i := first; i < len(lines); i++ {
 		line := strings.TrimSpace(lines[i])
 		if line == "" {
 			continue
 		}
 
-		idx := strings.Index(line, ":")
-		if idx > 0 {
-			key := strings.TrimSpace(line[:idx])
-			value := strings.TrimSpace(line[idx+1:])
+		if key, value, ok := splitHeaderLine(line); ok {
 			msg.Headers[key] = append(msg.Headers[key], value)
 		}
 	}
 
-	if bodyStart < len(data) {
-		msg.Body = data[bodyStart:]
+	if msg.StartLine == "" {
+		msg.StartLine = http2StartLine(msg.Headers)
 	}
 
+	msg.bodyStart = bodyStart
+
 	return msg
 }
 
+// splitHeaderLine splits a header line into name and value. HTTP/2
+// pseudo-headers such as ":authority" keep their leading colon.
+func splitHeaderLine(line string) (string, string, bool) {
+	start := 0
+	if strings.HasPrefix(line, ":") {
+		start = 1
+	}
+	idx := strings.Index(line[start:], ":")
+	if idx <= 0 {
+		return "", "", false
+	}
+	idx += start
+	return strings.TrimSpace(line[:idx]), strings.TrimSpace(line[idx+1:]), true
+}
+
+// http2StartLine rebuilds a request or status line from HTTP/2
+// pseudo-headers, returning "" when there are none.
+func http2StartLine(headers map[string][]string) string {
+	if status := headerValue(headers, ":status"); status != "" {
+		return "HTTP/2 " + status
+	}
+	if method := headerValue(headers, ":method"); method != "" {
+		path := headerValue(headers, ":path")
+		if path == "" {
+			path = "/"
+		}
+		return method + " " + path + " HTTP/2"
+	}
+	return ""
+}
+
+func headerValue(headers map[string][]string, name string) string {
+	for key, values := range headers {
+		if strings.EqualFold(key, name) && len(values) > 0 {
+			return values[0]
+		}
+	}
+	return ""
+}
+
+// parseRequestLine reads the method, target and version from a request line.
+// Absolute-form targets, as sent to an upstream proxy, and CONNECT
+// authority-form targets also set the entry's scheme, host and port.
 func parseRequestLine(entry *HTTPEntry, line string) {
 	parts := strings.Fields(line)
 	if len(parts) >= 2 {
 		entry.Method = parts[0]
 		fullPath := parts[1]
 
+		if idx := strings.Index(fullPath, "://"); idx > 0 && !strings.HasPrefix(fullPath, "/") {
+			scheme := strings.ToLower(fullPath[:idx])
+			if scheme == "http" || scheme == "https" {
+				entry.Scheme = scheme
+			}
+			rest := fullPath[idx+3:]
+			authority := rest
+			fullPath = "/"
+			if end := strings.IndexAny(rest, "/?"); end >= 0 {
+				authority = rest[:end]
+				fullPath = rest[end:]
+			}
+			entry.Host, entry.Port = splitHostPort(authority, defaultPortForScheme(scheme))
+		} else if strings.EqualFold(entry.Method, "CONNECT") {
+			entry.Host, entry.Port = splitHostPort(fullPath, 443)
+			fullPath = ""
+		}
+		if strings.HasPrefix(fullPath, "?") {
+			fullPath = "/" + fullPath
+		}
+
 		if idx := strings.Index(fullPath, "?"); idx >= 0 {
 			entry.Path = fullPath[:idx]
 			entry.QueryString = fullPath[idx+1:]
@@ -425,22 +594,63 @@ func parseStatusLine(entry *HTTPEntry, line string) {
 	}
 }
 
+// extractHostFromHeaders sets the entry's host and port from the Host header,
+// falling back to the HTTP/2 :authority pseudo-header. A host already taken
+// from an absolute-form request line is kept.
 func extractHostFromHeaders(entry *HTTPEntry, headers map[string][]string) {
-	for key, values := range headers {
-		if strings.EqualFold(key, "Host") && len(values) > 0 {
-			hostPort := values[0]
-			if idx := strings.Index(hostPort, ":"); idx >= 0 {
-				entry.Host = hostPort[:idx]
-				var port int
-				parseIntFromString(hostPort[idx+1:], &port)
-				entry.Port = port
-			} else {
-				entry.Host = hostPort
-				entry.Port = 80
-			}
-			break
+	if scheme := strings.ToLower(headerValue(headers, ":scheme")); entry.Scheme == "" && (scheme == "http" || scheme == "https") {
+		entry.Scheme = scheme
+	}
+	if entry.Host != "" {
+		return
+	}
+
+	hostPort := headerValue(headers, "Host")
+	if hostPort == "" {
+		hostPort = headerValue(headers, ":authority")
+	}
+	if hostPort == "" {
+		return
+	}
+
+	entry.Host, entry.Port = splitHostPort(hostPort, defaultPortForScheme(entry.Scheme))
+}
+
+// splitHostPort splits a Host header or URL authority into host and port,
+// accepting bracketed and bare IPv6 literals. The brackets are removed from
+// the returned host.
+func splitHostPort(hostPort string, defaultPort int) (string, int) {
+	hostPort = strings.TrimSpace(hostPort)
+	if at := strings.LastIndex(hostPort, "@"); at >= 0 {
+		hostPort = hostPort[at+1:]
+	}
+
+	host, portStr := hostPort, ""
+	if strings.HasPrefix(hostPort, "[") {
+		end := strings.Index(hostPort, "]")
+		if end == -1 {
+			return strings.TrimPrefix(hostPort, "["), defaultPort
 		}
+		host = hostPort[1:end]
+		portStr = strings.TrimPrefix(hostPort[end+1:], ":")
+	} else if strings.Count(hostPort, ":") == 1 {
+		idx := strings.Index(hostPort, ":")
+		host, portStr = hostPort[:idx], hostPort[idx+1:]
+	}
+
+	port, err := strconv.Atoi(portStr)
+	if err != nil || port <= 0 || port > 0xffff {
+		port = defaultPort
+	}
+	return host, port
+}
+
+// urlHost returns host in the form used in URLs, bracketing IPv6 literals.
+func urlHost(host string) string {
+	if strings.Contains(host, ":") && !strings.HasPrefix(host, "[") {
+		return "[" + host + "]"
 	}
+	return host
 }
 
 func extractContentTypeFromHeaders(entry *HTTPEntry, headers map[string][]string) {
@@ -464,27 +674,47 @@ func extractContentTypeFromHeaders(entry *HTTPEntry, headers map[string][]string
 	}
 }
 
-func buildURL(entry *HTTPEntry) {
-	if entry.Host == "" {
-		return
+// Origin returns the scheme, host and port that start the entry's URL, such
+// as "https://example.com" or "http://example.com:8080", leaving out the
+// scheme's default port. It is empty when the entry has no host.
+func (e *HTTPEntry) Origin() string {
+	if e.Host == "" {
+		return ""
 	}
 
-	scheme := "http"
-	if entry.Port == 443 {
-		scheme = "https"
+	scheme := e.Scheme
+	if scheme == "" {
+		scheme = "http"
+		if e.Port == 443 {
+			scheme = "https"
+		}
+	}
+
+	host := urlHost(e.Host)
+	if e.Port == 0 || (scheme == "http" && e.Port == 80) || (scheme == "https" && e.Port == 443) {
+		return scheme + "://" + host
 	}
+	return scheme + "://" + host + ":" + intToString(e.Port)
+}
 
-	if entry.Port == 80 || entry.Port == 443 {
-		entry.URL = scheme + "://" + entry.Host + entry.Path
-	} else {
-		entry.URL = scheme + "://" + entry.Host + ":" + intToString(entry.Port) + entry.Path
+func buildURL(entry *HTTPEntry) {
+	if entry.Host == "" {
+		return
 	}
 
+	entry.URL = entry.Origin() + entry.Path
 	if entry.QueryString != "" {
 		entry.URL += "?" + entry.QueryString
 	}
 }
 
+func defaultPortForScheme(scheme string) int {
+	if scheme == "https" {
+		return 443
+	}
+	return 80
+}
+
 func intToString(n int) string {
 	if n == 0 {
 		return "0"
@@ -499,69 +729,71 @@ func intToString(n int) string {
 }
 
 func (p *Parser) ScanRepeaterTabNames() ([]string, error) {
+	return p.ScanRepeaterTabNamesContext(context.Background())
+}
+
+// ScanRepeaterTabNamesContext is ScanRepeaterTabNames that stops when ctx is
+// cancelled, returning the names found so far with ctx.Err().
+func (p *Parser) ScanRepeaterTabNamesContext(ctx context.Context) ([]string, error) {
 	p.mu.RLock()
 	defer p.mu.RUnlock()
 
-	fileSize := p.reader.Size()
-	bufSize := 1024 * 1024
+	s := p.startScan(ctx, ScanRepeater)
+	defer s.done()
 
 	var tabNames []string
 	seenNames := make(map[string]struct{})
 
-	stringRecordHeader := []byte{0x00, 0x00, 0x00, 0x48, 0x00, 0x00, 0x00, 0x20}
-	repeaterRecordMarker := []byte{0x00, 0x02, 0x01, 0x00, 0x0a, 0x02, 0x00, 0x12, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x58}
+	err := p.scanRepeaterTabRecords(s, func(_ int64, name string) {
+		if _, ok := seenNames[name]; !ok {
+			seenNames[name] = struct{}{}
+			tabNames = append(tabNames, name)
+			s.found()
+		}
+	})
 
-	const (
-		stringChars       = 32
-		stringOffset      = 8
-		markerOffset      = 0xb8
-		minRequiredLength = markerOffset + 16
-	)
+	return tabNames, err
+}
 
-	offset := int64(HeaderSize)
+var (
+	repeaterTabNameRecordHeader = []byte{0x00, 0x00, 0x00, 0x48, 0x00, 0x00, 0x00, 0x20}
+	repeaterRecordMarker        = []byte{0x00, 0x02, 0x01, 0x00, 0x0a, 0x02, 0x00, 0x12, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x58}
+)
 
-	for offset < fileSize {
-		readSize := bufSize
-		remaining := fileSize - offset
-		if remaining < int64(bufSize) {
-			readSize = int(remaining)
-		}
+// scanRepeaterTabRecords calls fn with the offset and name of every Repeater
+// tab record in file order. Burp appends a fresh copy of a tab when it
+// changes, so the same name may be reported more than once. It stops with
+// the context's error when s is cancelled.
+func (p *Parser) scanRepeaterTabRecords(s *scanRun, fn func(offset int64, name string)) error {
+	offsets, err := s.offsets(sigRepeaterTabName)
+	if err != nil {
+		return err
+	}
 
-		data, err := p.reader.ReadAt(offset, readSize)
-		if err != nil || len(data) == 0 {
-			break
-		}
+	layout := p.layout.repeaterTab
+	minRequiredLength := int(layout.contentOffset())
 
-		searchIdx := 0
-		for {
-			pos := bytes.Index(data[searchIdx:], stringRecordHeader)
-			if pos == -1 {
-				break
-			}
-			i := searchIdx + pos
-
-			if i+minRequiredLength <= len(data) && matchesPattern(data[i+markerOffset:], repeaterRecordMarker) {
-				name := extractFixedUTF16BEString(data[i+stringOffset:], stringChars)
-				if name != "" {
-					if _, ok := seenNames[name]; !ok {
-						seenNames[name] = struct{}{}
-						tabNames = append(tabNames, name)
-					}
-				}
-			}
+	lastReported := int64(-1)
+	for _, abs := range offsets {
+		if err := s.at(abs); err != nil {
+			return err
+		}
+		if abs <= lastReported {
+			continue
+		}
 
-			searchIdx = i + 1
+		data, err := p.reader.View(abs, minRequiredLength)
+		if err != nil || len(data) < minRequiredLength || !matchesPattern(data[layout.markerOffset:], layout.marker) {
+			continue
 		}
 
-		overlap := 512
-		if len(data) > overlap {
-			offset += int64(len(data) - overlap)
-		} else {
-			offset += int64(len(data))
+		name := extractFixedUTF16BEString(data[layout.nameOffset:], layout.nameChars)
+		if name != "" {
+			lastReported = abs
+			fn(abs, name)
 		}
 	}
-
-	return tabNames, nil
+	return nil
 }
 
 func matchesPattern(data []byte, pattern []byte) bool {
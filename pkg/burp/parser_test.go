@@ -0,0 +1,147 @@
+package burp
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseHTTPEntryKeepsMetadataWithoutResponse(t *testing.T) {
+	b := newProjectBuilder()
+	b.list(b.historyItem("GET /a HTTP/1.1\r\nHost: a.example\r\n\r\n", "HTTP/1.1 200 OK\r\n\r\n", "note"))
+	path := b.write(t)
+
+	r, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	locs, err := r.parser.ScanHTTPRecords()
+	if err != nil || len(locs) != 1 {
+		t.Fatalf("ScanHTTPRecords = %d locations, %v; want 1", len(locs), err)
+	}
+	// The response record now runs off the end of the file.
+	loc := locs[0]
+	loc.ResponseOffset = r.parser.reader.Size()
+
+	entry, err := r.parser.ParseHTTPEntry(loc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if entry.Response != nil {
+		t.Errorf("Response = %q, want nil", entry.Response.Raw())
+	}
+	if entry.URL != "http://a.example/a" || entry.Comment != "note" || entry.ToolSource != ToolProxy ||
+		!entry.Timestamp.Equal(time.UnixMilli(1760000000000)) {
+		t.Errorf("entry = %+v, want the item's URL, comment, tool and time", entry)
+	}
+}
+
+func TestSplitHeaderLine(t *testing.T) {
+	tests := []struct {
+		line        string
+		name, value string
+		ok          bool
+	}{
+		{"Host: a.example", "Host", "a.example", true},
+		{"Host:a.example:8080", "Host", "a.example:8080", true},
+		{"X-Empty:", "X-Empty", "", true},
+		{":authority: a.example", ":authority", "a.example", true},
+		{":path: /a?b=c:d", ":path", "/a?b=c:d", true},
+		{":status:204", ":status", "204", true},
+		// A leading colon with nothing after the name is not a header.
+		{":authority", "", "", false},
+		{":", "", "", false},
+		{"::", "", "", false},
+		{": value", "", "", false},
+		{"no colon", "", "", false},
+	}
+	for _, tt := range tests {
+		name, value, ok := splitHeaderLine(tt.line)
+		if name != tt.name || value != tt.value || ok != tt.ok {
+			t.Errorf("splitHeaderLine(%q) = %q, %q, %v; want %q, %q, %v", tt.line, name, value, ok, tt.name, tt.value, tt.ok)
+		}
+	}
+}
+
+func TestHTTP2StartLine(t *testing.T) {
+	tests := []struct {
+		headers map[string][]string
+		want    string
+	}{
+		{map[string][]string{":method": {"GET"}, ":path": {"/a?b=1"}, ":authority": {"a.example"}, ":scheme": {"https"}}, "GET /a?b=1 HTTP/2"},
+		{map[string][]string{":method": {"POST"}}, "POST / HTTP/2"},
+		{map[string][]string{":status": {"404"}}, "HTTP/2 404"},
+		{map[string][]string{":status": {"200"}, ":method": {"GET"}}, "HTTP/2 200"},
+		{map[string][]string{":path": {"/a"}}, ""},
+		{map[string][]string{"Host": {"a.example"}}, ""},
+		{map[string][]string{}, ""},
+	}
+	for _, tt := range tests {
+		if got := http2StartLine(tt.headers); got != tt.want {
+			t.Errorf("http2StartLine(%v) = %q, want %q", tt.headers, got, tt.want)
+		}
+	}
+}
+
+func TestParseHTTPMessagePseudoHeaders(t *testing.T) {
+	tests := []struct {
+		name      string
+		data      string
+		startLine string
+		headers   map[string]string
+		missing   []string
+		body      string
+	}{
+		{
+			name:      "request",
+			data:      ":method: GET\r\n:path: /a?b=1\r\n:authority: a.example\r\n:scheme: https\r\nuser-agent: x\r\n\r\n",
+			startLine: "GET /a?b=1 HTTP/2",
+			headers:   map[string]string{":authority": "a.example", ":scheme": "https", "user-agent": "x"},
+		},
+		{
+			name:      "response",
+			data:      ":status: 200\r\ncontent-type: text/plain\r\n\r\nok",
+			startLine: "HTTP/2 200",
+			headers:   map[string]string{":status": "200", "content-type": "text/plain"},
+			body:      "ok",
+		},
+		{
+			name:      "pseudo-header after a regular header",
+			data:      ":method: GET\r\nuser-agent: x\r\n:path: /late\r\n\r\n",
+			startLine: "GET /late HTTP/2",
+			headers:   map[string]string{":path": "/late", "user-agent": "x"},
+		},
+		{
+			name:      "pseudo-header after a start line",
+			data:      "GET /a HTTP/1.1\r\nHost: a.example\r\n:authority: b.example\r\n\r\n",
+			startLine: "GET /a HTTP/1.1",
+			headers:   map[string]string{"Host": "a.example", ":authority": "b.example"},
+		},
+		{
+			name:      "leading colon without a value",
+			data:      ":method: GET\r\n:path: /a\r\n:authority\r\n\r\n",
+			startLine: "GET /a HTTP/2",
+			missing:   []string{":authority"},
+		},
+	}
+	for _, tt := range tests {
+		msg := parseHTTPMessage([]byte(tt.data))
+		if msg.StartLine != tt.startLine {
+			t.Errorf("%s: StartLine = %q, want %q", tt.name, msg.StartLine, tt.startLine)
+		}
+		for name, want := range tt.headers {
+			if got := headerValue(msg.Headers, name); got != want {
+				t.Errorf("%s: header %s = %q, want %q", tt.name, name, got, want)
+			}
+		}
+		for _, name := range tt.missing {
+			if values, ok := msg.Headers[name]; ok {
+				t.Errorf("%s: header %s = %q, want none", tt.name, name, values)
+			}
+		}
+		if body := string(msg.Body()); body != tt.body {
+			t.Errorf("%s: body = %q, want %q", tt.name, body, tt.body)
+		}
+	}
+}
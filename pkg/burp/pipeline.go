@@ -0,0 +1,132 @@
+package burp
+
+import (
+	"context"
+	"runtime"
+	"sync"
+)
+
+// ProgressFunc reports how many of total records have been processed. It is
+// called from the goroutine consuming the results, never concurrently.
+type ProgressFunc func(done, total int)
+
+// parseResult is a parsed location waiting to be emitted in file order. A
+// nil entry marks a location that could not be parsed.
+type parseResult struct {
+	index int
+	entry *HTTPEntry
+}
+
+// parseHTTPEntries parses locs on up to workers goroutines and calls emit
+// with every successfully parsed entry in the order of locs. Locations that
+// fail to parse are skipped, and logged when recovering. The
+// number of parsed entries held back waiting for an earlier, slower location
+// is bounded, so memory use does not grow with the size of the project.
+// Emission stops early when emit returns false or ctx is cancelled; in the
+// latter case ctx.Err() is returned. All workers have exited by the time
+// parseHTTPEntries returns.
+func (p *Parser) parseHTTPEntries(ctx context.Context, locs []HTTPRecordLocation, workers int, progress ProgressFunc, emit func(*HTTPEntry) bool) error {
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	workers = min(workers, max(len(locs), 1))
+
+	total := len(locs)
+	if workers == 1 {
+		for i, loc := range locs {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			entry, err := p.ParseHTTPEntry(loc)
+			if err != nil {
+				p.noteDamage(CategoryHistory, loc.recordOffset(), err)
+			}
+			if progress != nil {
+				progress(i+1, total)
+			}
+			if err == nil && !emit(entry) {
+				return nil
+			}
+		}
+		return nil
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	var wg sync.WaitGroup
+	defer func() {
+		cancel()
+		wg.Wait()
+	}()
+
+	// window bounds how far the workers may run ahead of the next location
+	// to be emitted.
+	window := make(chan struct{}, workers*16)
+	jobs := make(chan int)
+	results := make(chan parseResult, workers)
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		defer close(jobs)
+		for i := range locs {
+			select {
+			case window <- struct{}{}:
+			case <-ctx.Done():
+				return
+			}
+			select {
+			case jobs <- i:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				entry, err := p.ParseHTTPEntry(locs[i])
+				if err != nil {
+					p.noteDamage(CategoryHistory, locs[i].recordOffset(), err)
+					entry = nil
+				}
+				select {
+				case results <- parseResult{index: i, entry: entry}:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+	}
+
+	pending := make(map[int]*HTTPEntry)
+	for next := 0; next < total; {
+		select {
+		case res := <-results:
+			pending[res.index] = res.entry
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+
+		for {
+			entry, ok := pending[next]
+			if !ok {
+				break
+			}
+			delete(pending, next)
+			next++
+			<-window
+
+			if progress != nil {
+				progress(next, total)
+			}
+			if entry != nil && !emit(entry) {
+				return nil
+			}
+		}
+	}
+
+	return nil
+}
@@ -2,6 +2,7 @@ package burp
 
 import (
 	"context"
+	"io"
 	"sync"
 )
 
@@ -11,44 +12,151 @@ type Reader struct {
 	cache    *projectCache
 	mu       sync.RWMutex
 	metadata *ProjectMetadata
+	opts     ReaderOptions
 }
 
 type projectCache struct {
 	httpHistory []HTTPEntry
 	locations   []HTTPRecordLocation
 	loaded      bool
+
+	// parsing is closed when the history parse in progress finishes. It is
+	// nil when no parse is running.
+	parsing chan struct{}
+
+	// skippedHistory counts the items listed in the history table that
+	// could not be read.
+	skippedHistory int
+
+	// summaries holds history entries loaded from the project index, without
+	// requests or responses.
+	summaries []HTTPEntry
 }
 
 type ReaderOptions struct {
 	PreloadHistory bool
 	BufferSize     int
-	MaxRecordSize  int64
+
+	// MaxRecordSize, when positive, rejects history messages whose stored
+	// length is larger, as a guard against corrupt length fields. Zero
+	// means messages of any size are read in full.
+	MaxRecordSize int64
+
+	// Workers is the number of goroutines parsing HTTP history records.
+	// Zero or less uses one per available CPU. Entries are always returned
+	// in file order.
+	Workers int
+
+	// Progress, when set, is called as HTTP history records are parsed.
+	Progress ProgressFunc
+
+	// ScanProgress, when set, is called as the scans that look for records
+	// move through the file. See ScanProgress.
+	ScanProgress ScanProgressFunc
+
+	// BodyCacheSize is the byte budget for message bytes kept in memory.
+	// History entries hold only their headers; Raw and Body read the
+	// message from the project on demand, and the most recently used
+	// messages are kept until the budget is exceeded, together with their
+	// decoded bodies. Zero or less disables the cache, so every access
+	// rereads the file.
+	BodyCacheSize int64
+
+	// IndexPath, when set, names an index file (see IndexPathFor) used to
+	// answer HTTPHistorySummary, HTTPHistoryCount and the tool scanners
+	// without rescanning the project. A missing or out-of-date index is
+	// rebuilt when the project is opened. The index is neither read nor
+	// written with Recover set: salvaged records must not be mistaken for
+	// live ones later, and a recovering reader has to scan to find damage.
+	IndexPath string
+
+	// Recover opens damaged project files, such as ones cut short by a
+	// crash, and salvages every record that can still be read: a bad
+	// header is tolerated, messages running past the end of the file are
+	// returned in part, and history records the history table no longer
+	// references are picked up by scanning. Damaged records are reported
+	// by DamagedRegions instead of being skipped silently.
+	Recover bool
+
+	// AssumeFormatVersion, when non-zero, reads the project with the layout
+	// of that format version instead of the one named in its header, e.g.
+	// for files written by a Burp release newer than this package. Zero
+	// uses the header's version.
+	AssumeFormatVersion uint32
+
+	// TempDir is where compressed or archived projects are unpacked to,
+	// and where projects read from a stream are copied. Empty uses the
+	// default temporary directory.
+	TempDir string
 }
 
 func DefaultReaderOptions() ReaderOptions {
 	return ReaderOptions{
 		PreloadHistory: false,
 		BufferSize:     256 * 1024,
-		MaxRecordSize:  10 * 1024 * 1024,
+		BodyCacheSize:  64 * 1024 * 1024,
 	}
 }
 
-// Open opens a Burp project file for reading.
+// Open opens a Burp project file for reading. Projects compressed with gzip
+// or stored in a zip or tar archive are unpacked to a temporary file first.
 func Open(path string) (*Reader, error) {
 	return OpenWithOptions(path, DefaultReaderOptions())
 }
 
 // OpenWithOptions opens a Burp project file with custom options.
 func OpenWithOptions(path string, opts ReaderOptions) (*Reader, error) {
-	parser, err := NewParser(path)
+	src, err := openProjectFile(path, opts.TempDir)
 	if err != nil {
 		return nil, err
 	}
+	return openReader(path, src, opts)
+}
+
+// OpenReaderAt reads the project held in the first size bytes of src, which
+// may be compressed or archived like the files Open accepts. Closing the
+// Reader does not close src.
+func OpenReaderAt(src io.ReaderAt, size int64) (*Reader, error) {
+	return OpenReaderAtWithOptions(src, size, DefaultReaderOptions())
+}
+
+// OpenReaderAtWithOptions is OpenReaderAt with custom options. Without a
+// path, an index named by IndexPath is tied to the project by its size and
+// header alone.
+func OpenReaderAtWithOptions(src io.ReaderAt, size int64, opts ReaderOptions) (*Reader, error) {
+	s, err := unpackProject(src, size, opts.TempDir)
+	if err != nil {
+		return nil, err
+	}
+	return openReader("", s, opts)
+}
+
+// OpenStream reads a project from a stream such as stdin. The stream is
+// copied to a temporary file, since the parser needs random access, and the
+// file is removed when the Reader is closed.
+func OpenStream(r io.Reader, opts ReaderOptions) (*Reader, error) {
+	src, err := openProjectStream(r, opts.TempDir)
+	if err != nil {
+		return nil, err
+	}
+	return openReader("", src, opts)
+}
+
+func openReader(path string, src projectSource, opts ReaderOptions) (*Reader, error) {
+	parser, err := newParser(src, opts.Recover, opts.AssumeFormatVersion)
+	if err != nil {
+		return nil, err
+	}
+
+	parser.bodies = newBodyCache(opts.BodyCacheSize)
+	parser.maxRecordSize = opts.MaxRecordSize
+	parser.scanProgress = opts.ScanProgress
 
 	r := &Reader{
 		parser: parser,
 		path:   path,
 		cache:  &projectCache{},
+		opts:   opts,
 	}
 
 	meta, err := parser.GetMetadata()
@@ -58,6 +166,13 @@ func OpenWithOptions(path string, opts ReaderOptions) (*Reader, error) {
 	}
 	r.metadata = meta
 
+	if opts.IndexPath != "" && !opts.Recover {
+		if err := r.openIndex(opts.IndexPath); err != nil {
+			parser.Close()
+			return nil, err
+		}
+	}
+
 	if opts.PreloadHistory {
 		_, err := r.HTTPHistory()
 		if err != nil {
@@ -80,7 +195,8 @@ func (r *Reader) Close() error {
 	return nil
 }
 
-// Path returns the file path of the Burp project.
+// Path returns the file path of the Burp project, or "" when it was opened
+// from a reader or stream.
 func (r *Reader) Path() string {
 	return r.path
 }
@@ -92,29 +208,76 @@ func (r *Reader) Metadata() *ProjectMetadata {
 	return r.metadata
 }
 
+// FormatVersion returns the format version whose layout the project is read
+// with. It differs from Metadata().FormatVersion when a version was assumed,
+// or when a recovering reader fell back to the newest layout.
+func (r *Reader) FormatVersion() uint32 {
+	return r.parser.layout.version
+}
+
+// Supports reports whether f can be extracted from the project.
+func (r *Reader) Supports(f Feature) bool {
+	return r.parser.layout.features[f]
+}
+
+// Compatibility returns which features can be extracted from the project, in
+// the order of Features.
+func (r *Reader) Compatibility() []FeatureSupport {
+	return r.parser.layout.compatibility()
+}
+
 // HTTPHistory returns all HTTP entries from the project.
 func (r *Reader) HTTPHistory() ([]HTTPEntry, error) {
-	r.mu.Lock()
-	defer r.mu.Unlock()
+	return r.HTTPHistoryContext(context.Background())
+}
 
+// HTTPHistoryContext is HTTPHistory that stops when ctx is cancelled,
+// returning the entries parsed so far with ctx.Err(). Partial results are
+// not cached.
+func (r *Reader) HTTPHistoryContext(ctx context.Context) ([]HTTPEntry, error) {
+	// The lock is only held to check and fill the cache, so other calls are
+	// not held up by the parse. Callers arriving while a parse runs wait for
+	// it instead of parsing the history again.
+	r.mu.Lock()
+	for r.cache.parsing != nil {
+		parsing := r.cache.parsing
+		r.mu.Unlock()
+		select {
+		case <-parsing:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		r.mu.Lock()
+	}
 	if r.cache.loaded {
+		defer r.mu.Unlock()
 		return r.cache.httpHistory, nil
 	}
 
-	locations, err := r.parser.ScanHTTPRecords()
+	locations, err := r.historyLocations(ctx)
 	if err != nil {
+		r.mu.Unlock()
 		return nil, err
 	}
-
-	r.cache.locations = locations
+	parsing := make(chan struct{})
+	r.cache.parsing = parsing
+	r.mu.Unlock()
 
 	var entries []HTTPEntry
-	for _, loc := range locations {
-		entry, err := r.parser.ParseHTTPEntry(loc)
-		if err != nil {
-			continue
-		}
+	if len(locations) > 0 {
+		entries = make([]HTTPEntry, 0, len(locations))
+	}
+	err = r.parser.parseHTTPEntries(ctx, locations, r.opts.Workers, r.opts.Progress, func(entry *HTTPEntry) bool {
 		entries = append(entries, *entry)
+		return true
+	})
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.cache.parsing = nil
+	close(parsing)
+	if err != nil {
+		return entries, err
 	}
 
 	r.cache.httpHistory = entries
@@ -124,26 +287,79 @@ func (r *Reader) HTTPHistory() ([]HTTPEntry, error) {
 	return entries, nil
 }
 
+// HTTPHistorySummary returns the HTTP history entries without their Request
+// and Response messages, which is all that listing and metadata filters need.
+// It is answered from the project index when one was loaded; otherwise the
+// full history is parsed and the messages may be present.
+func (r *Reader) HTTPHistorySummary() ([]HTTPEntry, error) {
+	return r.HTTPHistorySummaryContext(context.Background())
+}
+
+// HTTPHistorySummaryContext is HTTPHistorySummary that stops when ctx is
+// cancelled, returning the entries parsed so far with ctx.Err().
+func (r *Reader) HTTPHistorySummaryContext(ctx context.Context) ([]HTTPEntry, error) {
+	r.mu.RLock()
+	summaries := r.cache.summaries
+	r.mu.RUnlock()
+
+	if summaries != nil {
+		return summaries, nil
+	}
+	return r.HTTPHistoryContext(ctx)
+}
+
 // HTTPHistoryCount returns the number of HTTP entries without loading all data.
 func (r *Reader) HTTPHistoryCount() (int, error) {
+	return r.HTTPHistoryCountContext(context.Background())
+}
+
+// HTTPHistoryCountContext is HTTPHistoryCount that stops when ctx is
+// cancelled, returning the number of entries found so far with ctx.Err().
+func (r *Reader) HTTPHistoryCountContext(ctx context.Context) (int, error) {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
 	if r.cache.loaded {
 		return len(r.cache.httpHistory), nil
 	}
+	if r.cache.summaries != nil {
+		return len(r.cache.summaries), nil
+	}
+
+	locations, err := r.historyLocations(ctx)
+	return len(locations), err
+}
 
-	if len(r.cache.locations) > 0 {
-		return len(r.cache.locations), nil
+// historyLocations returns the cached Proxy history record locations,
+// scanning for them on first use. The caller must hold r.mu. A cancelled
+// scan returns what it found without caching it.
+func (r *Reader) historyLocations(ctx context.Context) ([]HTTPRecordLocation, error) {
+	if r.cache.locations != nil {
+		return r.cache.locations, nil
 	}
 
-	locations, err := r.parser.ScanHTTPRecords()
+	locations, skipped, err := r.parser.scanHTTPRecords(ctx)
 	if err != nil {
-		return 0, err
+		return locations, err
+	}
+	if locations == nil {
+		locations = []HTTPRecordLocation{}
 	}
 
 	r.cache.locations = locations
-	return len(locations), nil
+	r.cache.skippedHistory = skipped
+	return locations, nil
+}
+
+// SkippedHistoryItems returns how many of the items listed in the project's
+// history table could not be read and so are missing from the history. It
+// is zero until the history has been read. When recovering, the damaged
+// items are also reported by DamagedRegions.
+func (r *Reader) SkippedHistoryItems() int {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.cache.skippedHistory
 }
 
 // StreamHTTPHistory returns channels for streaming HTTP entries.
@@ -156,36 +372,26 @@ func (r *Reader) StreamHTTPHistory(ctx context.Context) (<-chan HTTPEntry, <-cha
 		defer close(errChan)
 
 		r.mu.Lock()
-		if len(r.cache.locations) == 0 {
-			locations, err := r.parser.ScanHTTPRecords()
-			if err != nil {
-				r.mu.Unlock()
-				errChan <- err
-				return
-			}
-			r.cache.locations = locations
-		}
-		locations := r.cache.locations
+		locations, err := r.historyLocations(ctx)
 		r.mu.Unlock()
+		if err != nil {
+			errChan <- err
+			return
+		}
 
-		for _, loc := range locations {
+		err = r.parser.parseHTTPEntries(ctx, locations, r.opts.Workers, r.opts.Progress, func(entry *HTTPEntry) bool {
 			select {
+			case entryChan <- *entry:
+				return true
 			case <-ctx.Done():
-				errChan <- ctx.Err()
-				return
-			default:
-				entry, err := r.parser.ParseHTTPEntry(loc)
-				if err != nil {
-					continue
-				}
-
-				select {
-				case entryChan <- *entry:
-				case <-ctx.Done():
-					errChan <- ctx.Err()
-					return
-				}
+				return false
 			}
+		})
+		if err == nil {
+			err = ctx.Err()
+		}
+		if err != nil {
+			errChan <- err
 		}
 	}()
 
@@ -194,24 +400,34 @@ func (r *Reader) StreamHTTPHistory(ctx context.Context) (<-chan HTTPEntry, <-cha
 
 // Project loads and returns the complete project data.
 func (r *Reader) Project() (*Project, error) {
-	history, err := r.HTTPHistory()
-	if err != nil {
+	return r.ProjectContext(context.Background())
+}
+
+// ProjectContext is Project that stops when ctx is cancelled, returning the
+// project with the history parsed so far alongside ctx.Err().
+func (r *Reader) ProjectContext(ctx context.Context) (*Project, error) {
+	history, err := r.HTTPHistoryContext(ctx)
+	if err != nil && ctx.Err() == nil {
 		return nil, err
 	}
 
 	project := &Project{
 		FilePath:    r.path,
 		Magic:       MagicBytes,
+		Version:     r.metadata.FormatVersion,
 		HTTPHistory: history,
 		Metadata:    *r.metadata,
 	}
 
-	project.SiteMap = buildSiteMap(history)
+	project.SiteMap = BuildSiteMap(history)
 
-	return project, nil
+	return project, err
 }
 
-func buildSiteMap(entries []HTTPEntry) *SiteMap {
+// BuildSiteMap groups history entries by origin (see HTTPEntry.Origin), so
+// services on the same host but with a different scheme or port are kept
+// apart.
+func BuildSiteMap(entries []HTTPEntry) *SiteMap {
 	siteMap := &SiteMap{
 		Root: make(map[string]*SiteMapNode),
 	}
@@ -222,14 +438,15 @@ func buildSiteMap(entries []HTTPEntry) *SiteMap {
 			continue
 		}
 
-		hostNode, ok := siteMap.Root[entry.Host]
+		origin := entry.Origin()
+		hostNode, ok := siteMap.Root[origin]
 		if !ok {
 			hostNode = &SiteMapNode{
 				Host:     entry.Host,
 				Path:     "/",
 				Children: make(map[string]*SiteMapNode),
 			}
-			siteMap.Root[entry.Host] = hostNode
+			siteMap.Root[origin] = hostNode
 		}
 
 		hostNode.Entries = append(hostNode.Entries, entry)
@@ -239,29 +456,174 @@ func buildSiteMap(entries []HTTPEntry) *SiteMap {
 }
 
 func (r *Reader) RepeaterTabNames() ([]string, error) {
+	return r.RepeaterTabNamesContext(context.Background())
+}
+
+// RepeaterTabNamesContext is RepeaterTabNames that can be cancelled. Like all
+// the Context variants, it returns what was found so far with ctx.Err().
+func (r *Reader) RepeaterTabNamesContext(ctx context.Context) ([]string, error) {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
 
-	return r.parser.ScanRepeaterTabNames()
+	return r.parser.ScanRepeaterTabNamesContext(ctx)
+}
+
+// RepeaterTabs returns every Repeater tab with its current request, response
+// and send history.
+func (r *Reader) RepeaterTabs() ([]RepeaterTab, error) {
+	return r.RepeaterTabsContext(context.Background())
+}
+
+// RepeaterTabsContext is RepeaterTabs that can be cancelled.
+func (r *Reader) RepeaterTabsContext(ctx context.Context) ([]RepeaterTab, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.parser.ScanRepeaterTabsContext(ctx)
+}
+
+// IntruderAttacks returns the saved Intruder attacks with their configuration
+// and results.
+func (r *Reader) IntruderAttacks() ([]IntruderAttack, error) {
+	return r.IntruderAttacksContext(context.Background())
+}
+
+// IntruderAttacksContext is IntruderAttacks that can be cancelled.
+func (r *Reader) IntruderAttacksContext(ctx context.Context) ([]IntruderAttack, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.parser.ScanIntruderAttacksContext(ctx)
+}
+
+// WebSocketHistory returns the WebSocket connections from the WebSockets
+// history with their upgrade requests and messages.
+func (r *Reader) WebSocketHistory() ([]WebSocketConnection, error) {
+	return r.WebSocketHistoryContext(context.Background())
+}
+
+// WebSocketHistoryContext is WebSocketHistory that can be cancelled.
+func (r *Reader) WebSocketHistoryContext(ctx context.Context) ([]WebSocketConnection, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.parser.ScanWebSocketConnectionsContext(ctx)
+}
+
+// CollaboratorInteractions returns the stored Collaborator interactions, each
+// linked to the HTTP history entries and Scanner issues whose requests carry
+// its payload. The history and issues are only loaded when there is at least
+// one interaction.
+func (r *Reader) CollaboratorInteractions() ([]CollaboratorInteraction, error) {
+	return r.CollaboratorInteractionsContext(context.Background())
+}
+
+// CollaboratorInteractionsContext is CollaboratorInteractions that can be
+// cancelled. Interactions returned early are not linked.
+func (r *Reader) CollaboratorInteractionsContext(ctx context.Context) ([]CollaboratorInteraction, error) {
+	r.mu.RLock()
+	interactions, err := r.parser.ScanCollaboratorInteractionsContext(ctx)
+	r.mu.RUnlock()
+	if err != nil || len(interactions) == 0 {
+		return interactions, err
+	}
+
+	entries, err := r.HTTPHistoryContext(ctx)
+	if err != nil {
+		return interactions, err
+	}
+	issues, err := r.ScannerIssueMetasContext(ctx)
+	if err != nil {
+		return interactions, err
+	}
+
+	LinkCollaboratorInteractions(interactions, entries, issues)
+	return interactions, nil
+}
+
+// CarveDeleted recovers history items, requests, Repeater content and
+// Scanner issues that are still in the project file but no longer reachable
+// from the live project, e.g. because they were deleted.
+func (r *Reader) CarveDeleted() ([]CarvedRecord, error) {
+	return r.CarveDeletedContext(context.Background())
+}
+
+// CarveDeletedContext is CarveDeleted that can be cancelled.
+func (r *Reader) CarveDeletedContext(ctx context.Context) ([]CarvedRecord, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.parser.CarveDeletedContext(ctx)
+}
+
+// Dump decodes the raw record at offset. See Parser.Dump.
+func (r *Reader) Dump(offset int64, opts DumpOptions) (*DumpNode, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.parser.Dump(offset, opts)
+}
+
+// FindTypedRecords returns the offsets of up to limit typed records of the
+// given type.
+func (r *Reader) FindTypedRecords(recordType uint16, limit int) ([]int64, error) {
+	return r.FindTypedRecordsContext(context.Background(), recordType, limit)
+}
+
+// FindTypedRecordsContext is FindTypedRecords that can be cancelled.
+func (r *Reader) FindTypedRecordsContext(ctx context.Context, recordType uint16, limit int) ([]int64, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.parser.FindTypedRecordsContext(ctx, recordType, limit)
+}
+
+// TargetScope returns the project's Target scope rules, or nil when the
+// project does not define a scope.
+func (r *Reader) TargetScope() (*TargetScope, error) {
+	return r.TargetScopeContext(context.Background())
+}
+
+// TargetScopeContext is TargetScope that can be cancelled.
+func (r *Reader) TargetScopeContext(ctx context.Context) (*TargetScope, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.parser.ScanTargetScopeContext(ctx)
 }
 
 func (r *Reader) ScannerIssueMetas() ([]ScannerIssueMeta, error) {
+	return r.ScannerIssueMetasContext(context.Background())
+}
+
+// ScannerIssueMetasContext is ScannerIssueMetas that can be cancelled.
+func (r *Reader) ScannerIssueMetasContext(ctx context.Context) ([]ScannerIssueMeta, error) {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
 
-	return r.parser.ScanScannerIssueMetas(nil)
+	return r.parser.ScanScannerIssueMetasContext(ctx, nil)
 }
 
 func (r *Reader) ScannerTaskSummaries() ([]ScannerTaskSummary, error) {
+	return r.ScannerTaskSummariesContext(context.Background())
+}
+
+// ScannerTaskSummariesContext is ScannerTaskSummaries that can be cancelled.
+func (r *Reader) ScannerTaskSummariesContext(ctx context.Context) ([]ScannerTaskSummary, error) {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
 
-	return r.parser.ScanScannerTaskSummaries(nil)
+	return r.parser.ScanScannerTaskSummariesContext(ctx, nil)
 }
 
 func (r *Reader) UITasks() ([]UITask, error) {
+	return r.UITasksContext(context.Background())
+}
+
+// UITasksContext is UITasks that can be cancelled.
+func (r *Reader) UITasksContext(ctx context.Context) ([]UITask, error) {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
 
-	return r.parser.ScanUITasks()
+	return r.parser.ScanUITasksContext(ctx)
 }
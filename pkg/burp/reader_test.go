@@ -0,0 +1,82 @@
+package burp
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestBuildSiteMapKeysOnOrigin(t *testing.T) {
+	entries := []HTTPEntry{
+		{Host: "example.com", Port: 443, Scheme: "https", Path: "/"},
+		{Host: "example.com", Port: 443, Scheme: "https", Path: "/a"},
+		{Host: "example.com", Port: 80, Scheme: "http", Path: "/"},
+		{Host: "example.com", Port: 8443, Scheme: "https", Path: "/admin"},
+		{Host: "::1", Port: 8080, Scheme: "http", Path: "/"},
+		{Path: "/no-host"},
+	}
+
+	siteMap := BuildSiteMap(entries)
+	var origins []string
+	for origin := range siteMap.Root {
+		origins = append(origins, origin)
+	}
+	slices.Sort(origins)
+	want := []string{"http://[::1]:8080", "http://example.com", "https://example.com", "https://example.com:8443"}
+	if !slices.Equal(origins, want) {
+		t.Fatalf("origins = %q, want %q", origins, want)
+	}
+	if n := len(siteMap.Root["https://example.com"].Entries); n != 2 {
+		t.Errorf("https://example.com has %d entries, want 2", n)
+	}
+}
+
+func TestHTTPHistoryParsesWithoutHoldingTheReader(t *testing.T) {
+	b := newProjectBuilder()
+	b.list(
+		b.historyItem("GET /a HTTP/1.1\r\nHost: a\r\n\r\n", "", ""),
+		b.historyItem("GET /b HTTP/1.1\r\nHost: a\r\n\r\n", "", ""),
+	)
+	path := b.write(t)
+
+	var r *Reader
+	blocked := false
+	opts := DefaultReaderOptions()
+	opts.Progress = func(done, total int) {
+		// Other Reader calls must not wait for the parse to finish.
+		got := make(chan struct{})
+		go func() {
+			r.Metadata()
+			r.SkippedHistoryItems()
+			close(got)
+		}()
+		select {
+		case <-got:
+		case <-time.After(5 * time.Second):
+			blocked = true
+		}
+	}
+	r, err := OpenWithOptions(path, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	results := make(chan []HTTPEntry, 2)
+	for range 2 {
+		go func() {
+			entries, err := r.HTTPHistory()
+			if err != nil {
+				t.Error(err)
+			}
+			results <- entries
+		}()
+	}
+	first, second := <-results, <-results
+	if blocked {
+		t.Fatal("Reader calls were blocked while the history was parsed")
+	}
+	if len(first) != 2 || len(second) != 2 || &first[0] != &second[0] {
+		t.Fatalf("concurrent calls returned %d and %d entries, want the same 2", len(first), len(second))
+	}
+}
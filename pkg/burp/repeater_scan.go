@@ -0,0 +1,326 @@
+package burp
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// A Repeater tab name record ends with the layout's marker, which is followed
+// by a typed record holding the tab contents: the request currently in the editor,
+// the response shown next to it, the target service and a list wrapper with
+// the tab's previous sends, oldest first.
+const (
+	repeaterTabRecordType  uint16 = 0x000d
+	repeaterSendRecordType uint16 = 0x000e
+
+	repeaterTabFieldRequest  byte = 0x00
+	repeaterTabFieldResponse byte = 0x01
+	repeaterTabFieldService  byte = 0x02
+	repeaterTabFieldHistory  byte = 0x03
+
+	repeaterSendFieldRequest  byte = 0x00
+	repeaterSendFieldResponse byte = 0x01
+	repeaterSendFieldTime     byte = 0x02
+
+	maxRepeaterSends = 100_000
+)
+
+// Service records describe the target of a request: host name, port and
+// whether the connection uses TLS.
+const (
+	serviceRecordType uint16 = 0x0010
+
+	serviceFieldHost   byte = 0x00
+	serviceFieldPort   byte = 0x01
+	serviceFieldSecure byte = 0x02
+)
+
+type httpService struct {
+	Host   string
+	Port   int
+	Secure bool
+}
+
+func (s httpService) protocol() string {
+	if s.Secure {
+		return "https"
+	}
+	return "http"
+}
+
+// applyTo makes the service the entry's target, overriding whatever was
+// derived from the request headers.
+func (s httpService) applyTo(entry *HTTPEntry) {
+	entry.Host = s.Host
+	entry.Scheme = s.protocol()
+	entry.Port = s.Port
+	if entry.Port == 0 {
+		entry.Port = defaultPortForScheme(entry.Scheme)
+	}
+}
+
+// ScanRepeaterTabs returns every Repeater tab with its current request and
+// response and its send history. Burp appends a fresh copy of a tab when it
+// changes, and the copies carry no tab identifier, so a record is taken for a
+// newer copy of an earlier tab only when it has the same name and its send
+// history extends that tab's; otherwise it is a tab of its own, even when
+// tabs share a name. The last readable copy of a tab wins while the tab
+// keeps its first position.
+func (p *Parser) ScanRepeaterTabs() ([]RepeaterTab, error) {
+	return p.ScanRepeaterTabsContext(context.Background())
+}
+
+// ScanRepeaterTabsContext is ScanRepeaterTabs that stops when ctx is
+// cancelled, returning the tabs found so far with ctx.Err().
+func (p *Parser) ScanRepeaterTabsContext(ctx context.Context) ([]RepeaterTab, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	s := p.startScan(ctx, ScanRepeater)
+	defer s.done()
+
+	var tabs []RepeaterTab
+	byName := make(map[string][]int)
+
+	err := p.scanRepeaterTabRecords(s, func(offset int64, name string) {
+		tab, err := p.readRepeaterTab(offset+p.layout.repeaterTab.contentOffset(), name)
+		tab.RecordOffset = offset
+		if err != nil {
+			// The tab is still listed by name.
+			p.noteSalvaged(CategoryRepeater, offset, err)
+			if len(byName[name]) == 0 {
+				byName[name] = append(byName[name], len(tabs))
+				tabs = append(tabs, tab)
+				s.found()
+			}
+			return
+		}
+
+		for _, i := range byName[name] {
+			if sendsExtend(tab.History, tabs[i].History) {
+				tabs[i] = tab
+				return
+			}
+		}
+		byName[name] = append(byName[name], len(tabs))
+		tabs = append(tabs, tab)
+		s.found()
+	})
+
+	return tabs, err
+}
+
+// sendsExtend reports whether sends starts with the requests of earlier.
+func sendsExtend(sends, earlier []RepeaterSend) bool {
+	if len(earlier) > len(sends) {
+		return false
+	}
+	for i, send := range earlier {
+		if !bytes.Equal(send.Request.Raw(), sends[i].Request.Raw()) {
+			return false
+		}
+	}
+	return true
+}
+
+func (p *Parser) readRepeaterTab(offset int64, name string) (RepeaterTab, error) {
+	tab := RepeaterTab{Name: name}
+
+	rec, err := p.readTypedRecordHeader(offset)
+	if err != nil {
+		return tab, fmt.Errorf("read repeater tab record at 0x%x: %w", offset, err)
+	}
+	if rec.Type != repeaterTabRecordType {
+		return tab, fmt.Errorf("unexpected repeater tab record type at 0x%x: %d", offset, rec.Type)
+	}
+
+	tab.Request = p.readMessageField(offset, rec, repeaterTabFieldRequest)
+	tab.Response = p.readMessageField(offset, rec, repeaterTabFieldResponse)
+
+	if ptr, ok := p.readOptionalPointerField(offset, rec, repeaterTabFieldService); ok {
+		if svc, err := p.readHTTPService(ptr); err == nil {
+			tab.Host = svc.Host
+			tab.Port = svc.Port
+			tab.Protocol = svc.protocol()
+		}
+	}
+	if tab.Host == "" && tab.Request != nil {
+		entry := &HTTPEntry{}
+		extractHostFromHeaders(entry, tab.Request.Headers)
+		tab.Host = entry.Host
+		tab.Port = entry.Port
+	}
+
+	if ptr, ok := p.readOptionalPointerField(offset, rec, repeaterTabFieldHistory); ok {
+		tab.History = p.readRepeaterSends(ptr)
+	}
+
+	return tab, nil
+}
+
+func (p *Parser) readRepeaterSends(listPtr int64) []RepeaterSend {
+	count, vecPtr, err := p.readListWrapper(listPtr)
+	if err != nil || count == 0 || count > maxRepeaterSends {
+		return nil
+	}
+
+	ptrs, err := p.readPointerVector(vecPtr)
+	if err != nil {
+		return nil
+	}
+	if int(count) < len(ptrs) {
+		ptrs = ptrs[:count]
+	}
+
+	sends := make([]RepeaterSend, 0, len(ptrs))
+	for _, ptr := range ptrs {
+		rec, err := p.readTypedRecordHeader(ptr)
+		if err != nil || rec.Type != repeaterSendRecordType {
+			continue
+		}
+
+		send := RepeaterSend{
+			Request:  p.readMessageField(ptr, rec, repeaterSendFieldRequest),
+			Response: p.readMessageField(ptr, rec, repeaterSendFieldResponse),
+		}
+		if send.Request == nil {
+			continue
+		}
+		if off, ok := rec.fieldOffset(repeaterSendFieldTime); ok {
+			if ms, err := p.reader.ReadUint64At(ptr + int64(off)); err == nil {
+				send.Timestamp = timeFromBurpMillis(ms)
+			}
+		}
+		sends = append(sends, send)
+	}
+
+	return sends
+}
+
+// readOptionalPointerField reads a pointer field that Burp leaves as zero when
+// the referenced record does not exist.
+func (p *Parser) readOptionalPointerField(recordPtr int64, rec typedRecord, id byte) (int64, bool) {
+	off, ok := rec.fieldOffset(id)
+	if !ok {
+		return 0, false
+	}
+	raw, err := p.reader.ReadUint64At(recordPtr + int64(off))
+	if err != nil || raw == 0 || int64(raw) >= p.reader.Size() || int64(raw) < int64(HeaderSize) {
+		return 0, false
+	}
+	return int64(raw), true
+}
+
+func (p *Parser) readMessageField(recordPtr int64, rec typedRecord, id byte) *HTTPMessage {
+	ptr, ok := p.readOptionalPointerField(recordPtr, rec, id)
+	if !ok {
+		return nil
+	}
+	msg, err := p.readHTTPMessageRecord(ptr)
+	if err != nil {
+		return nil
+	}
+	return msg
+}
+
+func (p *Parser) readHTTPMessageRecord(ptr int64) (*HTTPMessage, error) {
+	dataOffset, length, err := p.readMessageRecord(ptr)
+	if err != nil {
+		return nil, err
+	}
+	data, err := p.reader.ReadAt(dataOffset, length)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < length {
+		return nil, errors.New("short message record read")
+	}
+	return parseHTTPMessage(data), nil
+}
+
+func (p *Parser) readHTTPService(ptr int64) (httpService, error) {
+	rec, err := p.readTypedRecordHeader(ptr)
+	if err != nil {
+		return httpService{}, fmt.Errorf("read service record at 0x%x: %w", ptr, err)
+	}
+	if rec.Type != serviceRecordType {
+		return httpService{}, fmt.Errorf("unexpected service record type at 0x%x: %d", ptr, rec.Type)
+	}
+
+	var svc httpService
+	if hostPtr, ok := p.readOptionalPointerField(ptr, rec, serviceFieldHost); ok {
+		host, err := p.readUTF16BEStringRecord(hostPtr)
+		if err != nil {
+			return httpService{}, fmt.Errorf("read service host: %w", err)
+		}
+		svc.Host = strings.Trim(strings.TrimSpace(host), "[]")
+	}
+	if off, ok := rec.fieldOffset(serviceFieldPort); ok {
+		if port, err := p.reader.ReadUint32At(ptr + int64(off)); err == nil && port <= 0xffff {
+			svc.Port = int(port)
+		}
+	}
+	if off, ok := rec.fieldOffset(serviceFieldSecure); ok {
+		if b, err := p.reader.ReadAt(ptr+int64(off), 1); err == nil && len(b) == 1 {
+			svc.Secure = b[0] == 1
+		}
+	}
+	if svc.Host == "" {
+		return httpService{}, errors.New("service record has no host")
+	}
+
+	return svc, nil
+}
+
+// RepeaterEntries flattens tabs into HTTP entries as RepeaterTabEntries does,
+// numbering them from 1 across all the tabs.
+func RepeaterEntries(tabs []RepeaterTab) []HTTPEntry {
+	var entries []HTTPEntry
+	for _, tab := range tabs {
+		for _, entry := range RepeaterTabEntries(tab) {
+			entry.ID = uint64(len(entries) + 1)
+			entries = append(entries, entry)
+		}
+	}
+	return entries
+}
+
+// RepeaterTabEntries flattens a tab into HTTP entries, one per previous send,
+// or the current editor request when the tab was never sent. Entry IDs
+// number the entries within the tab; use RepeaterEntries for IDs that are
+// unique across tabs.
+func RepeaterTabEntries(tab RepeaterTab) []HTTPEntry {
+	var entries []HTTPEntry
+
+	add := func(req, resp *HTTPMessage, send RepeaterSend) {
+		entry := &HTTPEntry{
+			Timestamp:  send.Timestamp,
+			ToolSource: ToolRepeater,
+			Comment:    tab.Name,
+		}
+		if req != nil {
+			setEntryRequest(entry, req)
+		}
+		if resp != nil {
+			setEntryResponse(entry, resp)
+		}
+		if tab.Host != "" {
+			httpService{Host: tab.Host, Port: tab.Port, Secure: tab.Protocol == "https"}.applyTo(entry)
+		}
+		buildURL(entry)
+		entry.ID = uint64(len(entries) + 1)
+		entries = append(entries, *entry)
+	}
+
+	for _, send := range tab.History {
+		add(send.Request, send.Response, send)
+	}
+	if len(entries) == 0 && tab.Request != nil {
+		add(tab.Request, tab.Response, RepeaterSend{})
+	}
+
+	return entries
+}
@@ -0,0 +1,80 @@
+package burp
+
+import (
+	"slices"
+	"testing"
+	"unicode/utf16"
+)
+
+// repeaterTab writes a Repeater tab name record followed by the tab contents,
+// with one previous send per request, and returns the name record's offset.
+func (b *projectBuilder) repeaterTab(name string, requests ...string) int64 {
+	var sends []int64
+	for _, req := range requests {
+		sends = append(sends, b.typed(repeaterSendRecordType,
+			ptrField(repeaterSendFieldRequest, b.message(req)),
+			ptrField(repeaterSendFieldResponse, 0),
+			ptrField(repeaterSendFieldTime, 1760000000000),
+		))
+	}
+	history := b.list(sends...)
+	current := b.message(requests[len(requests)-1])
+
+	off := b.raw(repeaterTabNameRecordHeader)
+	record := make([]byte, 0x40)
+	for i, u := range utf16.Encode([]rune(name)) {
+		record[2*i], record[2*i+1] = byte(u>>8), byte(u)
+	}
+	b.raw(record)
+	b.raw(make([]byte, 0xb8-int(b.offset()-off)))
+	b.raw(repeaterRecordMarker)
+	b.typed(repeaterTabRecordType,
+		ptrField(repeaterTabFieldRequest, current),
+		ptrField(repeaterTabFieldResponse, 0),
+		ptrField(repeaterTabFieldService, 0),
+		ptrField(repeaterTabFieldHistory, history),
+	)
+	return off
+}
+
+func TestScanRepeaterTabsKeepsSameNamedTabsApart(t *testing.T) {
+	const (
+		reqA1 = "GET /a1 HTTP/1.1\r\nHost: a\r\n\r\n"
+		reqA2 = "GET /a2 HTTP/1.1\r\nHost: a\r\n\r\n"
+		reqB1 = "GET /b1 HTTP/1.1\r\nHost: b\r\n\r\n"
+	)
+	b := newProjectBuilder()
+	b.repeaterTab("1", reqA1)
+	other := b.repeaterTab("1", reqB1)
+	// A newer copy of the first tab, sent once more.
+	newer := b.repeaterTab("1", reqA1, reqA2)
+	path := b.write(t)
+
+	r, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	tabs, err := r.RepeaterTabs()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tabs) != 2 {
+		t.Fatalf("found %d tabs, want 2", len(tabs))
+	}
+	if tabs[0].RecordOffset != newer || len(tabs[0].History) != 2 {
+		t.Errorf("first tab read from 0x%x with %d sends, want 0x%x with 2", tabs[0].RecordOffset, len(tabs[0].History), newer)
+	}
+	if tabs[1].RecordOffset != other {
+		t.Errorf("second tab read from 0x%x, want 0x%x", tabs[1].RecordOffset, other)
+	}
+
+	var ids []uint64
+	for _, entry := range RepeaterEntries(tabs) {
+		ids = append(ids, entry.ID)
+	}
+	if want := []uint64{1, 2, 3}; !slices.Equal(ids, want) {
+		t.Errorf("entry IDs = %v, want %v", ids, want)
+	}
+}
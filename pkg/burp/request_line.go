@@ -0,0 +1,180 @@
+package burp
+
+import (
+	"bytes"
+	stdbinary "encoding/binary"
+	"strings"
+)
+
+// Request lines are recognised by grammar rather than by a list of known
+// methods, so WebDAV, CONNECT, TRACE and made-up verbs used in method
+// tampering tests are all found. A request line is
+//
+//	method SP request-target SP HTTP-version
+//
+// where method is an RFC 9110 token.
+const (
+	maxHTTPMethodLen    = 32
+	maxRequestTargetLen = 16 * 1024
+)
+
+var httpVersionMarker = []byte(" HTTP/")
+
+// isHTTPMethodStart reports whether line is an HTTP request line.
+func isHTTPMethodStart(line string) bool {
+	if idx := strings.IndexAny(line, "\r\n"); idx >= 0 {
+		line = line[:idx]
+	}
+
+	parts := strings.Split(line, " ")
+	if len(parts) != 3 {
+		return false
+	}
+	return isHTTPMethod(parts[0]) && isRequestTarget(parts[1]) && isHTTPVersion(parts[2])
+}
+
+func isHTTPMethod(method string) bool {
+	if method == "" || len(method) > maxHTTPMethodLen {
+		return false
+	}
+	if !isASCIILetter(method[0]) {
+		return false
+	}
+	for i := 0; i < len(method); i++ {
+		if !isTokenChar(method[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func isRequestTarget(target string) bool {
+	if target == "" || len(target) > maxRequestTargetLen {
+		return false
+	}
+	for i := 0; i < len(target); i++ {
+		if target[i] <= ' ' || target[i] == 0x7f {
+			return false
+		}
+	}
+	return true
+}
+
+// isHTTPVersion accepts HTTP/1.0, HTTP/1.1, HTTP/2 and HTTP/2.0 style
+// versions.
+func isHTTPVersion(version string) bool {
+	rest, ok := strings.CutPrefix(version, "HTTP/")
+	if !ok || rest == "" || !isASCIIDigit(rest[0]) {
+		return false
+	}
+	rest = rest[1:]
+	if rest == "" {
+		return true
+	}
+	return len(rest) == 2 && rest[0] == '.' && isASCIIDigit(rest[1])
+}
+
+func isTokenChar(b byte) bool {
+	if isASCIILetter(b) || isASCIIDigit(b) {
+		return true
+	}
+	return strings.IndexByte("!#$%&'*+-.^_`|~", b) >= 0
+}
+
+func isASCIILetter(b byte) bool {
+	return (b >= 'A' && b <= 'Z') || (b >= 'a' && b <= 'z')
+}
+
+func isASCIIDigit(b byte) bool {
+	return b >= '0' && b <= '9'
+}
+
+// indexHTTPRequestLine returns the offset of the first request line in data,
+// or -1.
+func indexHTTPRequestLine(data []byte) int {
+	searchIdx := 0
+	for {
+		pos := bytes.Index(data[searchIdx:], httpVersionMarker)
+		if pos == -1 {
+			return -1
+		}
+		marker := searchIdx + pos
+		searchIdx = marker + 1
+
+		if start, ok := requestLineStartBefore(data, marker); ok {
+			return start
+		}
+	}
+}
+
+// requestLineStartAt returns the file offset of the request line whose
+// " HTTP/" marker is at the given file offset.
+func (p *Parser) requestLineStartAt(marker int64) (int64, bool) {
+	const lookBehind = maxRequestTargetLen + maxHTTPMethodLen + messageRecordHeaderLen + 1
+	const lookAhead = 64
+
+	windowStart := max(marker-lookBehind, int64(HeaderSize))
+	window, err := p.reader.View(windowStart, int(marker-windowStart)+lookAhead)
+	if err != nil {
+		return 0, false
+	}
+
+	start, ok := requestLineStartBefore(window, int(marker-windowStart))
+	if !ok {
+		return 0, false
+	}
+	return windowStart + int64(start), true
+}
+
+func requestLineStartBefore(data []byte, marker int) (int, bool) {
+	targetStart := marker
+	for targetStart > 0 && marker-targetStart < maxRequestTargetLen {
+		b := data[targetStart-1]
+		if b <= ' ' || b == 0x7f {
+			break
+		}
+		targetStart--
+	}
+	if targetStart == marker || targetStart == 0 || data[targetStart-1] != ' ' {
+		return 0, false
+	}
+
+	methodEnd := targetStart - 1
+	methodStart := methodEnd
+	for methodStart > 0 && methodEnd-methodStart < maxHTTPMethodLen && isTokenChar(data[methodStart-1]) {
+		methodStart--
+	}
+	if methodStart == methodEnd {
+		return 0, false
+	}
+
+	lineEnd := bytes.IndexAny(data[marker:], "\r\n")
+	if lineEnd == -1 {
+		lineEnd = len(data)
+	} else {
+		lineEnd += marker
+	}
+
+	for start := methodStart; start < methodEnd; start++ {
+		if start >= messageRecordHeaderLen && hasMessageRecordHeader(data[start-messageRecordHeaderLen:start], lineEnd-start) &&
+			isHTTPMethodStart(string(data[start:lineEnd])) {
+			return start, true
+		}
+	}
+
+	if methodStart > 0 && data[methodStart-1] == '\n' {
+		return 0, false
+	}
+	for start := methodStart; start < methodEnd; start++ {
+		if isHTTPMethodStart(string(data[start:lineEnd])) {
+			return start, true
+		}
+	}
+	return 0, false
+}
+
+func hasMessageRecordHeader(header []byte, minLen int) bool {
+	totalLen := stdbinary.BigEndian.Uint32(header[0:4])
+	dataLen := stdbinary.BigEndian.Uint32(header[4:8])
+	return dataLen > 0 && uint64(totalLen) == uint64(dataLen)+messageRecordHeaderLen && int64(dataLen) >= int64(minLen)
+}
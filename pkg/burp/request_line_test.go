@@ -0,0 +1,160 @@
+package burp
+
+import (
+	stdbinary "encoding/binary"
+	"strings"
+	"testing"
+)
+
+func TestIsHTTPMethod(t *testing.T) {
+	tests := []struct {
+		method string
+		want   bool
+	}{
+		{"GET", true},
+		{"PROPFIND", true},
+		{"M-SEARCH", true},
+		{"get", true},
+		{strings.Repeat("M", maxHTTPMethodLen), true},
+		{strings.Repeat("M", maxHTTPMethodLen+1), false},
+		{"", false},
+		{"1GET", false},
+		{"-GET", false},
+		{"GE T", false},
+		{"GE(T", false},
+		{"GET\x00", false},
+		{"G\xc3\x89T", false},
+	}
+	for _, tt := range tests {
+		if got := isHTTPMethod(tt.method); got != tt.want {
+			t.Errorf("isHTTPMethod(%q) = %v, want %v", tt.method, got, tt.want)
+		}
+	}
+}
+
+func TestIsHTTPMethodStart(t *testing.T) {
+	tests := []struct {
+		line string
+		want bool
+	}{
+		{"GET / HTTP/1.1", true},
+		{"PROPFIND /dav/ HTTP/1.1\r\nDepth: 1", true},
+		{"get /a HTTP/1.1", true},
+		{"GET http://a.example/x?y=1 HTTP/1.1", true},
+		{"OPTIONS * HTTP/1.1", true},
+		{"CONNECT a.example:443 HTTP/1.1", true},
+		{"GET / HTTP/2", true},
+		{"GET / HTTP/2.0", true},
+		{"GET / HTTP/1.0\nHost: a", true},
+		{"GET / HTTP/3.10", false},
+		{"GET / HTTP/", false},
+		{"GET / HTTPS/1.1", false},
+		{"GET / http/1.1", false},
+		{"GET  / HTTP/1.1", false},
+		{"GET /", false},
+		{"GE(T / HTTP/1.1", false},
+		{"1GET / HTTP/1.1", false},
+		{"GET /a\x7f HTTP/1.1", false},
+		{"", false},
+	}
+	for _, tt := range tests {
+		if got := isHTTPMethodStart(tt.line); got != tt.want {
+			t.Errorf("isHTTPMethodStart(%q) = %v, want %v", tt.line, got, tt.want)
+		}
+	}
+}
+
+// testMessageRecord returns data behind a message record header.
+func testMessageRecord(data string) string {
+	var header []byte
+	header = stdbinary.BigEndian.AppendUint32(header, uint32(len(data)+messageRecordHeaderLen))
+	header = stdbinary.BigEndian.AppendUint32(header, uint32(len(data)))
+	return string(header) + data
+}
+
+func TestRequestLineStartBefore(t *testing.T) {
+	tests := []struct {
+		data   string
+		want   int
+		wantOK bool
+	}{
+		{"PROPFIND /dav/ HTTP/1.1\r\n", 0, true},
+		{"junk GET /a HTTP/1.1", 5, true},
+		{"get /a HTTP/1.1", 0, true},
+		{"\x00OPTIONS * HTTP/1.1", 1, true},
+		{"\x00GET https://a.example/x HTTP/2", 1, true},
+		{"abc\n" + testMessageRecord("GET /a HTTP/1.0\r\n\r\n"), 4 + messageRecordHeaderLen, true},
+		// A request line starting a line inside a message is only taken
+		// when a record header marks it as a message of its own.
+		{"abc\nGET /a HTTP/1.1", 0, false},
+		{" /a HTTP/1.1", 0, false},
+		{"GET  HTTP/1.1", 0, false},
+		{"GET /a\x00 HTTP/1.1", 0, false},
+		{"\x00(/a HTTP/1.1", 0, false},
+	}
+	for _, tt := range tests {
+		marker := strings.LastIndex(tt.data, " HTTP/")
+		got, ok := requestLineStartBefore([]byte(tt.data), marker)
+		if got != tt.want || ok != tt.wantOK {
+			t.Errorf("requestLineStartBefore(%q) = %d, %v; want %d, %v", tt.data, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
+
+func TestIndexHTTPRequestLine(t *testing.T) {
+	tests := []struct {
+		data string
+		want int
+	}{
+		{"GET / HTTP/1.1\r\n", 0},
+		{"\x00\x01PROPFIND /dav/ HTTP/1.1\r\nDepth: 1\r\n", 2},
+		{"\x00GET http://a.example/x?y=1 HTTP/1.1\r\n", 1},
+		{"\x00OPTIONS * HTTP/1.1\r\n", 1},
+		{"\x00GET / HTTP/2\r\n", 1},
+		{"\x00GET / HTTP/1.0\r\n", 1},
+		{"x HTTP/1.1 GET /a HTTP/1.1\r\n", 11},
+		{"GET / HTTP/3.10\r\n", -1},
+		{"abc\nGET / HTTP/1.1\r\n", -1},
+		{"no request here", -1},
+		{"", -1},
+	}
+	for _, tt := range tests {
+		if got := indexHTTPRequestLine([]byte(tt.data)); got != tt.want {
+			t.Errorf("indexHTTPRequestLine(%q) = %d, want %d", tt.data, got, tt.want)
+		}
+	}
+}
+
+func TestRequestLineStartAtWindowStart(t *testing.T) {
+	// With the longest method and target allowed, the record header starts
+	// right at the start of the window read behind the marker, so no method
+	// token is cut in two by the window. A longer target is not a request
+	// line at all.
+	method := strings.Repeat("M", maxHTTPMethodLen)
+	tests := []struct {
+		targetLen int
+		wantOK    bool
+	}{
+		{maxRequestTargetLen, true},
+		{maxRequestTargetLen + 1, false},
+	}
+	for _, tt := range tests {
+		target := "/" + strings.Repeat("a", tt.targetLen-1)
+		b := newProjectBuilder()
+		b.raw(make([]byte, 64))
+		off := b.message(method + " " + target + " HTTP/1.1\r\nHost: a\r\n\r\n")
+		path := b.write(t)
+
+		r, err := Open(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		start := off + messageRecordHeaderLen
+		marker := start + int64(len(method)+1+len(target))
+		got, ok := r.parser.requestLineStartAt(marker)
+		r.Close()
+		if ok != tt.wantOK || (ok && got != start) {
+			t.Errorf("target of %d bytes: requestLineStartAt = %d, %v; want %d, %v", tt.targetLen, got, ok, start, tt.wantOK)
+		}
+	}
+}
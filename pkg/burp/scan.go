@@ -0,0 +1,95 @@
+package burp
+
+import (
+	"context"
+)
+
+// ScanProgress reports how far one of the whole-file scans has got. Scans
+// visit the file in offset order, so BytesScanned out of BytesTotal shows how
+// much is left; Records counts what the scan has found so far. The last
+// report of every scan has Done set, whether it ran to completion or was
+// cancelled.
+type ScanProgress struct {
+	Scan         string
+	BytesScanned int64
+	BytesTotal   int64
+	Records      int
+	Done         bool
+}
+
+// ScanProgressFunc receives ScanProgress reports. Scans started from
+// different goroutines report concurrently.
+type ScanProgressFunc func(ScanProgress)
+
+// Names of the scans reported in ScanProgress.Scan.
+const (
+	ScanSignatures   = "signatures"
+	ScanHistory      = "history"
+	ScanRepeater     = "repeater"
+	ScanIntruder     = "intruder"
+	ScanScope        = "scope"
+	ScanIssues       = "issues"
+	ScanUITasks      = "ui-tasks"
+	ScanWebSockets   = "websockets"
+	ScanCollaborator = "collaborator"
+	ScanCarve        = "carve"
+	ScanRecordTypes  = "record-types"
+)
+
+// scanRun tracks one scan: it checks for cancellation as the scan moves
+// through the file and reports progress along the way.
+type scanRun struct {
+	ctx     context.Context
+	p       *Parser
+	name    string
+	records int
+}
+
+func (p *Parser) startScan(ctx context.Context, name string) *scanRun {
+	return &scanRun{ctx: ctx, p: p, name: name}
+}
+
+// offsets returns the offsets of sig, running the signature pass first if no
+// scan has yet.
+func (s *scanRun) offsets(sig signature) ([]int64, error) {
+	return s.p.signatureOffsetsContext(s.ctx, sig)
+}
+
+// at reports that the scan has reached offset and returns ctx.Err() once
+// the scan should stop.
+func (s *scanRun) at(offset int64) error {
+	if err := s.ctx.Err(); err != nil {
+		return err
+	}
+	s.report(offset, false)
+	return nil
+}
+
+// err returns ctx.Err(), for scans whose records are not visited in file
+// order and so cannot report how far they have got.
+func (s *scanRun) err() error {
+	return s.ctx.Err()
+}
+
+// found counts one record found by the scan.
+func (s *scanRun) found() {
+	s.records++
+}
+
+// done sends the scan's final progress report.
+func (s *scanRun) done() {
+	s.report(s.p.reader.Size(), true)
+}
+
+func (s *scanRun) report(offset int64, done bool) {
+	if s.p.scanProgress == nil {
+		return
+	}
+	s.p.scanProgress(ScanProgress{
+		Scan:         s.name,
+		BytesScanned: offset,
+		BytesTotal:   s.p.reader.Size(),
+		Records:      s.records,
+		Done:         done,
+	})
+}
@@ -0,0 +1,376 @@
+package burp
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// The Target scope is a single typed record holding the scope mode and two
+// list wrappers of rule records. Burp rewrites it whenever the scope changes,
+// so older copies may still be present in the file.
+var targetScopeSignature = []byte{
+	0x00, 0x14, 0x00, 0x03,
+	0x00, 0x00, 0x0d,
+	0x01, 0x00, 0x0e,
+	0x02, 0x00, 0x16,
+}
+
+const (
+	scopeRuleRecordType uint16 = 0x0015
+
+	targetScopeFieldAdvanced byte = 0x00
+	targetScopeFieldInclude  byte = 0x01
+	targetScopeFieldExclude  byte = 0x02
+
+	scopeRuleFieldEnabled  byte = 0x00
+	scopeRuleFieldPrefix   byte = 0x01
+	scopeRuleFieldProtocol byte = 0x02
+	scopeRuleFieldHost     byte = 0x03
+	scopeRuleFieldPort     byte = 0x04
+	scopeRuleFieldFile     byte = 0x05
+)
+
+// TargetScope holds the project's Target scope. In simple mode each rule is a
+// URL prefix; in advanced mode rules match protocol, host, port and file
+// separately using regular expressions.
+type TargetScope struct {
+	RecordOffset int64       `json:"recordOffset"`
+	Advanced     bool        `json:"advanced"`
+	Include      []ScopeRule `json:"include,omitempty"`
+	Exclude      []ScopeRule `json:"exclude,omitempty"`
+}
+
+// ScopeRule is a single include or exclude rule. Prefix is only used in simple
+// mode; Protocol ("any", "http" or "https"), Host, Port and File only in
+// advanced mode, where an empty expression matches anything.
+type ScopeRule struct {
+	Enabled  bool   `json:"enabled"`
+	Prefix   string `json:"prefix,omitempty"`
+	Protocol string `json:"protocol,omitempty"`
+	Host     string `json:"host,omitempty"`
+	Port     string `json:"port,omitempty"`
+	File     string `json:"file,omitempty"`
+
+	compiled *compiledScopeRule
+}
+
+type compiledScopeRule struct {
+	host *regexp.Regexp
+	port *regexp.Regexp
+	file *regexp.Regexp
+
+	prefixScheme string
+	prefixHost   string
+	prefixPort   int
+	prefixPath   string
+
+	// err is why the rule can never match.
+	err error
+}
+
+// InScope reports whether entry matches an enabled include rule and no
+// enabled exclude rule. A nil or empty scope has nothing in scope, as in Burp.
+func (s *TargetScope) InScope(entry HTTPEntry) bool {
+	if s == nil {
+		return false
+	}
+
+	included := false
+	for i := range s.Include {
+		if s.Include[i].matches(entry, s.Advanced) {
+			included = true
+			break
+		}
+	}
+	if !included {
+		return false
+	}
+
+	for i := range s.Exclude {
+		if s.Exclude[i].matches(entry, s.Advanced) {
+			return false
+		}
+	}
+	return true
+}
+
+// Err returns why the rule can never match, or nil. Burp checks expressions
+// with Java's regular expression syntax, which accepts constructs such as
+// lookaheads that Go's does not, so a rule Burp applies may still fail here.
+func (r *ScopeRule) Err() error {
+	c := r.compiled
+	if c == nil {
+		c = compileScopeRule(*r)
+	}
+	return c.err
+}
+
+// RuleErrors returns an error for every enabled rule whose Err is set, naming
+// the rule. InScope treats those rules as matching nothing.
+func (s *TargetScope) RuleErrors() []error {
+	if s == nil {
+		return nil
+	}
+
+	var errs []error
+	check := func(kind string, rules []ScopeRule) {
+		for i := range rules {
+			if !rules[i].Enabled {
+				continue
+			}
+			if err := rules[i].Err(); err != nil {
+				errs = append(errs, fmt.Errorf("%s rule %d: %w", kind, i+1, err))
+			}
+		}
+	}
+	check("include", s.Include)
+	check("exclude", s.Exclude)
+	return errs
+}
+
+func (r *ScopeRule) matches(entry HTTPEntry, advanced bool) bool {
+	if !r.Enabled {
+		return false
+	}
+
+	c := r.compiled
+	if c == nil {
+		c = compileScopeRule(*r)
+	}
+	if c.err != nil {
+		return false
+	}
+
+	scheme := entryScheme(entry)
+	port := entry.Port
+	if port == 0 {
+		port = defaultPortForScheme(scheme)
+	}
+	file := entry.Path
+	if file == "" {
+		file = "/"
+	}
+	if entry.QueryString != "" {
+		file += "?" + entry.QueryString
+	}
+
+	if !advanced {
+		if c.prefixScheme != "" && c.prefixScheme != scheme {
+			return false
+		}
+		if !strings.EqualFold(c.prefixHost, entry.Host) {
+			return false
+		}
+		wantPort := c.prefixPort
+		if wantPort == 0 {
+			wantPort = defaultPortForScheme(scheme)
+		}
+		if wantPort != port {
+			return false
+		}
+		return strings.HasPrefix(file, c.prefixPath)
+	}
+
+	switch strings.ToLower(r.Protocol) {
+	case "http", "https":
+		if strings.ToLower(r.Protocol) != scheme {
+			return false
+		}
+	}
+	if c.host != nil && !c.host.MatchString(entry.Host) {
+		return false
+	}
+	if c.port != nil && !c.port.MatchString(strconv.Itoa(port)) {
+		return false
+	}
+	if c.file != nil && !c.file.MatchString(file) {
+		return false
+	}
+	return true
+}
+
+func compileScopeRule(r ScopeRule) *compiledScopeRule {
+	c := &compiledScopeRule{}
+
+	compile := func(field, expr string) *regexp.Regexp {
+		if expr == "" {
+			return nil
+		}
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			if c.err == nil {
+				c.err = fmt.Errorf("%s expression %q: %w", field, expr, err)
+			}
+			return nil
+		}
+		return re
+	}
+	c.host = compile("host", r.Host)
+	c.port = compile("port", r.Port)
+	c.file = compile("file", r.File)
+
+	if r.Prefix != "" {
+		rest := r.Prefix
+		if i := strings.Index(rest, "://"); i >= 0 {
+			c.prefixScheme = strings.ToLower(rest[:i])
+			rest = rest[i+3:]
+		}
+		hostPort := rest
+		c.prefixPath = "/"
+		if i := strings.Index(rest, "/"); i >= 0 {
+			hostPort = rest[:i]
+			c.prefixPath = rest[i:]
+		}
+		c.prefixHost = hostPort
+		if i := strings.LastIndex(hostPort, ":"); i >= 0 && !strings.HasSuffix(hostPort, "]") {
+			port, err := strconv.Atoi(hostPort[i+1:])
+			if err != nil && c.err == nil {
+				c.err = fmt.Errorf("prefix %q has an invalid port", r.Prefix)
+			}
+			c.prefixHost = hostPort[:i]
+			c.prefixPort = port
+		}
+		c.prefixHost = strings.Trim(c.prefixHost, "[]")
+	}
+
+	return c
+}
+
+// entryScheme returns the URL scheme of entry. HTTPEntry.Protocol holds the
+// HTTP version, so without a Scheme the URL built for the entry decides.
+func entryScheme(entry HTTPEntry) string {
+	if entry.Scheme != "" {
+		return entry.Scheme
+	}
+	if strings.HasPrefix(entry.URL, "https://") || (entry.URL == "" && entry.Port == 443) {
+		return "https"
+	}
+	return "http"
+}
+
+// ScanTargetScope returns the project's Target scope, or nil when the project
+// does not store one. When several copies exist the last readable one wins.
+func (p *Parser) ScanTargetScope() (*TargetScope, error) {
+	return p.ScanTargetScopeContext(context.Background())
+}
+
+// ScanTargetScopeContext is ScanTargetScope that stops when ctx is
+// cancelled, returning the last scope read so far with ctx.Err().
+func (p *Parser) ScanTargetScopeContext(ctx context.Context) (*TargetScope, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	s := p.startScan(ctx, ScanScope)
+	defer s.done()
+
+	offsets, err := s.offsets(sigTargetScope)
+	if err != nil {
+		return nil, err
+	}
+
+	var scope *TargetScope
+	for _, abs := range offsets {
+		if err := s.at(abs); err != nil {
+			return scope, err
+		}
+		found, err := p.readTargetScope(abs)
+		if err != nil {
+			p.noteDamage(CategoryScope, abs, err)
+			continue
+		}
+		scope = found
+		s.found()
+	}
+
+	return scope, nil
+}
+
+func (p *Parser) readTargetScope(offset int64) (*TargetScope, error) {
+	rec, err := p.readTypedRecordHeader(offset)
+	if err != nil {
+		return nil, fmt.Errorf("read target scope record at 0x%x: %w", offset, err)
+	}
+
+	scope := &TargetScope{RecordOffset: offset}
+
+	if off, ok := rec.fieldOffset(targetScopeFieldAdvanced); ok {
+		if b, err := p.reader.ReadAt(offset+int64(off), 1); err == nil && len(b) == 1 {
+			scope.Advanced = b[0] == 1
+		}
+	}
+
+	if ptr, ok := p.readOptionalPointerField(offset, rec, targetScopeFieldInclude); ok {
+		scope.Include = p.readScopeRules(ptr)
+	}
+	if ptr, ok := p.readOptionalPointerField(offset, rec, targetScopeFieldExclude); ok {
+		scope.Exclude = p.readScopeRules(ptr)
+	}
+
+	return scope, nil
+}
+
+func (p *Parser) readScopeRules(listPtr int64) []ScopeRule {
+	var rules []ScopeRule
+	for _, rulePtr := range p.readListPointers(listPtr) {
+		rule, err := p.readScopeRule(rulePtr)
+		if err != nil {
+			continue
+		}
+		rules = append(rules, rule)
+	}
+	return rules
+}
+
+func (p *Parser) readScopeRule(offset int64) (ScopeRule, error) {
+	var rule ScopeRule
+
+	rec, err := p.readTypedRecordHeader(offset)
+	if err != nil {
+		return rule, err
+	}
+	if rec.Type != scopeRuleRecordType {
+		return rule, fmt.Errorf("unexpected scope rule record type at 0x%x: %d", offset, rec.Type)
+	}
+
+	if off, ok := rec.fieldOffset(scopeRuleFieldEnabled); ok {
+		if b, err := p.reader.ReadAt(offset+int64(off), 1); err == nil && len(b) == 1 {
+			rule.Enabled = b[0] == 1
+		}
+	}
+
+	if off, ok := rec.fieldOffset(scopeRuleFieldProtocol); ok {
+		if b, err := p.reader.ReadAt(offset+int64(off), 1); err == nil && len(b) == 1 {
+			switch b[0] {
+			case 1:
+				rule.Protocol = "http"
+			case 2:
+				rule.Protocol = "https"
+			default:
+				rule.Protocol = "any"
+			}
+		}
+	}
+
+	readString := func(id byte) string {
+		ptr, ok := p.readOptionalPointerField(offset, rec, id)
+		if !ok {
+			return ""
+		}
+		s, err := p.readUTF16BEStringRecord(ptr)
+		if err != nil {
+			return ""
+		}
+		return strings.TrimSpace(s)
+	}
+	rule.Prefix = readString(scopeRuleFieldPrefix)
+	rule.Host = readString(scopeRuleFieldHost)
+	rule.Port = readString(scopeRuleFieldPort)
+	rule.File = readString(scopeRuleFieldFile)
+
+	rule.compiled = compileScopeRule(rule)
+
+	return rule, nil
+}
@@ -0,0 +1,38 @@
+package burp
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestScopeRuleErrors(t *testing.T) {
+	scope := &TargetScope{
+		Advanced: true,
+		Include: []ScopeRule{
+			{Enabled: true, Protocol: "any", Host: `^(?=api)\.example\.com$`},
+			{Enabled: true, Protocol: "any", Host: `example\.com$`},
+			{Enabled: false, Protocol: "any", File: `(`},
+		},
+		Exclude: []ScopeRule{
+			{Enabled: true, Protocol: "any", Port: `[`},
+		},
+	}
+
+	errs := scope.RuleErrors()
+	if len(errs) != 2 {
+		t.Fatalf("RuleErrors = %v, want 2 errors", errs)
+	}
+	for i, prefix := range []string{"include rule 1: host expression", "exclude rule 1: port expression"} {
+		if !strings.HasPrefix(errs[i].Error(), prefix) {
+			t.Errorf("error %d = %q, want prefix %q", i, errs[i], prefix)
+		}
+	}
+
+	// The broken exclude rule matches nothing, so it excludes nothing.
+	if !scope.InScope(HTTPEntry{Host: "www.example.com", Scheme: "https", Port: 443, Path: "/"}) {
+		t.Error("entry matching the valid include rule is out of scope")
+	}
+	if err := scope.Include[1].Err(); err != nil {
+		t.Errorf("valid rule Err = %v", err)
+	}
+}
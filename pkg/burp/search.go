@@ -2,7 +2,9 @@ package burp
 
 import (
 	"context"
+	"fmt"
 	"regexp"
+	"slices"
 	"strings"
 )
 
@@ -23,6 +25,9 @@ type SearchOptions struct {
 	Scope         SearchScope
 	Regex         bool
 	MaxResults    int
+	// RawBodies searches bodies as stored instead of decoding transfer and
+	// content encodings first.
+	RawBodies bool
 }
 
 type SearchResult struct {
@@ -45,63 +50,13 @@ func Search(entries []HTTPEntry, opts SearchOptions) []SearchResult {
 	}
 
 	var results []SearchResult
-	var pattern *regexp.Regexp
-
-	if opts.Regex {
-		var err error
-		flags := ""
-		if !opts.CaseSensitive {
-			flags = "(?i)"
-		}
-		pattern, err = regexp.Compile(flags + opts.Query)
-		if err != nil {
-			return nil
-		}
-	}
-
-	searchFunc := func(text, location string) []SearchMatch {
-		if opts.Regex {
-			return searchRegex(text, pattern, location)
-		}
-		return searchText(text, opts.Query, opts.CaseSensitive, location)
+	searchFunc, err := newSearchFunc(opts)
+	if err != nil {
+		return nil
 	}
 
 	for _, entry := range entries {
-		var matches []SearchMatch
-
-		switch opts.Scope {
-		case SearchAll:
-			matches = append(matches, searchEntry(entry, searchFunc)...)
-		case SearchRequests:
-			if entry.Request != nil {
-				matches = append(matches, searchFunc(string(entry.Request.Raw), "request")...)
-			}
-		case SearchResponses:
-			if entry.Response != nil {
-				matches = append(matches, searchFunc(string(entry.Response.Raw), "response")...)
-			}
-		case SearchHeaders:
-			if entry.Request != nil {
-				matches = append(matches, searchHeaders(entry.Request.Headers, "request_header", searchFunc)...)
-			}
-			if entry.Response != nil {
-				matches = append(matches, searchHeaders(entry.Response.Headers, "response_header", searchFunc)...)
-			}
-		case SearchBodies:
-			if entry.Request != nil && entry.Request.Body != nil {
-				matches = append(matches, searchFunc(string(entry.Request.Body), "request_body")...)
-			}
-			if entry.Response != nil && entry.Response.Body != nil {
-				matches = append(matches, searchFunc(string(entry.Response.Body), "response_body")...)
-			}
-		case SearchURLs:
-			matches = append(matches, searchFunc(entry.URL, "url")...)
-			matches = append(matches, searchFunc(entry.Path, "path")...)
-			if entry.QueryString != "" {
-				matches = append(matches, searchFunc(entry.QueryString, "query")...)
-			}
-		}
-
+		matches := searchEntryScope(entry, opts, searchFunc)
 		if len(matches) > 0 {
 			results = append(results, SearchResult{
 				Entry:   entry,
@@ -118,7 +73,8 @@ func Search(entries []HTTPEntry, opts SearchOptions) []SearchResult {
 	return results
 }
 
-// SearchStream searches through streamed HTTP entries.
+// SearchStream searches through streamed HTTP entries. Like Search, it only
+// looks in the parts of each entry selected by opts.Scope.
 func SearchStream(ctx context.Context, entryChan <-chan HTTPEntry, opts SearchOptions) (<-chan SearchResult, <-chan error) {
 	resultChan := make(chan SearchResult, 100)
 	errChan := make(chan error, 1)
@@ -127,25 +83,10 @@ func SearchStream(ctx context.Context, entryChan <-chan HTTPEntry, opts SearchOp
 		defer close(resultChan)
 		defer close(errChan)
 
-		var pattern *regexp.Regexp
-		if opts.Regex {
-			var err error
-			flags := ""
-			if !opts.CaseSensitive {
-				flags = "(?i)"
-			}
-			pattern, err = regexp.Compile(flags + opts.Query)
-			if err != nil {
-				errChan <- err
-				return
-			}
-		}
-
-		searchFunc := func(text, location string) []SearchMatch {
-			if opts.Regex {
-				return searchRegex(text, pattern, location)
-			}
-			return searchText(text, opts.Query, opts.CaseSensitive, location)
+		searchFunc, err := newSearchFunc(opts)
+		if err != nil {
+			errChan <- err
+			return
 		}
 
 		count := 0
@@ -157,7 +98,7 @@ func SearchStream(ctx context.Context, entryChan <-chan HTTPEntry, opts SearchOp
 			default:
 			}
 
-			matches := searchEntry(entry, searchFunc)
+			matches := searchEntryScope(entry, opts, searchFunc)
 			if len(matches) > 0 {
 				result := SearchResult{
 					Entry:   entry,
@@ -182,22 +123,191 @@ func SearchStream(ctx context.Context, entryChan <-chan HTTPEntry, opts SearchOp
 	return resultChan, errChan
 }
 
-func searchEntry(entry HTTPEntry, searchFunc func(string, string) []SearchMatch) []SearchMatch {
+// WebSocketSearchResult is a WebSocket connection with the matches found in
+// its URL, upgrade messages and message payloads.
+type WebSocketSearchResult struct {
+	Connection WebSocketConnection
+	Matches    []SearchMatch
+	Score      int
+}
+
+// SearchWebSockets searches WebSocket connections with the same options as
+// Search. The requests and responses scopes cover the upgrade request or
+// response and the messages sent in that direction; the bodies scope covers
+// every message payload. Matches in a payload are located as
+// "message N (to server)" or "message N (to client)".
+func SearchWebSockets(conns []WebSocketConnection, opts SearchOptions) []WebSocketSearchResult {
+	if opts.Query == "" {
+		return nil
+	}
+
+	searchFunc, err := newSearchFunc(opts)
+	if err != nil {
+		return nil
+	}
+
+	var results []WebSocketSearchResult
+	for _, conn := range conns {
+		matches := searchWebSocketScope(conn, opts, searchFunc)
+		if len(matches) == 0 {
+			continue
+		}
+
+		results = append(results, WebSocketSearchResult{
+			Connection: conn,
+			Matches:    matches,
+			Score:      len(matches),
+		})
+		if opts.MaxResults > 0 && len(results) >= opts.MaxResults {
+			break
+		}
+	}
+
+	return results
+}
+
+func searchWebSocketScope(conn WebSocketConnection, opts SearchOptions, searchFunc func(string, string) []SearchMatch) []SearchMatch {
+	var matches []SearchMatch
+
+	// searchMessages searches the payloads of the messages sent in the
+	// given directions, or of every message when none is given.
+	searchMessages := func(directions ...WebSocketDirection) {
+		for i, msg := range conn.Messages {
+			if len(directions) > 0 && !slices.Contains(directions, msg.Direction) {
+				continue
+			}
+			location := fmt.Sprintf("message %d (%s)", i+1, msg.Direction)
+			matches = append(matches, searchFunc(string(msg.Payload), location)...)
+		}
+	}
+
+	switch opts.Scope {
+	case SearchAll:
+		matches = append(matches, searchFunc(conn.URL, "url")...)
+		if conn.UpgradeRequest != nil {
+			matches = append(matches, searchFunc(string(conn.UpgradeRequest.Raw()), "upgrade_request")...)
+		}
+		if conn.UpgradeResponse != nil {
+			matches = append(matches, searchFunc(string(conn.UpgradeResponse.Raw()), "upgrade_response")...)
+		}
+		searchMessages()
+	case SearchRequests:
+		if conn.UpgradeRequest != nil {
+			matches = append(matches, searchFunc(string(conn.UpgradeRequest.Raw()), "upgrade_request")...)
+		}
+		searchMessages(WebSocketToServer)
+	case SearchResponses:
+		if conn.UpgradeResponse != nil {
+			matches = append(matches, searchFunc(string(conn.UpgradeResponse.Raw()), "upgrade_response")...)
+		}
+		searchMessages(WebSocketToClient)
+	case SearchHeaders:
+		if conn.UpgradeRequest != nil {
+			matches = append(matches, searchHeaders(conn.UpgradeRequest.Headers, "request_header", searchFunc)...)
+		}
+		if conn.UpgradeResponse != nil {
+			matches = append(matches, searchHeaders(conn.UpgradeResponse.Headers, "response_header", searchFunc)...)
+		}
+	case SearchBodies:
+		searchMessages()
+	case SearchURLs:
+		matches = append(matches, searchFunc(conn.URL, "url")...)
+	}
+
+	return matches
+}
+
+// newSearchFunc returns the matcher for opts: a compiled regular expression
+// or a plain substring search.
+func newSearchFunc(opts SearchOptions) (func(text, location string) []SearchMatch, error) {
+	if !opts.Regex {
+		return func(text, location string) []SearchMatch {
+			return searchText(text, opts.Query, opts.CaseSensitive, location)
+		}, nil
+	}
+
+	flags := ""
+	if !opts.CaseSensitive {
+		flags = "(?i)"
+	}
+	pattern, err := regexp.Compile(flags + opts.Query)
+	if err != nil {
+		return nil, err
+	}
+	return func(text, location string) []SearchMatch {
+		return searchRegex(text, pattern, location)
+	}, nil
+}
+
+func searchEntryScope(entry HTTPEntry, opts SearchOptions, searchFunc func(string, string) []SearchMatch) []SearchMatch {
+	var matches []SearchMatch
+
+	switch opts.Scope {
+	case SearchAll:
+		matches = append(matches, searchEntry(entry, opts.RawBodies, searchFunc)...)
+	case SearchRequests:
+		if entry.Request != nil {
+			matches = append(matches, searchFunc(string(searchableRaw(entry.Request, opts.RawBodies)), "request")...)
+		}
+	case SearchResponses:
+		if entry.Response != nil {
+			matches = append(matches, searchFunc(string(searchableRaw(entry.Response, opts.RawBodies)), "response")...)
+		}
+	case SearchHeaders:
+		if entry.Request != nil {
+			matches = append(matches, searchHeaders(entry.Request.Headers, "request_header", searchFunc)...)
+		}
+		if entry.Response != nil {
+			matches = append(matches, searchHeaders(entry.Response.Headers, "response_header", searchFunc)...)
+		}
+	case SearchBodies:
+		if entry.Request.BodySize() > 0 {
+			matches = append(matches, searchFunc(string(searchableBody(entry.Request, opts.RawBodies)), "request_body")...)
+		}
+		if entry.Response.BodySize() > 0 {
+			matches = append(matches, searchFunc(string(searchableBody(entry.Response, opts.RawBodies)), "response_body")...)
+		}
+	case SearchURLs:
+		matches = append(matches, searchFunc(entry.URL, "url")...)
+		matches = append(matches, searchFunc(entry.Path, "path")...)
+		if entry.QueryString != "" {
+			matches = append(matches, searchFunc(entry.QueryString, "query")...)
+		}
+	}
+
+	return matches
+}
+
+func searchEntry(entry HTTPEntry, rawBodies bool, searchFunc func(string, string) []SearchMatch) []SearchMatch {
 	var matches []SearchMatch
 
 	matches = append(matches, searchFunc(entry.URL, "url")...)
 
 	if entry.Request != nil {
-		matches = append(matches, searchFunc(string(entry.Request.Raw), "request")...)
+		matches = append(matches, searchFunc(string(searchableRaw(entry.Request, rawBodies)), "request")...)
 	}
 
 	if entry.Response != nil {
-		matches = append(matches, searchFunc(string(entry.Response.Raw), "response")...)
+		matches = append(matches, searchFunc(string(searchableRaw(entry.Response, rawBodies)), "response")...)
 	}
 
 	return matches
 }
 
+func searchableRaw(msg *HTTPMessage, raw bool) []byte {
+	if raw {
+		return msg.Raw()
+	}
+	return msg.DecodedRaw()
+}
+
+func searchableBody(msg *HTTPMessage, raw bool) []byte {
+	if raw {
+		return msg.Body()
+	}
+	return msg.DecodedBody()
+}
+
 func searchText(text, query string, caseSensitive bool, location string) []SearchMatch {
 	var matches []SearchMatch
 
@@ -0,0 +1,108 @@
+package burp
+
+import (
+	"context"
+	"sync"
+)
+
+// signature identifies one of the byte patterns the whole-file scanners look
+// for. Rather than each scanner walking the file with its own bytes.Index
+// loop, every signature is located in a single multi-pattern pass the first
+// time any scanner needs one, and the offsets are shared afterwards.
+type signature int
+
+const (
+	sigListWrapper signature = iota
+	sigRepeaterTabName
+	sigIntruderAttack
+	sigTargetScope
+	sigScannerIssueIndexEntry
+	sigScannerIssueEntry
+	sigHTTPVersion
+	sigWebSocketConnection
+	sigCollaboratorInteraction
+	sigHistoryItem
+	sigHistoryItemWithService
+
+	numSignatures
+)
+
+type signatureIndex struct {
+	// mu serialises the signature pass. A pass that is cancelled or cut
+	// short by a read error leaves scanned unset, so the next caller starts
+	// over.
+	mu      sync.Mutex
+	scanned bool
+	offsets [numSignatures][]int64
+}
+
+// setSignatureOffsets seeds the index with offsets found by an earlier run,
+// typically loaded from the project index. It has no effect once the file
+// has been scanned.
+func (p *Parser) setSignatureOffsets(offsets [numSignatures][]int64) {
+	idx := &p.signatures
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	if !idx.scanned {
+		idx.offsets = offsets
+		idx.scanned = true
+	}
+}
+
+// allSignatureOffsets returns the offsets of every signature, scanning the
+// file first if needed.
+func (p *Parser) allSignatureOffsets() [numSignatures][]int64 {
+	p.signatureOffsets(sigListWrapper)
+	return p.signatures.offsets
+}
+
+// signatureOffsets returns the file offsets of every occurrence of sig after
+// the project header, in ascending order.
+func (p *Parser) signatureOffsets(sig signature) []int64 {
+	offsets, _ := p.signatureOffsetsContext(context.Background(), sig)
+	return offsets
+}
+
+// signatureOffsetsContext is signatureOffsets for callers that can be
+// cancelled. The signature pass reports its progress as ScanSignatures.
+// Only a pass that reaches the end of the file is kept: a cancelled pass
+// returns ctx.Err(), and one ended early by a read error returns what it
+// found for this caller alone, so the next caller scans again.
+func (p *Parser) signatureOffsetsContext(ctx context.Context, sig signature) ([]int64, error) {
+	idx := &p.signatures
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	if idx.scanned {
+		return idx.offsets[sig], nil
+	}
+
+	offsets, err := p.scanSignatures(ctx)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, err
+		}
+		return offsets[sig], nil
+	}
+
+	idx.offsets = offsets
+	idx.scanned = true
+	return offsets[sig], nil
+}
+
+// scanSignatures runs the signature pass over the whole file.
+func (p *Parser) scanSignatures(ctx context.Context) ([numSignatures][]int64, error) {
+	s := p.startScan(ctx, ScanSignatures)
+	defer s.done()
+
+	var offsets [numSignatures][]int64
+	err := p.reader.FindAllContext(ctx, p.layout.matcher, int64(HeaderSize), func(scanned int64) {
+		s.report(scanned, false)
+	}, func(pattern int, offset int64) bool {
+		offsets[pattern] = append(offsets[pattern], offset)
+		s.found()
+		return true
+	})
+	return offsets, err
+}
@@ -1,10 +1,25 @@
 package burp
 
 import (
+	"bytes"
+	"context"
 	stdbinary "encoding/binary"
 	"sort"
 )
 
+// Scanner task records are found by following an issue's task ID, which is
+// the offset of the task record.
+var scannerTaskSignature = []byte{
+	0x00, 0x00, 0x00, 0x07,
+	0x00, 0x00, 0x19,
+	0x01, 0x00, 0x21,
+	0x02, 0x00, 0x25,
+	0x03, 0x00, 0x26,
+	0x04, 0x00, 0x27,
+	0x05, 0x00, 0x2f,
+	0x06, 0x00, 0x37,
+}
+
 type ScannerTaskSummary struct {
 	TaskID           uint64         `json:"taskId"`
 	Host             string         `json:"host,omitempty"`
@@ -18,9 +33,16 @@ type ScannerTaskSummary struct {
 }
 
 func (p *Parser) ScanScannerTaskSummaries(filterSerialNumbers map[uint64]struct{}) ([]ScannerTaskSummary, error) {
-	metas, err := p.ScanScannerIssueMetas(filterSerialNumbers)
-	if err != nil {
-		return nil, err
+	return p.ScanScannerTaskSummariesContext(context.Background(), filterSerialNumbers)
+}
+
+// ScanScannerTaskSummariesContext is ScanScannerTaskSummaries that stops when
+// ctx is cancelled, returning summaries of the issues found so far with
+// ctx.Err().
+func (p *Parser) ScanScannerTaskSummariesContext(ctx context.Context, filterSerialNumbers map[uint64]struct{}) ([]ScannerTaskSummary, error) {
+	metas, scanErr := p.ScanScannerIssueMetasContext(ctx, filterSerialNumbers)
+	if scanErr != nil && ctx.Err() == nil {
+		return nil, scanErr
 	}
 
 	byTask := make(map[uint64]*ScannerTaskSummary)
@@ -58,7 +80,7 @@ func (p *Parser) ScanScannerTaskSummaries(filterSerialNumbers map[uint64]struct{
 		return summaries[i].TaskID < summaries[j].TaskID
 	})
 
-	return summaries, nil
+	return summaries, scanErr
 }
 
 func (p *Parser) populateScannerTaskMetadata(summary *ScannerTaskSummary) {
@@ -66,40 +88,20 @@ func (p *Parser) populateScannerTaskMetadata(summary *ScannerTaskSummary) {
 		return
 	}
 
-	const (
-		taskSigLen = 4 + 7*3
-		needLen    = 0x2f + 8
-
-		hostPtrOff  = 0x19
-		portOff     = 0x21
-		secureOff   = 0x25
-		timestampOff = 0x27
-	)
-
-	taskSig := []byte{
-		0x00, 0x00, 0x00, 0x07,
-		0x00, 0x00, 0x19,
-		0x01, 0x00, 0x21,
-		0x02, 0x00, 0x25,
-		0x03, 0x00, 0x26,
-		0x04, 0x00, 0x27,
-		0x05, 0x00, 0x2f,
-		0x06, 0x00, 0x37,
-	}
-
+	layout := p.layout.scannerTask
 	abs := int64(summary.TaskID)
-	rec, err := p.reader.ReadAt(abs, needLen)
-	if err != nil || len(rec) < needLen {
+	rec, err := p.reader.ReadAt(abs, layout.minRecordLen)
+	if err != nil || len(rec) < layout.minRecordLen {
 		return
 	}
-	if len(rec) < taskSigLen || !bytesEqual(rec[:taskSigLen], taskSig) {
+	if !bytes.HasPrefix(rec, layout.signature) {
 		return
 	}
 
-	hostPtr := int64(stdbinary.BigEndian.Uint64(rec[hostPtrOff : hostPtrOff+8]))
-	port := stdbinary.BigEndian.Uint32(rec[portOff : portOff+4])
-	secureFlag := rec[secureOff]
-	ts := stdbinary.BigEndian.Uint64(rec[timestampOff : timestampOff+8])
+	hostPtr := int64(stdbinary.BigEndian.Uint64(rec[layout.hostPtrOffset : layout.hostPtrOffset+8]))
+	port := stdbinary.BigEndian.Uint32(rec[layout.portOffset : layout.portOffset+4])
+	secureFlag := rec[layout.secureOffset]
+	ts := stdbinary.BigEndian.Uint64(rec[layout.timestampOffset : layout.timestampOffset+8])
 
 	host, _ := p.readUTF16BEStringRecord(hostPtr)
 
@@ -108,15 +110,3 @@ func (p *Parser) populateScannerTaskMetadata(summary *ScannerTaskSummary) {
 	summary.Secure = secureFlag == 1
 	summary.Timestamp = ts
 }
-
-func bytesEqual(a, b []byte) bool {
-	if len(a) != len(b) {
-		return false
-	}
-	for i := range a {
-		if a[i] != b[i] {
-			return false
-		}
-	}
-	return true
-}
@@ -0,0 +1,126 @@
+package burp
+
+import (
+	stdbinary "encoding/binary"
+	"os"
+	"path/filepath"
+	"testing"
+	"unicode/utf16"
+)
+
+// projectBuilder assembles a project file record by record. Every method
+// appends to the file and returns the offset of what it wrote, so records
+// can point at the ones written before them.
+type projectBuilder struct {
+	buf []byte
+}
+
+func newProjectBuilder() *projectBuilder {
+	buf := make([]byte, HeaderSize)
+	stdbinary.BigEndian.PutUint32(buf, MagicBytes)
+	return &projectBuilder{buf: buf}
+}
+
+func (b *projectBuilder) offset() int64 {
+	return int64(len(b.buf))
+}
+
+func (b *projectBuilder) raw(data []byte) int64 {
+	off := b.offset()
+	b.buf = append(b.buf, data...)
+	return off
+}
+
+// message writes a length-prefixed message record.
+func (b *projectBuilder) message(data string) int64 {
+	off := b.offset()
+	b.buf = stdbinary.BigEndian.AppendUint32(b.buf, uint32(len(data)+messageRecordHeaderLen))
+	b.buf = stdbinary.BigEndian.AppendUint32(b.buf, uint32(len(data)))
+	b.buf = append(b.buf, data...)
+	return off
+}
+
+type testField struct {
+	id   byte
+	data []byte
+}
+
+// typed writes a typed record holding fields in order.
+func (b *projectBuilder) typed(recType uint16, fields ...testField) int64 {
+	off := b.offset()
+	b.buf = stdbinary.BigEndian.AppendUint16(b.buf, recType)
+	b.buf = stdbinary.BigEndian.AppendUint16(b.buf, uint16(len(fields)))
+	pos := 4 + 3*len(fields)
+	for _, f := range fields {
+		b.buf = append(b.buf, f.id)
+		b.buf = stdbinary.BigEndian.AppendUint16(b.buf, uint16(pos))
+		pos += len(f.data)
+	}
+	for _, f := range fields {
+		b.buf = append(b.buf, f.data...)
+	}
+	return off
+}
+
+// list writes a pointer vector and the list wrapper that refers to it,
+// returning the offset of the wrapper.
+func (b *projectBuilder) list(ptrs ...int64) int64 {
+	vec := b.offset()
+	b.buf = stdbinary.BigEndian.AppendUint32(b.buf, uint32(8+8*len(ptrs)))
+	b.buf = stdbinary.BigEndian.AppendUint32(b.buf, uint32(len(ptrs)))
+	for _, p := range ptrs {
+		b.buf = stdbinary.BigEndian.AppendUint64(b.buf, uint64(p))
+	}
+
+	off := b.raw(listWrapperSignature)
+	b.buf = stdbinary.BigEndian.AppendUint32(b.buf, uint32(len(ptrs)))
+	b.buf = stdbinary.BigEndian.AppendUint64(b.buf, uint64(vec))
+	return off
+}
+
+// utf16String writes a UTF-16BE string record.
+func (b *projectBuilder) utf16String(s string) int64 {
+	units := utf16.Encode([]rune(s))
+	off := b.raw([]byte{0x00, 0x02, 0x00, 0x00, 0x0a, 0x01, 0x00, 0x12, 0x00, 0x00, 0x00, 0x00})
+	b.buf = append(b.buf, make([]byte, 8)...)
+	b.buf = stdbinary.BigEndian.AppendUint64(b.buf, uint64(8+2*len(units)))
+	b.buf = stdbinary.BigEndian.AppendUint32(b.buf, uint32(len(units)))
+	for _, u := range units {
+		b.buf = stdbinary.BigEndian.AppendUint16(b.buf, u)
+	}
+	return off
+}
+
+// write saves the project to a temporary file and returns its path. The
+// file ends in padding so that records at the very end are not cut off.
+func (b *projectBuilder) write(tb testing.TB) string {
+	tb.Helper()
+
+	path := filepath.Join(tb.TempDir(), "project.burp")
+	if err := os.WriteFile(path, append(b.buf, make([]byte, 64)...), 0o600); err != nil {
+		tb.Fatal(err)
+	}
+	return path
+}
+
+// writeTestProject writes a project file made of a blank header followed by
+// body and returns its path.
+func writeTestProject(tb testing.TB, body []byte) string {
+	tb.Helper()
+
+	b := newProjectBuilder()
+	b.raw(body)
+	return b.write(tb)
+}
+
+func ptrField(id byte, off int64) testField {
+	return testField{id, stdbinary.BigEndian.AppendUint64(nil, uint64(off))}
+}
+
+func u32Field(id byte, v uint32) testField {
+	return testField{id, stdbinary.BigEndian.AppendUint32(nil, v)}
+}
+
+func byteField(id byte, v byte) testField {
+	return testField{id, []byte{v}}
+}
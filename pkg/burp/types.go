@@ -2,6 +2,8 @@ package burp
 
 import (
 	"net/http"
+	"strings"
+	"sync"
 	"time"
 )
 
@@ -16,11 +18,14 @@ type Project struct {
 }
 
 type ProjectMetadata struct {
-	CreatedAt   time.Time
-	ModifiedAt  time.Time
-	BurpVersion string
-	FileSize    int64
-	RecordCount int
+	CreatedAt      time.Time
+	ModifiedAt     time.Time
+	BurpVersion    string
+	BurpEdition    string
+	FormatVersion  uint32
+	ProjectOptions string
+	FileSize       int64
+	RecordCount    int
 }
 
 type HTTPEntry struct {
@@ -29,6 +34,7 @@ type HTTPEntry struct {
 	Host          string
 	Port          int
 	Protocol      string
+	Scheme        string
 	Method        string
 	Path          string
 	QueryString   string
@@ -38,16 +44,33 @@ type HTTPEntry struct {
 	StatusCode    int
 	ContentLength int64
 	MIMEType      string
-	ToolSource    ToolType
-	Comment       string
-	Highlight     string
+	// ToolSource is the tool flag Burp stored on the history item. Entries
+	// come only from the Proxy history table: requests sent by the other
+	// tools are kept in their own records (see RepeaterTabs,
+	// IntruderAttacks and ScannerIssueMetas) and carry that tool here only
+	// when Burp also added them to the history. It is ToolUnknown when the
+	// item has no flag or one not listed in ToolType, and for entries found
+	// by scanning for request lines.
+	ToolSource ToolType
+	Comment    string
+	Highlight  string
 }
 
+// HTTPMessage is a parsed request or response. Headers and StartLine are
+// always populated; the message bytes are reached through Raw and Body.
 type HTTPMessage struct {
-	Raw       []byte
 	Headers   http.Header
-	Body      []byte
 	StartLine string
+
+	raw       []byte
+	size      int
+	bodyStart int
+	source    *messageSource // set when raw is loaded from the project on demand
+
+	// decoded memoises DecodedBody for messages held in memory; messages
+	// loaded on demand keep their decoded body in the body cache instead.
+	decodeOnce sync.Once
+	decoded    []byte
 }
 
 type SiteMapNode struct {
@@ -57,6 +80,7 @@ type SiteMapNode struct {
 	Entries  []*HTTPEntry
 }
 
+// SiteMap holds one tree per origin, keyed by HTTPEntry.Origin.
 type SiteMap struct {
 	Root map[string]*SiteMapNode
 }
@@ -157,11 +181,47 @@ func (t ToolType) String() string {
 	}
 }
 
+// ParseToolType maps a tool name such as "proxy" or "Repeater" to its ToolType.
+func ParseToolType(name string) (ToolType, bool) {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "proxy":
+		return ToolProxy, true
+	case "repeater":
+		return ToolRepeater, true
+	case "scanner":
+		return ToolScanner, true
+	case "intruder":
+		return ToolIntruder, true
+	case "spider":
+		return ToolSpider, true
+	case "sequencer":
+		return ToolSequencer, true
+	case "extension", "extender":
+		return ToolExtension, true
+	case "target":
+		return ToolTarget, true
+	case "unknown":
+		return ToolUnknown, true
+	default:
+		return ToolUnknown, false
+	}
+}
+
 type RepeaterTab struct {
-	Name     string
-	Request  *HTTPMessage
-	Response *HTTPMessage
-	Host     string
-	Port     int
-	Protocol string
+	// RecordOffset is where the copy of the tab that was read starts.
+	RecordOffset int64
+	Name         string
+	Request      *HTTPMessage
+	Response     *HTTPMessage
+	Host         string
+	Port         int
+	Protocol     string
+	History      []RepeaterSend
+}
+
+// RepeaterSend is one entry in a Repeater tab's navigation history.
+type RepeaterSend struct {
+	Request   *HTTPMessage
+	Response  *HTTPMessage
+	Timestamp time.Time
 }
@@ -2,6 +2,7 @@ package burp
 
 import (
 	"bytes"
+	"context"
 	stdbinary "encoding/binary"
 	"errors"
 	"fmt"
@@ -16,17 +17,25 @@ type UITask struct {
 }
 
 func (p *Parser) ScanUITasks() ([]UITask, error) {
+	return p.ScanUITasksContext(context.Background())
+}
+
+// ScanUITasksContext is ScanUITasks that stops when ctx is cancelled,
+// returning the tasks read so far with ctx.Err(). The task list is found at
+// a fixed offset, so no signature pass is needed.
+func (p *Parser) ScanUITasksContext(ctx context.Context) ([]UITask, error) {
 	p.mu.RLock()
 	defer p.mu.RUnlock()
 
-	const (
-		uiTasksListOffset = 0x1f4
-		maxTaskCount      = 256
-	)
+	s := p.startScan(ctx, ScanUITasks)
+	defer s.done()
+
+	const maxTaskCount = 256
 
-	count, vecPtr, err := p.readListWrapper(uiTasksListOffset)
+	listOffset := p.layout.uiTasksListOffset
+	count, vecPtr, err := p.readListWrapper(listOffset)
 	if err != nil {
-		return nil, fmt.Errorf("read UI tasks list wrapper at 0x%x: %w", uiTasksListOffset, err)
+		return nil, fmt.Errorf("read UI tasks list wrapper at 0x%x: %w", listOffset, err)
 	}
 	if count == 0 {
 		return nil, nil
@@ -45,6 +54,9 @@ func (p *Parser) ScanUITasks() ([]UITask, error) {
 
 	tasks := make([]UITask, 0, count)
 	for i := uint32(0); i < count; i++ {
+		if err := s.err(); err != nil {
+			return tasks, err
+		}
 		taskPtr := ptrs[i]
 		if taskPtr <= 0 {
 			return nil, fmt.Errorf("invalid task pointer at index=%d: 0x%x", i, taskPtr)
@@ -67,6 +79,7 @@ func (p *Parser) ScanUITasks() ([]UITask, error) {
 			Name:  displayName,
 			Scope: scope,
 		})
+		s.found()
 	}
 
 	return tasks, nil
@@ -113,6 +126,8 @@ func hasNumericPrefix(s string) bool {
 	return strings.HasPrefix(s[1:], ". ")
 }
 
+var listWrapperSignature = []byte{0x00, 0x00, 0x00, 0x02, 0x00, 0x00, 0x0a, 0x01, 0x00, 0x0e}
+
 func (p *Parser) readListWrapper(offset int64) (count uint32, vecPtr int64, _ error) {
 	const (
 		headerLen = 10
@@ -122,13 +137,11 @@ func (p *Parser) readListWrapper(offset int64) (count uint32, vecPtr int64, _ er
 		maxVecPtr = int64(^uint64(0) >> 1)
 	)
 
-	listWrapperSig := []byte{0x00, 0x00, 0x00, 0x02, 0x00, 0x00, 0x0a, 0x01, 0x00, 0x0e}
-
 	buf, err := p.reader.ReadAt(offset, readLen)
 	if err != nil || len(buf) < readLen {
 		return 0, 0, fmt.Errorf("read list wrapper: %w", err)
 	}
-	if !bytes.Equal(buf[:headerLen], listWrapperSig) {
+	if !bytes.Equal(buf[:headerLen], p.layout.signatures[sigListWrapper]) {
 		return 0, 0, errors.New("unexpected list wrapper signature")
 	}
 
@@ -161,14 +174,18 @@ func (p *Parser) readPointerVector(offset int64) ([]int64, error) {
 	}
 
 	data, err := p.reader.ReadAt(offset+8, int(capacity*8))
-	if err != nil || len(data) < int(capacity*8) {
+	if err != nil {
 		return nil, fmt.Errorf("read pointer vector data: %w", err)
 	}
 
 	ptrs := make([]int64, 0, capacity)
-	for i := uint32(0); i < capacity; i++ {
-		raw := stdbinary.BigEndian.Uint64(data[i*8 : i*8+8])
-		ptrs = append(ptrs, int64(raw))
+	for i := 0; i+8 <= len(data); i += 8 {
+		ptrs = append(ptrs, int64(stdbinary.BigEndian.Uint64(data[i:i+8])))
+	}
+
+	// The pointers before a cut-off end are returned along with the error.
+	if len(data) < int(capacity*8) {
+		return ptrs, fmt.Errorf("read pointer vector data at 0x%x: %w", offset, errRecordTruncated)
 	}
 
 	return ptrs, nil
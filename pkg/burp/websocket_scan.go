@@ -0,0 +1,298 @@
+package burp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Each row of the Proxy WebSockets history is a type 0x16 record pointing at
+// the upgrade request and response, the target service and a list of type
+// 0x17 message records in the order they were sent.
+var webSocketConnectionSignature = []byte{
+	0x00, 0x16, 0x00, 0x06,
+	0x00, 0x00, 0x16,
+	0x01, 0x00, 0x1a,
+	0x02, 0x00, 0x22,
+	0x03, 0x00, 0x2a,
+	0x04, 0x00, 0x32,
+	0x05, 0x00, 0x3a,
+}
+
+const (
+	webSocketConnectionRecordType uint16 = 0x0016
+	webSocketMessageRecordType    uint16 = 0x0017
+
+	webSocketFieldID              byte = 0x00
+	webSocketFieldUpgradeRequest  byte = 0x01
+	webSocketFieldUpgradeResponse byte = 0x02
+	webSocketFieldService         byte = 0x03
+	webSocketFieldTime            byte = 0x04
+	webSocketFieldMessages        byte = 0x05
+
+	webSocketMessageFieldDirection byte = 0x00
+	webSocketMessageFieldOpcode    byte = 0x01
+	webSocketMessageFieldTime      byte = 0x02
+	webSocketMessageFieldPayload   byte = 0x03
+	webSocketMessageFieldEdited    byte = 0x04
+)
+
+type WebSocketDirection int
+
+const (
+	WebSocketToServer WebSocketDirection = iota
+	WebSocketToClient
+)
+
+func (d WebSocketDirection) String() string {
+	switch d {
+	case WebSocketToServer:
+		return "to server"
+	case WebSocketToClient:
+		return "to client"
+	default:
+		return "unknown"
+	}
+}
+
+func (d WebSocketDirection) MarshalText() ([]byte, error) {
+	return []byte(d.String()), nil
+}
+
+// WebSocketOpcode is the frame opcode from RFC 6455.
+type WebSocketOpcode uint8
+
+const (
+	WebSocketContinuation WebSocketOpcode = 0x0
+	WebSocketText         WebSocketOpcode = 0x1
+	WebSocketBinary       WebSocketOpcode = 0x2
+	WebSocketClose        WebSocketOpcode = 0x8
+	WebSocketPing         WebSocketOpcode = 0x9
+	WebSocketPong         WebSocketOpcode = 0xa
+)
+
+func (o WebSocketOpcode) String() string {
+	switch o {
+	case WebSocketContinuation:
+		return "continuation"
+	case WebSocketText:
+		return "text"
+	case WebSocketBinary:
+		return "binary"
+	case WebSocketClose:
+		return "close"
+	case WebSocketPing:
+		return "ping"
+	case WebSocketPong:
+		return "pong"
+	default:
+		return fmt.Sprintf("0x%x", uint8(o))
+	}
+}
+
+func (o WebSocketOpcode) MarshalText() ([]byte, error) {
+	return []byte(o.String()), nil
+}
+
+// WebSocketConnection is one entry in Burp's WebSockets history. URL uses the
+// ws or wss scheme and is built from the target service and the upgrade
+// request.
+type WebSocketConnection struct {
+	RecordOffset    int64
+	ID              int
+	URL             string
+	Host            string
+	Port            int
+	Protocol        string
+	Timestamp       time.Time
+	UpgradeRequest  *HTTPMessage
+	UpgradeResponse *HTTPMessage
+	Messages        []WebSocketMessage
+}
+
+type WebSocketMessage struct {
+	Direction WebSocketDirection
+	Opcode    WebSocketOpcode
+	Timestamp time.Time
+	Edited    bool
+	Payload   []byte
+}
+
+// ScanWebSocketConnections returns every WebSocket connection with its
+// messages, ordered by connection ID. When Burp has left several copies of a
+// connection behind, the last readable copy wins. Connections whose ID is 0,
+// because the field is missing or unset, cannot be told apart and are all
+// kept.
+func (p *Parser) ScanWebSocketConnections() ([]WebSocketConnection, error) {
+	return p.ScanWebSocketConnectionsContext(context.Background())
+}
+
+// ScanWebSocketConnectionsContext is ScanWebSocketConnections that stops
+// when ctx is cancelled, returning the connections found so far with
+// ctx.Err().
+func (p *Parser) ScanWebSocketConnectionsContext(ctx context.Context) ([]WebSocketConnection, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	s := p.startScan(ctx, ScanWebSockets)
+	defer s.done()
+
+	offsets, err := s.offsets(sigWebSocketConnection)
+	if err != nil {
+		return nil, err
+	}
+
+	var conns []WebSocketConnection
+	index := make(map[int]int)
+
+	for _, abs := range offsets {
+		if err = s.at(abs); err != nil {
+			break
+		}
+		conn, err := p.readWebSocketConnection(abs)
+		if err != nil {
+			p.noteDamage(CategoryWebSockets, abs, err)
+			continue
+		}
+		if conn.ID != 0 {
+			if j, ok := index[conn.ID]; ok {
+				conns[j] = conn
+				continue
+			}
+			index[conn.ID] = len(conns)
+		}
+		conns = append(conns, conn)
+		s.found()
+	}
+
+	sort.SliceStable(conns, func(i, j int) bool {
+		return conns[i].ID < conns[j].ID
+	})
+	return conns, err
+}
+
+func (p *Parser) readWebSocketConnection(offset int64) (WebSocketConnection, error) {
+	conn := WebSocketConnection{RecordOffset: offset}
+
+	rec, err := p.readTypedRecordHeader(offset)
+	if err != nil {
+		return conn, fmt.Errorf("read websocket connection record at 0x%x: %w", offset, err)
+	}
+
+	if off, ok := rec.fieldOffset(webSocketFieldID); ok {
+		if id, err := p.reader.ReadUint32At(offset + int64(off)); err == nil {
+			conn.ID = int(id)
+		}
+	}
+
+	conn.UpgradeRequest = p.readMessageField(offset, rec, webSocketFieldUpgradeRequest)
+	if conn.UpgradeRequest == nil {
+		return conn, errors.New("websocket connection has no upgrade request")
+	}
+	conn.UpgradeResponse = p.readMessageField(offset, rec, webSocketFieldUpgradeResponse)
+
+	entry := &HTTPEntry{}
+	setEntryRequest(entry, conn.UpgradeRequest)
+	if ptr, ok := p.readOptionalPointerField(offset, rec, webSocketFieldService); ok {
+		if svc, err := p.readHTTPService(ptr); err == nil {
+			svc.applyTo(entry)
+		}
+	}
+	buildURL(entry)
+	conn.Host = entry.Host
+	conn.Port = entry.Port
+	conn.Protocol = entry.Scheme
+	conn.URL = webSocketURL(entry.URL)
+
+	if off, ok := rec.fieldOffset(webSocketFieldTime); ok {
+		if ms, err := p.reader.ReadUint64At(offset + int64(off)); err == nil {
+			conn.Timestamp = timeFromBurpMillis(ms)
+		}
+	}
+
+	if ptr, ok := p.readOptionalPointerField(offset, rec, webSocketFieldMessages); ok {
+		for _, msgPtr := range p.readListPointers(ptr) {
+			msg, err := p.readWebSocketMessage(msgPtr)
+			if err != nil {
+				p.noteDamage(CategoryWebSockets, msgPtr, err)
+				continue
+			}
+			conn.Messages = append(conn.Messages, msg)
+		}
+	}
+
+	return conn, nil
+}
+
+func (p *Parser) readWebSocketMessage(offset int64) (WebSocketMessage, error) {
+	var msg WebSocketMessage
+
+	rec, err := p.readTypedRecordHeader(offset)
+	if err != nil {
+		return msg, err
+	}
+	if rec.Type != webSocketMessageRecordType {
+		return msg, fmt.Errorf("unexpected websocket message record type at 0x%x: %d", offset, rec.Type)
+	}
+
+	readByte := func(id byte) (byte, bool) {
+		off, ok := rec.fieldOffset(id)
+		if !ok {
+			return 0, false
+		}
+		b, err := p.reader.ReadAt(offset+int64(off), 1)
+		if err != nil || len(b) != 1 {
+			return 0, false
+		}
+		return b[0], true
+	}
+
+	if b, ok := readByte(webSocketMessageFieldDirection); ok {
+		msg.Direction = WebSocketDirection(b)
+	}
+	if b, ok := readByte(webSocketMessageFieldOpcode); ok {
+		msg.Opcode = WebSocketOpcode(b)
+	}
+	if b, ok := readByte(webSocketMessageFieldEdited); ok {
+		msg.Edited = b != 0
+	}
+	if off, ok := rec.fieldOffset(webSocketMessageFieldTime); ok {
+		if ms, err := p.reader.ReadUint64At(offset + int64(off)); err == nil {
+			msg.Timestamp = timeFromBurpMillis(ms)
+		}
+	}
+
+	if ptr, ok := p.readOptionalPointerField(offset, rec, webSocketMessageFieldPayload); ok {
+		// Ping, pong and close frames often carry no payload at all.
+		dataOffset, length, err := p.readLengthPrefixedRecord(ptr, true)
+		if err != nil {
+			return msg, fmt.Errorf("read websocket payload at 0x%x: %w", ptr, err)
+		}
+		if length == 0 {
+			msg.Payload = []byte{}
+			return msg, nil
+		}
+		data, err := p.reader.ReadAt(dataOffset, length)
+		if err != nil || len(data) < length {
+			return msg, fmt.Errorf("short websocket payload at 0x%x", ptr)
+		}
+		msg.Payload = data
+	}
+
+	return msg, nil
+}
+
+// webSocketURL turns the http or https URL of an upgrade request into the
+// matching ws or wss URL.
+func webSocketURL(httpURL string) string {
+	if rest, ok := strings.CutPrefix(httpURL, "https://"); ok {
+		return "wss://" + rest
+	}
+	if rest, ok := strings.CutPrefix(httpURL, "http://"); ok {
+		return "ws://" + rest
+	}
+	return httpURL
+}
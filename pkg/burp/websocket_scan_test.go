@@ -0,0 +1,142 @@
+package burp
+
+import (
+	"testing"
+)
+
+type testWebSocketMessage struct {
+	direction WebSocketDirection
+	payload   string
+}
+
+// webSocketConnection writes a WebSocket connection record with its upgrade
+// request and text messages and returns the record's offset.
+func (b *projectBuilder) webSocketConnection(id uint32, request string, messages ...testWebSocketMessage) int64 {
+	var ptrs []int64
+	for _, msg := range messages {
+		ptrs = append(ptrs, b.webSocketMessage(msg.direction, WebSocketText, msg.payload))
+	}
+	return b.webSocketConnectionOf(id, request, ptrs...)
+}
+
+// webSocketMessage writes a WebSocket message record and returns its offset.
+func (b *projectBuilder) webSocketMessage(direction WebSocketDirection, opcode WebSocketOpcode, payload string) int64 {
+	return b.typed(webSocketMessageRecordType,
+		byteField(webSocketMessageFieldDirection, byte(direction)),
+		byteField(webSocketMessageFieldOpcode, byte(opcode)),
+		ptrField(webSocketMessageFieldTime, 1760000000000),
+		ptrField(webSocketMessageFieldPayload, b.message(payload)),
+		byteField(webSocketMessageFieldEdited, 0),
+	)
+}
+
+// webSocketConnectionOf writes a WebSocket connection record listing the
+// message records at msgPtrs and returns the record's offset.
+func (b *projectBuilder) webSocketConnectionOf(id uint32, request string, msgPtrs ...int64) int64 {
+	list := b.list(msgPtrs...)
+	upgrade := b.message(request)
+
+	return b.typed(webSocketConnectionRecordType,
+		u32Field(webSocketFieldID, id),
+		ptrField(webSocketFieldUpgradeRequest, upgrade),
+		ptrField(webSocketFieldUpgradeResponse, 0),
+		ptrField(webSocketFieldService, 0),
+		ptrField(webSocketFieldTime, 1760000000000),
+		ptrField(webSocketFieldMessages, list),
+	)
+}
+
+func TestScanWebSocketConnections(t *testing.T) {
+	const upgrade = "GET /socket HTTP/1.1\r\nHost: chat.example.com\r\nUpgrade: websocket\r\n\r\n"
+
+	b := newProjectBuilder()
+	b.webSocketConnection(2, upgrade, testWebSocketMessage{WebSocketToServer, "old"})
+	b.webSocketConnection(0, upgrade, testWebSocketMessage{WebSocketToServer, "first"})
+	b.webSocketConnection(0, upgrade, testWebSocketMessage{WebSocketToClient, "second"})
+	// A newer copy of connection 2 replaces the first one.
+	newer := b.webSocketConnection(2, upgrade,
+		testWebSocketMessage{WebSocketToServer, "hello"},
+		testWebSocketMessage{WebSocketToClient, "world"},
+	)
+	path := b.write(t)
+
+	r, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	conns, err := r.WebSocketHistory()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(conns) != 3 {
+		t.Fatalf("found %d connections, want 3: %+v", len(conns), conns)
+	}
+
+	// Connections without an ID are kept apart, in file order.
+	for i, want := range []string{"first", "second"} {
+		if conns[i].ID != 0 || len(conns[i].Messages) != 1 || string(conns[i].Messages[0].Payload) != want {
+			t.Errorf("connection %d = %+v, want ID 0 with message %q", i, conns[i], want)
+		}
+	}
+
+	conn := conns[2]
+	if conn.ID != 2 || conn.RecordOffset != newer {
+		t.Fatalf("connection 2 read from 0x%x with ID %d, want the copy at 0x%x", conn.RecordOffset, conn.ID, newer)
+	}
+	if conn.URL != "ws://chat.example.com/socket" {
+		t.Errorf("URL = %q, want %q", conn.URL, "ws://chat.example.com/socket")
+	}
+	if len(conn.Messages) != 2 {
+		t.Fatalf("found %d messages, want 2", len(conn.Messages))
+	}
+	for i, want := range []testWebSocketMessage{{WebSocketToServer, "hello"}, {WebSocketToClient, "world"}} {
+		msg := conn.Messages[i]
+		if msg.Direction != want.direction || msg.Opcode != WebSocketText || string(msg.Payload) != want.payload {
+			t.Errorf("message %d = %+v, want %s %q", i, msg, want.direction, want.payload)
+		}
+	}
+}
+
+func TestWebSocketMessagesWithoutPayloadOrUnreadable(t *testing.T) {
+	const upgrade = "GET /socket HTTP/1.1\r\nHost: chat.example.com\r\nUpgrade: websocket\r\n\r\n"
+
+	b := newProjectBuilder()
+	ping := b.webSocketMessage(WebSocketToServer, WebSocketPing, "")
+	text := b.webSocketMessage(WebSocketToClient, WebSocketText, "hello")
+	notAMessage := b.typed(0x0099, byteField(0, 0))
+	b.webSocketConnectionOf(1, upgrade, ping, notAMessage, text)
+	path := b.write(t)
+
+	opts := DefaultReaderOptions()
+	opts.Recover = true
+	r, err := OpenWithOptions(path, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	conns, err := r.WebSocketHistory()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(conns) != 1 || len(conns[0].Messages) != 2 {
+		t.Fatalf("found %+v, want one connection with 2 messages", conns)
+	}
+
+	// An empty ping frame is a message with an empty payload.
+	msg := conns[0].Messages[0]
+	if msg.Opcode != WebSocketPing || msg.Payload == nil || len(msg.Payload) != 0 {
+		t.Errorf("message 0 = %+v, want a ping with an empty payload", msg)
+	}
+	if msg := conns[0].Messages[1]; string(msg.Payload) != "hello" {
+		t.Errorf("message 1 = %+v, want %q", msg, "hello")
+	}
+
+	// The record that is not a message is reported, not silently dropped.
+	damaged := r.DamagedRegions()
+	if len(damaged) != 1 || damaged[0].Category != CategoryWebSockets || damaged[0].Offset != notAMessage {
+		t.Errorf("damaged = %+v, want the websockets record at 0x%x", damaged, notAMessage)
+	}
+}